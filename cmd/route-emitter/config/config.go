@@ -29,6 +29,63 @@ type OAuthConfig struct {
 	SkipCertVerify    bool                  `json:"skip_cert_verify"`
 }
 
+type FileRouteSinkConfig struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+// WebhookRouteSinkConfig posts every change to URL. Posts time out after
+// Timeout, 5 seconds by default.
+type WebhookRouteSinkConfig struct {
+	Name    string                `json:"name"`
+	URL     string                `json:"url"`
+	Timeout durationjson.Duration `json:"timeout,omitempty"`
+}
+
+type ConfigRendererConfig struct {
+	Name          string                `json:"name"`
+	TemplateFile  string                `json:"template_file"`
+	OutputFile    string                `json:"output_file"`
+	ReloadCommand []string              `json:"reload_command,omitempty"`
+	Debounce      durationjson.Duration `json:"debounce,omitempty"`
+	AllowEmpty    bool                  `json:"allow_empty,omitempty"`
+}
+
+// RouteSinksConfig adds sinks that are emitted every route change besides
+// NATS and routing-api. Every sink emits from its own queue; an emit waits for
+// a sink at most EmitTimeout, 5 seconds by default, and a sink whose queue is
+// full drops changes and is resynced from the routing table. NATS and
+// routing-api are not sinks: they are emitted every change before the sinks
+// and report through their own metrics. Route sinks are not used in dry runs.
+type RouteSinksConfig struct {
+	EmitTimeout durationjson.Duration    `json:"emit_timeout,omitempty"`
+	Files       []FileRouteSinkConfig    `json:"files,omitempty"`
+	Webhooks    []WebhookRouteSinkConfig `json:"webhooks,omitempty"`
+	Renderers   []ConfigRendererConfig   `json:"renderers,omitempty"`
+}
+
+type XDSConfig struct {
+	ListenAddress    string `json:"listen_address,omitempty"`
+	HTTPListenerPort uint32 `json:"http_listener_port,omitempty"`
+	RouterGroupGUID  string `json:"router_group_guid,omitempty"`
+	CACertFile       string `json:"ca_cert_file,omitempty"`
+	ServerCertFile   string `json:"server_cert_file,omitempty"`
+	ServerKeyFile    string `json:"server_key_file,omitempty"`
+}
+
+type InternalDNSConfig struct {
+	ListenAddress string                `json:"listen_address,omitempty"`
+	TTL           durationjson.Duration `json:"ttl,omitempty"`
+}
+
+// TCPReconcileConfig makes syncs delete orphaned TCP route mappings from
+// routing-api and upsert missing ones. Mappings of the router groups are
+// assumed to be owned by the emitter, so at least one has to be given.
+type TCPReconcileConfig struct {
+	Enabled          bool     `json:"enabled,omitempty"`
+	RouterGroupGUIDs []string `json:"router_group_guids,omitempty"`
+}
+
 type RouteEmitterConfig struct {
 	BBSAddress                   string                `json:"bbs_address"`
 	BBSCACertFile                string                `json:"bbs_ca_cert_file"`
@@ -41,6 +98,7 @@ type RouteEmitterConfig struct {
 	RegisterDirectInstanceRoutes bool                  `json:"register_direct_instance_routes,omitempty"`
 	CommunicationTimeout         durationjson.Duration `json:"communication_timeout,omitempty"`
 	HealthCheckAddress           string                `json:"healthcheck_address,omitempty"`
+	AdminAddress                 string                `json:"admin_address,omitempty"`
 	LockRetryInterval            durationjson.Duration `json:"lock_retry_interval,omitempty"`
 	LockTTL                      durationjson.Duration `json:"lock_ttl,omitempty"`
 	NATSAddresses                string                `json:"nats_addresses,omitempty"`
@@ -53,9 +111,12 @@ type RouteEmitterConfig struct {
 	RouteEmittingWorkers         int                   `json:"route_emitting_workers,omitempty"`
 	SyncInterval                 durationjson.Duration `json:"sync_interval,omitempty"`
 	TCPRouteTTL                  durationjson.Duration `json:"tcp_route_ttl,omitempty"`
+	TCPRouteReconciliation       TCPReconcileConfig    `json:"tcp_route_reconciliation,omitempty"`
 	OAuth                        OAuthConfig           `json:"oauth"`
 	RoutingAPI                   RoutingAPIConfig      `json:"routing_api"`
 	EnableTCPEmitter             bool                  `json:"enable_tcp_emitter"`
+	HTTPRoutesViaRoutingAPI      bool                  `json:"http_routes_via_routing_api,omitempty"`
+	HTTPRouteTTL                 durationjson.Duration `json:"http_route_ttl,omitempty"`
 	LoggregatorConfig            loggingclient.Config  `json:"loggregator"`
 	ReportInterval               durationjson.Duration `json:"report_interval,omitempty"`
 	UnregistrationInterval       durationjson.Duration `json:"unregistration_interval,omitempty"`
@@ -63,6 +124,26 @@ type RouteEmitterConfig struct {
 	EnableInternalEmitter        bool                  `json:"enable_internal_emitter"`
 	LocketEnabled                bool                  `json:"locket_enabled"`
 	LocketSessionName            string                `json:"locket_session_name"`
+	RoutingTableSnapshotFile     string                `json:"routing_table_snapshot_file,omitempty"`
+	RoutingTableSnapshotInterval durationjson.Duration `json:"routing_table_snapshot_interval,omitempty"`
+	RoutingTableSnapshotMaxAge   durationjson.Duration `json:"routing_table_snapshot_max_age,omitempty"`
+	AggregateRouteRegistrations  bool                  `json:"aggregate_route_registrations,omitempty"`
+	EventWorkers                 int                   `json:"event_workers,omitempty"`
+	RoutingTableShards           int                   `json:"routing_table_shards,omitempty"`
+	AddressCollisionPolicy       string                `json:"address_collision_policy,omitempty"`
+	MaxSyncUnregistrationPercent int                   `json:"max_sync_unregistration_percent,omitempty"`
+	MaxSyncUnregistrations       int                   `json:"max_sync_unregistrations,omitempty"`
+	DryRunDiffLogFile            string                `json:"dry_run_diff_log_file,omitempty"`
+	TCPRoutesToTLSProxy          bool                  `json:"tcp_routes_to_tls_proxy,omitempty"`
+	InstanceHostnameTemplates    []string              `json:"instance_hostname_templates,omitempty"`
+	MetricTagSources             map[string]string     `json:"metric_tag_sources,omitempty"`
+	NATSEmitRateLimit            float64               `json:"nats_emit_rate_limit,omitempty"`
+	NATSEmitBurst                int                   `json:"nats_emit_burst,omitempty"`
+	NATSPublishRetries           int                   `json:"nats_publish_retries,omitempty"`
+	NATSPublishRetryBackoff      durationjson.Duration `json:"nats_publish_retry_backoff,omitempty"`
+	RouteSinks                   RouteSinksConfig      `json:"route_sinks,omitempty"`
+	XDS                          XDSConfig             `json:"xds,omitempty"`
+	InternalDNS                  InternalDNSConfig     `json:"internal_dns,omitempty"`
 
 	lagerflags.LagerConfig
 	debugserver.DebugServerConfig
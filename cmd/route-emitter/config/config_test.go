@@ -23,6 +23,7 @@ var _ = Describe("Config", func() {
 	BeforeEach(func() {
 		configData = `{
 			"healthcheck_address": "127.0.0.1:8090",
+			"admin_address": "127.0.0.1:8091",
 			"cell_id": "cellID",
 			"uuid": "bosh-boshy-bosh-bosh",
 			"communication_timeout":"2s",
@@ -44,9 +45,15 @@ var _ = Describe("Config", func() {
 			"lock_retry_interval": "15s",
 			"lock_ttl": "20s",
 			"tcp_route_ttl": "2m",
+			"tcp_route_reconciliation": {
+				"enabled": true,
+				"router_group_guids": ["default-tcp"]
+			},
 			"log_level": "debug",
 			"debug_address": "127.0.0.1:9999",
 			"enable_tcp_emitter": true,
+			"http_routes_via_routing_api": true,
+			"http_route_ttl": "3m",
 			"enable_internal_emitter": true,
 			"register_direct_instance_routes": true,
 			"routing_api": {
@@ -57,6 +64,48 @@ var _ = Describe("Config", func() {
 				"client_key_file": "/tmp/routing_api_client_key_file"
 			},
 			"locket_enabled": true,
+			"routing_table_snapshot_file": "/var/vcap/data/route_emitter/routing_table.json",
+			"routing_table_snapshot_interval": "30s",
+			"routing_table_snapshot_max_age": "10m",
+			"aggregate_route_registrations": true,
+			"event_workers": 8,
+			"routing_table_shards": 64,
+			"address_collision_policy": "prefer-newest",
+			"max_sync_unregistration_percent": 50,
+			"max_sync_unregistrations": 1000,
+			"dry_run_diff_log_file": "/var/vcap/data/route-emitter/diff.log",
+			"tcp_routes_to_tls_proxy": true,
+			"instance_hostname_templates": ["{index}.{hostname}", "{instance_guid}.{hostname}"],
+			"metric_tag_sources": {"az": "availability_zone", "cell": "cell_id"},
+			"nats_emit_rate_limit": 500,
+			"nats_emit_burst": 1000,
+			"nats_publish_retries": 3,
+			"nats_publish_retry_backoff": "200ms",
+			"route_sinks": {
+				"emit_timeout": "5s",
+				"files": [{"name": "Audit", "path": "/var/vcap/data/route-emitter/routes.log"}],
+				"webhooks": [{"name": "Inventory", "url": "https://inventory.example.com/routes", "timeout": "2s"}],
+				"renderers": [{
+					"name": "HAProxy",
+					"template_file": "/var/vcap/jobs/haproxy/config/haproxy.cfg.tmpl",
+					"output_file": "/var/vcap/jobs/haproxy/config/haproxy.cfg",
+					"reload_command": ["/var/vcap/jobs/haproxy/bin/reload"],
+					"debounce": "3s",
+					"allow_empty": true
+				}]
+			},
+			"xds": {
+				"listen_address": "0.0.0.0:18000",
+				"http_listener_port": 8080,
+				"router_group_guid": "default-tcp",
+				"ca_cert_file": "/tmp/xds_ca_cert",
+				"server_cert_file": "/tmp/xds_server_cert",
+				"server_key_file": "/tmp/xds_server_key"
+			},
+			"internal_dns": {
+				"listen_address": "169.254.0.3:53",
+				"ttl": "10s"
+			},
 			"locket_address": "127.0.0.1:18018",
 			"locket_ca_cert_file": "locket-ca-cert",
 			"report_interval": "1m",
@@ -109,6 +158,7 @@ var _ = Describe("Config", func() {
 
 		expectedConfig := config.RouteEmitterConfig{
 			HealthCheckAddress:           "127.0.0.1:8090",
+			AdminAddress:                 "127.0.0.1:8091",
 			CellID:                       "cellID",
 			UUID:                         "bosh-boshy-bosh-bosh",
 			CommunicationTimeout:         durationjson.Duration(2 * time.Second),
@@ -132,9 +182,63 @@ var _ = Describe("Config", func() {
 			TCPRouteTTL:                  durationjson.Duration(2 * time.Minute),
 			ReportInterval:               durationjson.Duration(1 * time.Minute),
 			EnableTCPEmitter:             true,
+			HTTPRoutesViaRoutingAPI:      true,
+			HTTPRouteTTL:                 durationjson.Duration(3 * time.Minute),
 			EnableInternalEmitter:        true,
 			RegisterDirectInstanceRoutes: true,
 			LocketEnabled:                true,
+			RoutingTableSnapshotFile:     "/var/vcap/data/route_emitter/routing_table.json",
+			RoutingTableSnapshotInterval: durationjson.Duration(30 * time.Second),
+			RoutingTableSnapshotMaxAge:   durationjson.Duration(10 * time.Minute),
+			AggregateRouteRegistrations:  true,
+			EventWorkers:                 8,
+			RoutingTableShards:           64,
+			AddressCollisionPolicy:       "prefer-newest",
+			MaxSyncUnregistrationPercent: 50,
+			MaxSyncUnregistrations:       1000,
+			DryRunDiffLogFile:            "/var/vcap/data/route-emitter/diff.log",
+			TCPRoutesToTLSProxy:          true,
+			InstanceHostnameTemplates:    []string{"{index}.{hostname}", "{instance_guid}.{hostname}"},
+			MetricTagSources:             map[string]string{"az": "availability_zone", "cell": "cell_id"},
+			NATSEmitRateLimit:            500,
+			NATSEmitBurst:                1000,
+			NATSPublishRetries:           3,
+			NATSPublishRetryBackoff:      durationjson.Duration(200 * time.Millisecond),
+			RouteSinks: config.RouteSinksConfig{
+				EmitTimeout: durationjson.Duration(5 * time.Second),
+				Files: []config.FileRouteSinkConfig{
+					{Name: "Audit", Path: "/var/vcap/data/route-emitter/routes.log"},
+				},
+				Webhooks: []config.WebhookRouteSinkConfig{
+					{Name: "Inventory", URL: "https://inventory.example.com/routes", Timeout: durationjson.Duration(2 * time.Second)},
+				},
+				Renderers: []config.ConfigRendererConfig{
+					{
+						Name:          "HAProxy",
+						TemplateFile:  "/var/vcap/jobs/haproxy/config/haproxy.cfg.tmpl",
+						OutputFile:    "/var/vcap/jobs/haproxy/config/haproxy.cfg",
+						ReloadCommand: []string{"/var/vcap/jobs/haproxy/bin/reload"},
+						Debounce:      durationjson.Duration(3 * time.Second),
+						AllowEmpty:    true,
+					},
+				},
+			},
+			XDS: config.XDSConfig{
+				ListenAddress:    "0.0.0.0:18000",
+				HTTPListenerPort: 8080,
+				RouterGroupGUID:  "default-tcp",
+				CACertFile:       "/tmp/xds_ca_cert",
+				ServerCertFile:   "/tmp/xds_server_cert",
+				ServerKeyFile:    "/tmp/xds_server_key",
+			},
+			TCPRouteReconciliation: config.TCPReconcileConfig{
+				Enabled:          true,
+				RouterGroupGUIDs: []string{"default-tcp"},
+			},
+			InternalDNS: config.InternalDNSConfig{
+				ListenAddress: "169.254.0.3:53",
+				TTL:           durationjson.Duration(10 * time.Second),
+			},
 			RoutingAPI: config.RoutingAPIConfig{
 				URL:            "https://routing-api.cf.service.internal",
 				Port:           443,
@@ -23,14 +23,19 @@ import (
 	"code.cloudfoundry.org/locket/lock"
 	locketmodels "code.cloudfoundry.org/locket/models"
 	"code.cloudfoundry.org/route-emitter/cmd/route-emitter/config"
+	"code.cloudfoundry.org/route-emitter/configrenderer"
 	"code.cloudfoundry.org/route-emitter/diegonats"
 	"code.cloudfoundry.org/route-emitter/emitter"
+	"code.cloudfoundry.org/route-emitter/internaldns"
+	"code.cloudfoundry.org/route-emitter/introspection"
+	"code.cloudfoundry.org/route-emitter/persister"
 	"code.cloudfoundry.org/route-emitter/routehandlers"
 	"code.cloudfoundry.org/route-emitter/routingtable"
 	"code.cloudfoundry.org/route-emitter/scheduler"
 	"code.cloudfoundry.org/route-emitter/syncer"
 	"code.cloudfoundry.org/route-emitter/unregistration"
 	"code.cloudfoundry.org/route-emitter/watcher"
+	"code.cloudfoundry.org/route-emitter/xds"
 	routing_api "code.cloudfoundry.org/routing-api"
 	"code.cloudfoundry.org/routing-api/uaaclient"
 	"code.cloudfoundry.org/tlsconfig"
@@ -49,6 +54,17 @@ var configFilePath = flag.String(
 
 const (
 	routeEmitterLockKey = "route_emitter"
+
+	defaultRoutingTableSnapshotInterval = 30 * time.Second
+	defaultRoutingTableSnapshotMaxAge   = 5 * time.Minute
+	defaultNATSPublishRetryBackoff      = 100 * time.Millisecond
+	defaultXDSHTTPListenerPort          = 8080
+	xdsRouteSinkName                    = "XDS"
+	defaultInternalDNSTTL               = 5 * time.Second
+	internalDNSRouteSinkName            = "InternalDNS"
+	defaultConfigRendererDebounce       = time.Second
+	defaultHTTPRouteTTL                 = 2 * time.Minute
+	defaultWebhookRouteSinkTimeout      = 5 * time.Second
 )
 
 func main() {
@@ -87,44 +103,178 @@ func main() {
 	bbsClient := initializeBBSClient(logger, cfg)
 
 	localMode := cfg.CellID != ""
-	table := routingtable.NewRoutingTable(cfg.RegisterDirectInstanceRoutes, metronClient)
-	natsEmitter := initializeNatsEmitter(logger, natsClient, cfg.RouteEmittingWorkers, metronClient, cfg.EnableInternalEmitter)
+	var tableOptions []routingtable.Option
+	if cfg.AggregateRouteRegistrations {
+		tableOptions = append(tableOptions, routingtable.WithHostnameAggregation())
+	}
+	if cfg.RoutingTableShards > 0 {
+		tableOptions = append(tableOptions, routingtable.WithShardCount(cfg.RoutingTableShards))
+	}
+	if cfg.AddressCollisionPolicy != "" {
+		policy := routingtable.AddressCollisionPolicy(cfg.AddressCollisionPolicy)
+		if err := policy.Validate(); err != nil {
+			logger.Fatal("invalid-address-collision-policy", err)
+		}
+		tableOptions = append(tableOptions, routingtable.WithAddressCollisionPolicy(policy))
+	}
+	if cfg.TCPRoutesToTLSProxy {
+		tableOptions = append(tableOptions, routingtable.WithTCPRoutesToTLSProxy())
+	}
+	if len(cfg.InstanceHostnameTemplates) > 0 {
+		for _, template := range cfg.InstanceHostnameTemplates {
+			if err := routingtable.ValidateInstanceHostnameTemplate(template); err != nil {
+				logger.Fatal("invalid-instance-hostname-template", err)
+			}
+		}
+		tableOptions = append(tableOptions, routingtable.WithInstanceHostnameTemplates(cfg.InstanceHostnameTemplates))
+	}
+	if len(cfg.MetricTagSources) > 0 {
+		sources := map[string]routingtable.MetricTagSource{}
+		for name, value := range cfg.MetricTagSources {
+			source := routingtable.MetricTagSource(value)
+			if err := source.Validate(); err != nil {
+				logger.Fatal("invalid-metric-tag-source", err, lager.Data{"tag": name})
+			}
+			sources[name] = source
+		}
+		tableOptions = append(tableOptions, routingtable.WithMetricTagSources(sources))
+	}
+	tableOptions = append(tableOptions, routingtable.WithClock(clock))
+	table := routingtable.NewRoutingTable(cfg.RegisterDirectInstanceRoutes, metronClient, tableOptions...)
+	unregistrationCache := unregistration.NewCache(logger)
+
+	natsEmitterOptions := []emitter.NATSEmitterOption{emitter.WithUnregistrationCache(unregistrationCache)}
+	if cfg.NATSPublishRetries > 0 {
+		backoff := time.Duration(cfg.NATSPublishRetryBackoff)
+		if backoff == 0 {
+			backoff = defaultNATSPublishRetryBackoff
+		}
+		natsEmitterOptions = append(natsEmitterOptions, emitter.WithPublishRetries(clock, cfg.NATSPublishRetries, backoff))
+	}
+	if cfg.NATSEmitRateLimit > 0 {
+		limiter := emitter.NewRateLimiter(clock, cfg.NATSEmitRateLimit, cfg.NATSEmitBurst)
+		natsEmitterOptions = append(natsEmitterOptions, emitter.WithRateLimiter(limiter))
+	}
+	natsEmitter := initializeNatsEmitter(logger, natsClient, cfg.RouteEmittingWorkers, metronClient, cfg.EnableInternalEmitter, natsEmitterOptions...)
 
 	routeTTL := time.Duration(cfg.TCPRouteTTL)
 	if routeTTL.Seconds() > 65535 {
 		logger.Fatal("invalid-route-ttl", errors.New("route TTL value too large"), lager.Data{"ttl": routeTTL.Seconds()})
 	}
 
+	var routingAPIClient routing_api.Client
+	var uaaTokenFetcher uaaclient.TokenFetcher
+	if cfg.EnableTCPEmitter || cfg.HTTPRoutesViaRoutingAPI {
+		uaaTokenFetcher = newUaaTokenFetcher(logger.Session("routing-api"), &cfg, clock)
+		routingAPIClient = newRoutingAPIClient(logger, &cfg)
+	}
+
 	var routingAPIEmitter emitter.RoutingAPIEmitter
 	if cfg.EnableTCPEmitter {
-		tcpLogger := logger.Session("tcp")
-		uaaTokenFetcher := newUaaTokenFetcher(tcpLogger, &cfg, clock)
-
-		routingAPIAddress := fmt.Sprintf("%s:%d", cfg.RoutingAPI.URL, cfg.RoutingAPI.Port)
-		logger.Debug("creating-routing-api-client", lager.Data{"api-location": routingAPIAddress})
-
-		var routingAPIClient routing_api.Client
-		if cfg.RoutingAPI.ClientCertFile != "" && cfg.RoutingAPI.ClientKeyFile != "" && cfg.RoutingAPI.CACertFile != "" {
-			tlsConfig, err := tlsconfig.Build(
-				tlsconfig.WithInternalServiceDefaults(),
-				tlsconfig.WithIdentityFromFile(cfg.RoutingAPI.ClientCertFile, cfg.RoutingAPI.ClientKeyFile),
-			).Client(
-				tlsconfig.WithAuthorityFromFile(cfg.RoutingAPI.CACertFile),
-			)
-			if err != nil {
-				logger.Fatal("failed-to-create-routing-api-tls-config", err)
-			}
-			routingAPIClient = routing_api.NewClientWithTLSConfig(routingAPIAddress, tlsConfig)
-		} else {
-			routingAPIClient = routing_api.NewClient(routingAPIAddress, false)
-		}
+		routingAPIEmitter = emitter.NewRoutingAPIEmitter(logger.Session("tcp"), routingAPIClient, uaaTokenFetcher, int(routeTTL.Seconds()))
+	}
 
-		routingAPIEmitter = emitter.NewRoutingAPIEmitter(tcpLogger, routingAPIClient, uaaTokenFetcher, int(routeTTL.Seconds()))
+	if cfg.HTTPRoutesViaRoutingAPI {
+		httpRouteTTL := time.Duration(cfg.HTTPRouteTTL)
+		if httpRouteTTL == 0 {
+			httpRouteTTL = defaultHTTPRouteTTL
+		}
+		// the routing api route model has no instance, tag, TLS, isolation
+		// segment or load balancing fields, so routes using them stay on NATS
+		logger.Info("registering-http-routes-via-routing-api", lager.Data{
+			"ttl":                         httpRouteTTL.Seconds(),
+			"nats-for-unsupported-fields": true,
+		})
+		natsEmitter = emitter.NewRoutingAPIHTTPEmitter(logger.Session("http"), routingAPIClient, uaaTokenFetcher, int(httpRouteTTL.Seconds()), natsEmitter)
 	}
 
-	unregistrationCache := unregistration.NewCache(logger)
+	if cfg.DryRunDiffLogFile != "" {
+		diffLogFile, err := os.OpenFile(cfg.DryRunDiffLogFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			logger.Fatal("failed-to-open-diff-log", err, lager.Data{"path": cfg.DryRunDiffLogFile})
+		}
+		logger.Info("dry-run-writing-diff-log", lager.Data{"path": cfg.DryRunDiffLogFile})
 
-	handler := routehandlers.NewHandler(table, natsEmitter, routingAPIEmitter, localMode, metronClient, unregistrationCache)
+		diffLog := emitter.NewDiffLog(diffLogFile, clock)
+		natsEmitter = diffLog.NATSEmitter(cfg.EnableInternalEmitter)
+		if routingAPIEmitter != nil {
+			routingAPIEmitter = diffLog.RoutingAPIEmitter()
+		}
+	}
+
+	var handlerOptions []routehandlers.Option
+	var unregistrationGuard *routehandlers.UnregistrationGuard
+	if cfg.MaxSyncUnregistrationPercent > 0 || cfg.MaxSyncUnregistrations > 0 {
+		unregistrationGuard = routehandlers.NewUnregistrationGuard(cfg.MaxSyncUnregistrationPercent, cfg.MaxSyncUnregistrations)
+		handlerOptions = append(handlerOptions, routehandlers.WithUnregistrationGuard(unregistrationGuard))
+	}
+	if cfg.TCPRouteReconciliation.Enabled {
+		switch {
+		case !cfg.EnableTCPEmitter:
+			logger.Fatal("invalid-tcp-route-reconciliation", errors.New("tcp route reconciliation requires the tcp emitter"))
+		case localMode:
+			logger.Fatal("invalid-tcp-route-reconciliation", errors.New("tcp route reconciliation is not supported in local mode"))
+		case len(cfg.TCPRouteReconciliation.RouterGroupGUIDs) == 0:
+			logger.Fatal("invalid-tcp-route-reconciliation", errors.New("tcp route reconciliation requires router_group_guids"))
+		case cfg.DryRunDiffLogFile != "":
+			logger.Info("dry-run-skipping-tcp-route-reconciliation")
+		default:
+			reconciler := emitter.NewTCPRouteReconciler(logger.Session("tcp"), routingAPIClient, uaaTokenFetcher, int(routeTTL.Seconds()), cfg.TCPRouteReconciliation.RouterGroupGUIDs)
+			handlerOptions = append(handlerOptions, routehandlers.WithTCPRouteReconciler(reconciler))
+		}
+	}
+	// a dry run emitter must not serve or send its routes anywhere but the
+	// diff log
+	dryRun := cfg.DryRunDiffLogFile != ""
+	if dryRun && (cfg.XDS.ListenAddress != "" || cfg.InternalDNS.ListenAddress != "" ||
+		len(cfg.RouteSinks.Files) > 0 || len(cfg.RouteSinks.Webhooks) > 0 || len(cfg.RouteSinks.Renderers) > 0) {
+		logger.Info("dry-run-skipping-route-sinks")
+	}
+	var xdsServer *xds.Server
+	var extraSinks []emitter.NamedRouteSink
+	if cfg.XDS.ListenAddress != "" && !dryRun {
+		xdsServer = initializeXDSServer(logger, cfg.XDS)
+		extraSinks = append(extraSinks, emitter.NamedRouteSink{Name: xdsRouteSinkName, Sink: xdsServer})
+		handlerOptions = append(handlerOptions, routehandlers.WithRouteSnapshotSink(xdsServer))
+	}
+	var internalDNSServer *internaldns.Server
+	if cfg.InternalDNS.ListenAddress != "" && !dryRun {
+		ttl := time.Duration(cfg.InternalDNS.TTL)
+		if ttl <= 0 {
+			ttl = defaultInternalDNSTTL
+		}
+		internalDNSServer = internaldns.NewServer(logger, table, cfg.InternalDNS.ListenAddress, ttl)
+		extraSinks = append(extraSinks, emitter.NamedRouteSink{Name: internalDNSRouteSinkName, Sink: internalDNSServer})
+	}
+	// renderers of a dry run emitter would rewrite and reload the
+	// configuration of live proxies
+	rendererConfigs := cfg.RouteSinks.Renderers
+	if dryRun {
+		rendererConfigs = nil
+	}
+	var renderers []*configrenderer.Renderer
+	for _, rendererConfig := range rendererConfigs {
+		debounce := time.Duration(rendererConfig.Debounce)
+		if debounce <= 0 {
+			debounce = defaultConfigRendererDebounce
+		}
+		var rendererOptions []configrenderer.Option
+		if rendererConfig.AllowEmpty {
+			rendererOptions = append(rendererOptions, configrenderer.WithAllowEmpty())
+		}
+		renderer, err := configrenderer.NewRenderer(logger, table, clock, rendererConfig.TemplateFile, rendererConfig.OutputFile, rendererConfig.ReloadCommand, debounce, rendererOptions...)
+		if err != nil {
+			logger.Fatal("failed-to-create-config-renderer", err, lager.Data{"sink": rendererConfig.Name})
+		}
+		renderers = append(renderers, renderer)
+		handlerOptions = append(handlerOptions, routehandlers.WithSyncListener(renderer))
+		extraSinks = append(extraSinks, emitter.NamedRouteSink{Name: rendererConfig.Name, Sink: renderer})
+	}
+	if !dryRun && (len(cfg.RouteSinks.Files) > 0 || len(cfg.RouteSinks.Webhooks) > 0 || len(extraSinks) > 0) {
+		routeSink := initializeRouteSink(logger, cfg, clock, metronClient, table, extraSinks...)
+		handlerOptions = append(handlerOptions, routehandlers.WithRouteSink(routeSink))
+	}
+	handler := routehandlers.NewHandler(table, natsEmitter, routingAPIEmitter, localMode, metronClient, unregistrationCache, handlerOptions...)
 
 	watcher := watcher.NewWatcher(
 		cfg.CellID,
@@ -136,6 +286,7 @@ func main() {
 		internalScheduler.EmitCh(),
 		logger,
 		metronClient,
+		cfg.EventWorkers,
 	)
 
 	healthHandler := func(resp http.ResponseWriter, req *http.Request) {
@@ -149,6 +300,15 @@ func main() {
 		{Name: "unregistration", Runner: unregistrationSender},
 	}
 
+	if cfg.AdminAddress != "" {
+		// the admin api can force held syncs through without authentication
+		if unregistrationGuard != nil && !introspection.IsLoopbackAddress(cfg.AdminAddress) {
+			logger.Fatal("invalid-admin-address", errors.New("the admin address must be a loopback address when the unregistration guard is enabled"), lager.Data{"address": cfg.AdminAddress})
+		}
+		adminServer := http_server.New(cfg.AdminAddress, introspection.NewHandler(logger, table, handler, unregistrationGuard))
+		members = append(members, grouper.Member{Name: "admin-api", Runner: adminServer})
+	}
+
 	if cfg.CellID == "" && cfg.LocketEnabled {
 		locketClient, err := locket.NewClient(logger, cfg.ClientLocketConfig)
 		if err != nil {
@@ -181,12 +341,48 @@ func main() {
 		)
 	}
 
+	if cfg.RoutingTableSnapshotFile != "" {
+		maxAge := time.Duration(cfg.RoutingTableSnapshotMaxAge)
+		if maxAge <= 0 {
+			maxAge = defaultRoutingTableSnapshotMaxAge
+		}
+		// the table is restored once the lock is held, so that a snapshot
+		// restored by a passive emitter does not go stale while it waits
+		members = append(members, grouper.Member{
+			Name:   "routing-table-restorer",
+			Runner: routingTableRestorer(logger, clock, table, cfg.RoutingTableSnapshotFile, maxAge, xdsServer),
+		})
+	}
+
 	members = append(members,
 		grouper.Member{Name: "watcher", Runner: watcher},
 		grouper.Member{Name: "external-scheduler", Runner: externalScheduler},
 		grouper.Member{Name: "syncer", Runner: syncer},
 	)
 
+	// only the emitter holding the lock persists its table, serves xDS and
+	// DNS and renders configuration, as it is the only one whose routes are
+	// up to date
+	if cfg.RoutingTableSnapshotFile != "" {
+		snapshotInterval := time.Duration(cfg.RoutingTableSnapshotInterval)
+		if snapshotInterval <= 0 {
+			snapshotInterval = defaultRoutingTableSnapshotInterval
+		}
+		members = append(members, grouper.Member{
+			Name:   "routing-table-persister",
+			Runner: persister.NewPersister(logger, clock, table, cfg.RoutingTableSnapshotFile, snapshotInterval),
+		})
+	}
+	if xdsServer != nil {
+		members = append(members, grouper.Member{Name: "xds-server", Runner: xdsServer})
+	}
+	if internalDNSServer != nil {
+		members = append(members, grouper.Member{Name: "internal-dns-server", Runner: internalDNSServer})
+	}
+	for i, renderer := range renderers {
+		members = append(members, grouper.Member{Name: fmt.Sprintf("config-renderer-%d", i), Runner: renderer})
+	}
+
 	if cfg.EnableInternalEmitter {
 		members = append(members, grouper.Member{Name: "internal-scheduler", Runner: internalScheduler})
 	}
@@ -224,6 +420,25 @@ func lockRunner(logger lager.Logger, clk clock.Clock, locks []grouper.Member) if
 	}
 }
 
+func newRoutingAPIClient(logger lager.Logger, c *config.RouteEmitterConfig) routing_api.Client {
+	routingAPIAddress := fmt.Sprintf("%s:%d", c.RoutingAPI.URL, c.RoutingAPI.Port)
+	logger.Debug("creating-routing-api-client", lager.Data{"api-location": routingAPIAddress})
+
+	if c.RoutingAPI.ClientCertFile != "" && c.RoutingAPI.ClientKeyFile != "" && c.RoutingAPI.CACertFile != "" {
+		tlsConfig, err := tlsconfig.Build(
+			tlsconfig.WithInternalServiceDefaults(),
+			tlsconfig.WithIdentityFromFile(c.RoutingAPI.ClientCertFile, c.RoutingAPI.ClientKeyFile),
+		).Client(
+			tlsconfig.WithAuthorityFromFile(c.RoutingAPI.CACertFile),
+		)
+		if err != nil {
+			logger.Fatal("failed-to-create-routing-api-tls-config", err)
+		}
+		return routing_api.NewClientWithTLSConfig(routingAPIAddress, tlsConfig)
+	}
+	return routing_api.NewClient(routingAPIAddress, false)
+}
+
 func newUaaTokenFetcher(logger lager.Logger, c *config.RouteEmitterConfig, klok clock.Clock) uaaclient.TokenFetcher {
 	cfg := uaaclient.Config{}
 	if c.RoutingAPI.AuthEnabled {
@@ -274,19 +489,133 @@ func initializeMetron(logger lager.Logger, locketConfig config.RouteEmitterConfi
 	return client, nil
 }
 
+func initializeRouteSink(
+	logger lager.Logger,
+	cfg config.RouteEmitterConfig,
+	klok clock.Clock,
+	metronClient loggingclient.IngressClient,
+	table routingtable.RoutingTable,
+	extraSinks ...emitter.NamedRouteSink,
+) emitter.RouteSink {
+	sinks := extraSinks
+
+	for _, fileSink := range cfg.RouteSinks.Files {
+		file, err := os.OpenFile(fileSink.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			logger.Fatal("failed-to-open-route-sink-file", err, lager.Data{"sink": fileSink.Name, "path": fileSink.Path})
+		}
+		diffLog := emitter.NewDiffLog(file, klok)
+		sinks = append(sinks, emitter.NamedRouteSink{Name: fileSink.Name, Sink: diffLog.RouteSink(cfg.EnableInternalEmitter)})
+	}
+
+	for _, webhookSink := range cfg.RouteSinks.Webhooks {
+		timeout := time.Duration(webhookSink.Timeout)
+		if timeout <= 0 {
+			timeout = defaultWebhookRouteSinkTimeout
+		}
+		client := &http.Client{Timeout: timeout}
+		sinks = append(sinks, emitter.NamedRouteSink{Name: webhookSink.Name, Sink: emitter.WebhookRouteSink(client, webhookSink.URL)})
+	}
+
+	names := map[string]bool{}
+	for _, sink := range sinks {
+		if sink.Name == "" || names[sink.Name] {
+			logger.Fatal("invalid-route-sink-name", errors.New("route sink names must be unique and not empty"), lager.Data{"sink": sink.Name})
+		}
+		names[sink.Name] = true
+	}
+
+	logger.Info("emitting-to-route-sinks", lager.Data{"sinks": len(sinks)})
+	return emitter.NewFanOutSink(logger, metronClient, klok, time.Duration(cfg.RouteSinks.EmitTimeout), table, sinks...)
+}
+
+func initializeXDSServer(logger lager.Logger, xdsConfig config.XDSConfig) *xds.Server {
+	httpListenerPort := xdsConfig.HTTPListenerPort
+	if httpListenerPort == 0 {
+		httpListenerPort = defaultXDSHTTPListenerPort
+	}
+
+	var options []xds.Option
+	if xdsConfig.RouterGroupGUID != "" {
+		options = append(options, xds.WithRouterGroup(xdsConfig.RouterGroupGUID))
+	}
+	if xdsConfig.ServerCertFile != "" && xdsConfig.ServerKeyFile != "" && xdsConfig.CACertFile != "" {
+		tlsConfig, err := tlsconfig.Build(
+			tlsconfig.WithInternalServiceDefaults(),
+			tlsconfig.WithIdentityFromFile(xdsConfig.ServerCertFile, xdsConfig.ServerKeyFile),
+		).Server(
+			tlsconfig.WithClientAuthenticationFromFile(xdsConfig.CACertFile),
+		)
+		if err != nil {
+			logger.Fatal("failed-to-create-xds-tls-config", err)
+		}
+		options = append(options, xds.WithTLSConfig(tlsConfig))
+	}
+
+	return xds.NewServer(logger, xdsConfig.ListenAddress, httpListenerPort, options...)
+}
+
 func initializeNatsEmitter(
 	logger lager.Logger,
 	natsClient diegonats.NATSClient,
 	routeEmittingWorkers int,
 	metronClient loggingclient.IngressClient,
 	emitInternalRoutes bool,
+	options ...emitter.NATSEmitterOption,
 ) emitter.NATSEmitter {
 	workPool, err := workpool.NewWorkPool(routeEmittingWorkers)
 	if err != nil {
 		logger.Fatal("failed-to-construct-nats-emitter-workpool", err, lager.Data{"num-workers": routeEmittingWorkers}) // should never happen
 	}
 
-	return emitter.NewNATSEmitter(natsClient, workPool, logger, metronClient, emitInternalRoutes)
+	return emitter.NewNATSEmitter(natsClient, workPool, logger, metronClient, emitInternalRoutes, options...)
+}
+
+func routingTableRestorer(logger lager.Logger, klok clock.Clock, table routingtable.RoutingTable, path string, maxAge time.Duration, xdsServer *xds.Server) ifrit.Runner {
+	return ifrit.RunFunc(func(signals <-chan os.Signal, ready chan<- struct{}) error {
+		if restoreRoutingTable(logger, klok, table, path, maxAge) && xdsServer != nil {
+			// serve the restored routes until the first sync completes
+			routingEvents, messagesToEmit := table.GetExternalRoutingEvents()
+			err := xdsServer.Replace(messagesToEmit, routingEvents)
+			if err != nil {
+				return err
+			}
+		}
+		close(ready)
+		<-signals
+		return nil
+	})
+}
+
+// restoreRoutingTable restores the table from the snapshot at path, unless
+// the last sync of the snapshot's routes is older than maxAge: the addresses
+// of an old snapshot may have been reused by other apps since. It returns
+// whether the table was restored.
+func restoreRoutingTable(logger lager.Logger, klok clock.Clock, table routingtable.RoutingTable, path string, maxAge time.Duration) bool {
+	logger = logger.Session("restore-routing-table", lager.Data{"path": path})
+
+	snapshot, err := routingtable.ReadSnapshot(path)
+	if os.IsNotExist(err) {
+		logger.Info("no-snapshot-found")
+		return false
+	}
+	if err != nil {
+		logger.Error("failed-to-read-snapshot", err)
+		return false
+	}
+
+	if snapshot.SyncedAt.IsZero() {
+		logger.Info("snapshot-never-synced")
+		return false
+	}
+	age := klok.Since(snapshot.SyncedAt)
+	if age > maxAge {
+		logger.Info("snapshot-too-old", lager.Data{"age": age.String(), "max-age": maxAge.String()})
+		return false
+	}
+
+	table.Restore(logger, snapshot)
+	return true
 }
 
 func initializeBBSClient(
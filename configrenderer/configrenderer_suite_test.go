@@ -0,0 +1,13 @@
+package configrenderer_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestConfigRenderer(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Config Renderer Suite")
+}
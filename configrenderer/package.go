@@ -0,0 +1 @@
+package configrenderer // import "code.cloudfoundry.org/route-emitter/configrenderer"
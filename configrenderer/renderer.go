@@ -0,0 +1,232 @@
+package configrenderer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync/atomic"
+	"text/template"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/lager/v3"
+	"code.cloudfoundry.org/route-emitter/emitter"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+)
+
+const reloadTimeout = 30 * time.Second
+
+// ErrEmptyRender is returned instead of replacing a file that has content
+// with a render of no routes, which more likely comes from a table that lost
+// its routes than from a foundation without any.
+var ErrEmptyRender = errors.New("refusing to replace the file with a render of no routes")
+
+// Renderer renders the HTTP and TCP routes of the routing table into a file
+// with a Go template, e.g. an HAProxy or nginx configuration. It is a route
+// sink: changes to external routes schedule a render, and renders happen at
+// most once per debounce interval. Nothing is rendered before the first sync
+// completed, as the table is incomplete until then. The file is replaced
+// atomically, and the reload command runs after every render that changed it.
+type Renderer struct {
+	logger        lager.Logger
+	table         routingtable.RoutingTable
+	clock         clock.Clock
+	template      *template.Template
+	outputFile    string
+	reloadCommand []string
+	debounce      time.Duration
+	allowEmpty    bool
+
+	synced       int32
+	changes      chan struct{}
+	lastRendered []byte
+}
+
+var _ emitter.RouteSink = new(Renderer)
+
+type Option func(*Renderer)
+
+// WithAllowEmpty lets a render of no routes replace a file with content.
+func WithAllowEmpty() Option {
+	return func(r *Renderer) {
+		r.allowEmpty = true
+	}
+}
+
+// NewRenderer parses the template file.
+func NewRenderer(
+	logger lager.Logger,
+	table routingtable.RoutingTable,
+	clock clock.Clock,
+	templateFile string,
+	outputFile string,
+	reloadCommand []string,
+	debounce time.Duration,
+	options ...Option,
+) (*Renderer, error) {
+	tmpl, err := template.New(filepath.Base(templateFile)).
+		Funcs(template.FuncMap{"identifier": identifier}).
+		ParseFiles(templateFile)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Renderer{
+		logger:        logger.Session("config-renderer", lager.Data{"output-file": outputFile}),
+		table:         table,
+		clock:         clock,
+		template:      tmpl,
+		outputFile:    outputFile,
+		reloadCommand: reloadCommand,
+		debounce:      debounce,
+		changes:       make(chan struct{}, 1),
+	}
+	for _, option := range options {
+		option(r)
+	}
+	return r, nil
+}
+
+// SyncCompleted schedules a render. The first one lets the renderer render
+// the table.
+func (r *Renderer) SyncCompleted() {
+	if atomic.CompareAndSwapInt32(&r.synced, 0, 1) {
+		r.logger.Info("first-sync-completed")
+	}
+	r.scheduleRender()
+}
+
+// Emit schedules a render if external routes changed after the first sync.
+func (r *Renderer) Emit(messagesToEmit routingtable.MessagesToEmit, routeMappings routingtable.TCPRouteMappings) error {
+	if atomic.LoadInt32(&r.synced) == 0 {
+		return nil
+	}
+	if len(messagesToEmit.RegistrationMessages) == 0 &&
+		len(messagesToEmit.UnregistrationMessages) == 0 &&
+		len(routeMappings.Registrations) == 0 &&
+		len(routeMappings.Unregistrations) == 0 {
+		return nil
+	}
+	r.scheduleRender()
+	return nil
+}
+
+func (r *Renderer) scheduleRender() {
+	select {
+	case r.changes <- struct{}{}:
+	default:
+	}
+}
+
+func (r *Renderer) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	close(ready)
+
+	var timer <-chan time.Time
+	for {
+		select {
+		case <-signals:
+			return nil
+		case <-r.changes:
+			if timer == nil {
+				timer = r.clock.NewTimer(r.debounce).C()
+			}
+		case <-timer:
+			timer = nil
+			err := r.render()
+			if err != nil {
+				r.logger.Error("failed-to-render", err)
+			}
+		}
+	}
+}
+
+// render renders the whole table, as the changes only tell that it changed.
+func (r *Renderer) render() error {
+	routeMappings, messagesToEmit := r.table.GetExternalRoutingEvents()
+
+	data := NewTemplateData(messagesToEmit, routeMappings)
+	var rendered bytes.Buffer
+	err := r.template.Execute(&rendered, data)
+	if err != nil {
+		return err
+	}
+	if r.lastRendered != nil && bytes.Equal(rendered.Bytes(), r.lastRendered) {
+		r.logger.Debug("unchanged")
+		return nil
+	}
+
+	if len(data.HTTP) == 0 && len(data.TCP) == 0 && !r.allowEmpty {
+		info, err := os.Stat(r.outputFile)
+		if err == nil && info.Size() > 0 {
+			return ErrEmptyRender
+		}
+	}
+
+	err = writeFileAtomically(r.outputFile, rendered.Bytes())
+	if err != nil {
+		return err
+	}
+	r.logger.Info("rendered", lager.Data{"bytes": rendered.Len()})
+
+	// a render that failed to reload is written and reloaded again by the
+	// next render, even if it is unchanged
+	err = r.reload()
+	if err != nil {
+		return err
+	}
+	r.lastRendered = rendered.Bytes()
+	return nil
+}
+
+func (r *Renderer) reload() error {
+	if len(r.reloadCommand) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), reloadTimeout)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, r.reloadCommand[0], r.reloadCommand[1:]...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("reload command failed: %w: %s", err, output)
+	}
+	r.logger.Info("reloaded")
+	return nil
+}
+
+// writeFileAtomically replaces the file at path, so that readers never see a
+// partially written file.
+func writeFileAtomically(path string, data []byte) error {
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	_, err = tmpFile.Write(data)
+	if err != nil {
+		tmpFile.Close()
+		return err
+	}
+
+	err = tmpFile.Sync()
+	if err != nil {
+		tmpFile.Close()
+		return err
+	}
+
+	err = tmpFile.Close()
+	if err != nil {
+		return err
+	}
+
+	err = os.Chmod(tmpFile.Name(), 0644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmpFile.Name(), path)
+}
@@ -0,0 +1,205 @@
+package configrenderer_test
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"code.cloudfoundry.org/route-emitter/configrenderer"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/route-emitter/routingtable/fakeroutingtable"
+	tcpmodels "code.cloudfoundry.org/routing-api/models"
+	"github.com/tedsuo/ifrit"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+const haproxyTemplate = `{{range .HTTP}}backend {{identifier .URI}}
+{{range .Backends}}  server {{.Host}}:{{.Port}}
+{{end}}{{end}}{{range .TCP}}listen tcp_{{.Port}}
+{{range .Backends}}  server {{.Host}}:{{.Port}}
+{{end}}{{end}}`
+
+var _ = Describe("Renderer", func() {
+	var (
+		dir           string
+		table         *fakeroutingtable.FakeRoutingTable
+		clock         *fakeclock.FakeClock
+		reloadCommand []string
+		renderer      *configrenderer.Renderer
+		process       ifrit.Process
+		outputFile    string
+		reloadsFile   string
+	)
+
+	debounce := time.Second
+
+	setRoutes := func(hosts ...string) {
+		messages := routingtable.MessagesToEmit{}
+		for _, host := range hosts {
+			messages.RegistrationMessages = append(messages.RegistrationMessages, routingtable.RegistryMessage{
+				URIs: []string{"foo.com", "foo.com/bar"}, Host: host, Port: 61001,
+			})
+		}
+		table.GetExternalRoutingEventsReturns(routingtable.TCPRouteMappings{
+			Registrations: []tcpmodels.TcpRouteMapping{tcpmodels.NewTcpRouteMapping("router-group", 5222, "1.1.1.1", 61002, 0)},
+		}, messages)
+	}
+
+	output := func() string {
+		contents, err := os.ReadFile(outputFile)
+		if err != nil {
+			return ""
+		}
+		return string(contents)
+	}
+
+	reloads := func() int {
+		contents, err := os.ReadFile(reloadsFile)
+		if err != nil {
+			return 0
+		}
+		return len(contents)
+	}
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+		outputFile = filepath.Join(dir, "haproxy.cfg")
+		reloadsFile = filepath.Join(dir, "reloads")
+		templateFile := filepath.Join(dir, "haproxy.cfg.tmpl")
+		Expect(os.WriteFile(templateFile, []byte(haproxyTemplate), 0644)).To(Succeed())
+
+		table = &fakeroutingtable.FakeRoutingTable{}
+		setRoutes("1.1.1.1")
+		clock = fakeclock.NewFakeClock(time.Now())
+		reloadCommand = []string{"sh", "-c", "printf . >> " + reloadsFile}
+
+		var err error
+		renderer, err = configrenderer.NewRenderer(lagertest.NewTestLogger("test"), table, clock, templateFile, outputFile, reloadCommand, debounce)
+		Expect(err).NotTo(HaveOccurred())
+		process = ifrit.Background(renderer)
+		Eventually(process.Ready()).Should(BeClosed())
+	})
+
+	AfterEach(func() {
+		process.Signal(os.Interrupt)
+		Eventually(process.Wait()).Should(Receive())
+	})
+
+	It("does not render before the first sync completed", func() {
+		changes := routingtable.MessagesToEmit{RegistrationMessages: []routingtable.RegistryMessage{{Host: "1.1.1.1"}}}
+		Expect(renderer.Emit(changes, routingtable.TCPRouteMappings{})).To(Succeed())
+		Consistently(clock.WatcherCount).Should(Equal(0))
+		Expect(output()).To(BeEmpty())
+	})
+
+	It("renders the table after the first sync and the debounce interval, and reloads", func() {
+		renderer.SyncCompleted()
+		Consistently(output).Should(BeEmpty())
+
+		clock.WaitForWatcherAndIncrement(debounce)
+		Eventually(output).Should(Equal(`backend foo_com_41ad0598
+  server 1.1.1.1:61001
+backend foo_com_bar_1c57fcea
+  server 1.1.1.1:61001
+listen tcp_5222
+  server 1.1.1.1:61002
+`))
+		Eventually(reloads).Should(Equal(1))
+	})
+
+	Context("after the first render", func() {
+		BeforeEach(func() {
+			renderer.SyncCompleted()
+			clock.WaitForWatcherAndIncrement(debounce)
+			Eventually(reloads).Should(Equal(1))
+		})
+
+		It("renders once for changes within the debounce interval", func() {
+			setRoutes("1.1.1.1", "1.1.1.2")
+			changes := routingtable.MessagesToEmit{RegistrationMessages: []routingtable.RegistryMessage{{Host: "1.1.1.2"}}}
+			Expect(renderer.Emit(changes, routingtable.TCPRouteMappings{})).To(Succeed())
+			Expect(renderer.Emit(changes, routingtable.TCPRouteMappings{})).To(Succeed())
+
+			clock.WaitForWatcherAndIncrement(debounce)
+			Eventually(output).Should(ContainSubstring("server 1.1.1.2:61001"))
+			Eventually(reloads).Should(Equal(2))
+			Consistently(reloads).Should(Equal(2))
+		})
+
+		It("does not render for emits without external route changes", func() {
+			Expect(renderer.Emit(routingtable.MessagesToEmit{
+				InternalRegistrationMessages: []routingtable.RegistryMessage{{Host: "1.1.1.2"}},
+			}, routingtable.TCPRouteMappings{})).To(Succeed())
+			Consistently(clock.WatcherCount).Should(Equal(0))
+		})
+
+		It("does not rewrite or reload when the render is unchanged", func() {
+			changes := routingtable.MessagesToEmit{RegistrationMessages: []routingtable.RegistryMessage{{Host: "1.1.1.1"}}}
+			Expect(renderer.Emit(changes, routingtable.TCPRouteMappings{})).To(Succeed())
+
+			clock.WaitForWatcherAndIncrement(debounce)
+			Eventually(table.GetExternalRoutingEventsCallCount).Should(Equal(2))
+			Consistently(reloads).Should(Equal(1))
+		})
+	})
+
+	Context("when the table has no routes", func() {
+		BeforeEach(func() {
+			table.GetExternalRoutingEventsReturns(routingtable.TCPRouteMappings{}, routingtable.MessagesToEmit{})
+			Expect(os.WriteFile(outputFile, []byte("backend previous\n"), 0644)).To(Succeed())
+		})
+
+		It("does not replace a file with content", func() {
+			renderer.SyncCompleted()
+			clock.WaitForWatcherAndIncrement(debounce)
+			Eventually(table.GetExternalRoutingEventsCallCount).Should(Equal(1))
+			Consistently(output).Should(Equal("backend previous\n"))
+			Expect(reloads()).To(Equal(0))
+		})
+
+		Context("when empty renders are allowed", func() {
+			BeforeEach(func() {
+				process.Signal(os.Interrupt)
+				Eventually(process.Wait()).Should(Receive())
+
+				var err error
+				renderer, err = configrenderer.NewRenderer(lagertest.NewTestLogger("test"), table, clock, filepath.Join(dir, "haproxy.cfg.tmpl"), outputFile, reloadCommand, debounce, configrenderer.WithAllowEmpty())
+				Expect(err).NotTo(HaveOccurred())
+				process = ifrit.Background(renderer)
+				Eventually(process.Ready()).Should(BeClosed())
+			})
+
+			It("replaces the file", func() {
+				renderer.SyncCompleted()
+				clock.WaitForWatcherAndIncrement(debounce)
+				Eventually(reloads).Should(Equal(1))
+				Expect(output()).To(BeEmpty())
+			})
+		})
+	})
+
+	Context("when the template is invalid", func() {
+		It("returns an error", func() {
+			templateFile := filepath.Join(dir, "invalid.tmpl")
+			Expect(os.WriteFile(templateFile, []byte("{{range}"), 0644)).To(Succeed())
+			_, err := configrenderer.NewRenderer(lagertest.NewTestLogger("test"), table, clock, templateFile, outputFile, nil, debounce)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
+
+var _ = Describe("TemplateData", func() {
+	It("carries the TLS port of TCP backends", func() {
+		mapping := tcpmodels.NewTcpRouteMapping("router-group", 5222, "1.1.1.1", 61002, 0)
+		mapping.HostTLSPort = 61443
+		data := configrenderer.NewTemplateData(routingtable.MessagesToEmit{}, routingtable.TCPRouteMappings{
+			Registrations: []tcpmodels.TcpRouteMapping{mapping},
+		})
+		Expect(data.TCP).To(HaveLen(1))
+		Expect(data.TCP[0].Backends).To(ConsistOf(configrenderer.Backend{Host: "1.1.1.1", Port: 61002, TLSPort: 61443}))
+	})
+})
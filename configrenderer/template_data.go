@@ -0,0 +1,137 @@
+package configrenderer
+
+import (
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"sort"
+	"strings"
+
+	"code.cloudfoundry.org/route-emitter/routingtable"
+)
+
+// TemplateData is what templates are rendered with: every HTTP route and TCP
+// route of the routing table, sorted so that renders of the same table are
+// identical.
+type TemplateData struct {
+	HTTP []HTTPRoute
+	TCP  []TCPRoute
+}
+
+// HTTPRoute is a route URI. Path is empty or starts with a slash.
+type HTTPRoute struct {
+	URI      string
+	Hostname string
+	Path     string
+	Backends []Backend
+}
+
+type TCPRoute struct {
+	RouterGroupGUID string
+	Port            uint32
+	SniHostname     string
+	Backends        []Backend
+}
+
+type Backend struct {
+	Host    string
+	Port    uint32
+	TLSPort uint32
+}
+
+func NewTemplateData(messagesToEmit routingtable.MessagesToEmit, routeMappings routingtable.TCPRouteMappings) TemplateData {
+	httpRoutes := map[string]*HTTPRoute{}
+	for _, message := range messagesToEmit.RegistrationMessages {
+		backend := Backend{Host: message.Host, Port: message.Port, TLSPort: message.TlsPort}
+		for _, uri := range message.URIs {
+			uri = strings.ToLower(uri)
+			route, ok := httpRoutes[uri]
+			if !ok {
+				hostname, path := uri, ""
+				if i := strings.Index(uri, "/"); i >= 0 {
+					hostname, path = uri[:i], uri[i:]
+				}
+				route = &HTTPRoute{URI: uri, Hostname: hostname, Path: path}
+				httpRoutes[uri] = route
+			}
+			route.Backends = appendBackend(route.Backends, backend)
+		}
+	}
+
+	type tcpKey struct {
+		routerGroupGUID string
+		port            uint32
+		sniHostname     string
+	}
+	tcpRoutes := map[tcpKey]*TCPRoute{}
+	for _, mapping := range routeMappings.Registrations {
+		key := tcpKey{routerGroupGUID: mapping.RouterGroupGuid, port: uint32(mapping.ExternalPort)}
+		if mapping.SniHostname != nil {
+			key.sniHostname = *mapping.SniHostname
+		}
+		route, ok := tcpRoutes[key]
+		if !ok {
+			route = &TCPRoute{RouterGroupGUID: key.routerGroupGUID, Port: key.port, SniHostname: key.sniHostname}
+			tcpRoutes[key] = route
+		}
+		backend := Backend{Host: mapping.HostIP, Port: uint32(mapping.HostPort)}
+		if mapping.HostTLSPort > 0 {
+			backend.TLSPort = uint32(mapping.HostTLSPort)
+		}
+		route.Backends = appendBackend(route.Backends, backend)
+	}
+
+	data := TemplateData{}
+	for _, route := range httpRoutes {
+		sortBackends(route.Backends)
+		data.HTTP = append(data.HTTP, *route)
+	}
+	sort.Slice(data.HTTP, func(i, j int) bool {
+		return data.HTTP[i].URI < data.HTTP[j].URI
+	})
+
+	for _, route := range tcpRoutes {
+		sortBackends(route.Backends)
+		data.TCP = append(data.TCP, *route)
+	}
+	sort.Slice(data.TCP, func(i, j int) bool {
+		a, b := data.TCP[i], data.TCP[j]
+		if a.Port != b.Port {
+			return a.Port < b.Port
+		}
+		if a.RouterGroupGUID != b.RouterGroupGUID {
+			return a.RouterGroupGUID < b.RouterGroupGUID
+		}
+		return a.SniHostname < b.SniHostname
+	})
+	return data
+}
+
+func appendBackend(backends []Backend, backend Backend) []Backend {
+	for _, existing := range backends {
+		if existing == backend {
+			return backends
+		}
+	}
+	return append(backends, backend)
+}
+
+func sortBackends(backends []Backend) {
+	sort.Slice(backends, func(i, j int) bool {
+		if backends[i].Host != backends[j].Host {
+			return backends[i].Host < backends[j].Host
+		}
+		return backends[i].Port < backends[j].Port
+	})
+}
+
+var nonIdentifierChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// identifier turns a route into a name that is safe to use as an HAProxy
+// backend or nginx upstream name. A hash of the route is appended, as routes
+// differing only in replaced characters would get the same name otherwise.
+func identifier(s string) string {
+	hash := fnv.New32a()
+	hash.Write([]byte(s))
+	return fmt.Sprintf("%s_%08x", nonIdentifierChars.ReplaceAllString(s, "_"), hash.Sum32())
+}
@@ -0,0 +1,122 @@
+package emitter
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+)
+
+const (
+	NATSDiffLogOutput       = "nats"
+	RoutingAPIDiffLogOutput = "routing-api"
+)
+
+// DiffLogEntry is one line of a diff log. It holds everything one call to
+// Emit would have sent to its output.
+type DiffLogEntry struct {
+	Timestamp     int64                          `json:"timestamp"`
+	Output        string                         `json:"output"`
+	Messages      *routingtable.MessagesToEmit   `json:"messages,omitempty"`
+	RouteMappings *routingtable.TCPRouteMappings `json:"route_mappings,omitempty"`
+}
+
+// DiffLog writes the messages and route mappings given to its emitters to a
+// writer as JSON lines instead of sending them, so that the output of two
+// route emitters can be compared without either of them changing routes.
+type DiffLog struct {
+	writer io.Writer
+	clock  clock.Clock
+	lock   sync.Mutex
+}
+
+func NewDiffLog(writer io.Writer, clock clock.Clock) *DiffLog {
+	return &DiffLog{
+		writer: writer,
+		clock:  clock,
+	}
+}
+
+// NATSEmitter returns an emitter that logs the messages the NATS emitter
+// would publish. Internal route messages are dropped unless
+// emitInternalRoutes is true, as they would be by the NATS emitter.
+func (d *DiffLog) NATSEmitter(emitInternalRoutes bool) NATSEmitter {
+	return &diffLogNATSEmitter{diffLog: d, emitInternalRoutes: emitInternalRoutes}
+}
+
+// RoutingAPIEmitter returns an emitter that logs the route mappings the
+// routing api emitter would send.
+func (d *DiffLog) RoutingAPIEmitter() RoutingAPIEmitter {
+	return &diffLogRoutingAPIEmitter{diffLog: d}
+}
+
+// RouteSink returns a sink that logs both the messages and the route
+// mappings of every change, so that a diff log can be one of the outputs of
+// a fan-out sink.
+func (d *DiffLog) RouteSink(emitInternalRoutes bool) RouteSink {
+	return &diffLogRouteSink{
+		natsEmitter:       d.NATSEmitter(emitInternalRoutes),
+		routingAPIEmitter: d.RoutingAPIEmitter(),
+	}
+}
+
+func (d *DiffLog) write(entry DiffLogEntry) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	entry.Timestamp = d.clock.Now().UnixNano()
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = d.writer.Write(append(line, '\n'))
+	return err
+}
+
+type diffLogNATSEmitter struct {
+	diffLog            *DiffLog
+	emitInternalRoutes bool
+}
+
+func (e *diffLogNATSEmitter) Emit(messagesToEmit routingtable.MessagesToEmit) error {
+	if !e.emitInternalRoutes {
+		messagesToEmit.InternalRegistrationMessages = nil
+		messagesToEmit.InternalUnregistrationMessages = nil
+	}
+
+	if len(messagesToEmit.RegistrationMessages) == 0 &&
+		len(messagesToEmit.UnregistrationMessages) == 0 &&
+		len(messagesToEmit.InternalRegistrationMessages) == 0 &&
+		len(messagesToEmit.InternalUnregistrationMessages) == 0 {
+		return nil
+	}
+
+	return e.diffLog.write(DiffLogEntry{Output: NATSDiffLogOutput, Messages: &messagesToEmit})
+}
+
+type diffLogRoutingAPIEmitter struct {
+	diffLog *DiffLog
+}
+
+func (e *diffLogRoutingAPIEmitter) Emit(routingEvents routingtable.TCPRouteMappings) error {
+	if len(routingEvents.Registrations) == 0 && len(routingEvents.Unregistrations) == 0 {
+		return nil
+	}
+
+	return e.diffLog.write(DiffLogEntry{Output: RoutingAPIDiffLogOutput, RouteMappings: &routingEvents})
+}
+
+type diffLogRouteSink struct {
+	natsEmitter       NATSEmitter
+	routingAPIEmitter RoutingAPIEmitter
+}
+
+func (s *diffLogRouteSink) Emit(messagesToEmit routingtable.MessagesToEmit, routeMappings routingtable.TCPRouteMappings) error {
+	err := s.natsEmitter.Emit(messagesToEmit)
+	if err != nil {
+		return err
+	}
+	return s.routingAPIEmitter.Emit(routeMappings)
+}
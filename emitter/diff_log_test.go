@@ -0,0 +1,116 @@
+package emitter_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/route-emitter/emitter"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	apimodels "code.cloudfoundry.org/routing-api/models"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DiffLog", func() {
+	var (
+		buffer  *bytes.Buffer
+		clock   *fakeclock.FakeClock
+		diffLog *emitter.DiffLog
+	)
+
+	messagesToEmit := routingtable.MessagesToEmit{
+		RegistrationMessages: []routingtable.RegistryMessage{
+			{URIs: []string{"foo.com"}, Host: "1.1.1.1", Port: 11},
+		},
+		InternalRegistrationMessages: []routingtable.RegistryMessage{
+			{URIs: []string{"foo.apps.internal"}, Host: "1.2.1.1", Port: 11},
+		},
+	}
+
+	entries := func() []emitter.DiffLogEntry {
+		entries := []emitter.DiffLogEntry{}
+		scanner := bufio.NewScanner(bytes.NewReader(buffer.Bytes()))
+		for scanner.Scan() {
+			var entry emitter.DiffLogEntry
+			Expect(json.Unmarshal(scanner.Bytes(), &entry)).To(Succeed())
+			entries = append(entries, entry)
+		}
+		return entries
+	}
+
+	BeforeEach(func() {
+		buffer = &bytes.Buffer{}
+		clock = fakeclock.NewFakeClock(time.Unix(100, 0))
+		diffLog = emitter.NewDiffLog(buffer, clock)
+	})
+
+	Describe("NATSEmitter", func() {
+		It("writes one line per emit", func() {
+			natsEmitter := diffLog.NATSEmitter(true)
+			Expect(natsEmitter.Emit(messagesToEmit)).To(Succeed())
+			clock.Increment(time.Second)
+			Expect(natsEmitter.Emit(messagesToEmit)).To(Succeed())
+
+			logged := entries()
+			Expect(logged).To(HaveLen(2))
+			Expect(logged[0].Output).To(Equal(emitter.NATSDiffLogOutput))
+			Expect(logged[0].Timestamp).To(Equal(time.Unix(100, 0).UnixNano()))
+			Expect(logged[1].Timestamp).To(Equal(time.Unix(101, 0).UnixNano()))
+			Expect(logged[0].Messages.RegistrationMessages).To(Equal(messagesToEmit.RegistrationMessages))
+			Expect(logged[0].Messages.InternalRegistrationMessages).To(Equal(messagesToEmit.InternalRegistrationMessages))
+			Expect(logged[0].RouteMappings).To(BeNil())
+		})
+
+		It("drops internal route messages unless internal routes are emitted", func() {
+			Expect(diffLog.NATSEmitter(false).Emit(messagesToEmit)).To(Succeed())
+
+			logged := entries()
+			Expect(logged).To(HaveLen(1))
+			Expect(logged[0].Messages.RegistrationMessages).To(Equal(messagesToEmit.RegistrationMessages))
+			Expect(logged[0].Messages.InternalRegistrationMessages).To(BeEmpty())
+		})
+
+		It("does not write empty emits", func() {
+			Expect(diffLog.NATSEmitter(true).Emit(routingtable.MessagesToEmit{})).To(Succeed())
+			Expect(buffer.Len()).To(Equal(0))
+		})
+	})
+
+	Describe("RoutingAPIEmitter", func() {
+		It("writes the route mappings", func() {
+			mappings := routingtable.TCPRouteMappings{
+				Registrations: []apimodels.TcpRouteMapping{apimodels.NewTcpRouteMapping("123", 61000, "some-ip-1", 62003, 0)},
+			}
+			Expect(diffLog.RoutingAPIEmitter().Emit(mappings)).To(Succeed())
+
+			logged := entries()
+			Expect(logged).To(HaveLen(1))
+			Expect(logged[0].Output).To(Equal(emitter.RoutingAPIDiffLogOutput))
+			Expect(logged[0].RouteMappings.Registrations).To(HaveLen(1))
+			Expect(logged[0].Messages).To(BeNil())
+		})
+
+		It("does not write empty emits", func() {
+			Expect(diffLog.RoutingAPIEmitter().Emit(routingtable.TCPRouteMappings{})).To(Succeed())
+			Expect(buffer.Len()).To(Equal(0))
+		})
+	})
+
+	Describe("RouteSink", func() {
+		It("writes the messages and the route mappings", func() {
+			mappings := routingtable.TCPRouteMappings{
+				Registrations: []apimodels.TcpRouteMapping{apimodels.NewTcpRouteMapping("123", 61000, "some-ip-1", 62003, 0)},
+			}
+			Expect(diffLog.RouteSink(true).Emit(messagesToEmit, mappings)).To(Succeed())
+
+			logged := entries()
+			Expect(logged).To(HaveLen(2))
+			Expect(logged[0].Output).To(Equal(emitter.NATSDiffLogOutput))
+			Expect(logged[1].Output).To(Equal(emitter.RoutingAPIDiffLogOutput))
+		})
+	})
+})
@@ -3,7 +3,10 @@ package emitter
 import (
 	"encoding/json"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"code.cloudfoundry.org/clock"
 	loggingclient "code.cloudfoundry.org/diego-logging-client"
 	"code.cloudfoundry.org/lager/v3"
 	"code.cloudfoundry.org/route-emitter/diegonats"
@@ -14,6 +17,8 @@ import (
 const (
 	httpRouteNATSMessagesEmittedCounter     = "HTTPRouteNATSMessagesEmitted"
 	internalRouteNATSMessagesEmittedCounter = "InternalRouteNATSMessagesEmitted"
+	natsEmitQueueDepthMetric                = "NATSEmitQueueDepth"
+	natsEmitRateLimitWaitDuration           = "NATSEmitRateLimitWait"
 )
 
 //go:generate counterfeiter -o fakes/fake_nats_emitter.go . NATSEmitter
@@ -27,81 +32,193 @@ type natsEmitter struct {
 	logger             lager.Logger
 	metronClient       loggingclient.IngressClient
 	emitInternalRoutes bool
+	rateLimiter        *RateLimiter
+	queued             int64
+
+	clock               clock.Clock
+	publishRetries      int
+	retryBackoff        time.Duration
+	unregistrationCache UnregistrationCache
+}
+
+// NATSEmitterOption configures optional behaviour of a NATS emitter.
+type NATSEmitterOption func(*natsEmitter)
+
+// WithRateLimiter makes the emitter take a token from the limiter before
+// publishing each message. Unregistrations take priority over registrations.
+func WithRateLimiter(limiter *RateLimiter) NATSEmitterOption {
+	return func(n *natsEmitter) {
+		n.rateLimiter = limiter
+	}
+}
+
+// WithPublishRetries makes the emitter retry a failed publish up to retries
+// times, waiting backoff before the first retry and doubling it for every
+// further one. All publishes of an emit share one retry budget, the time a
+// single publish would wait for all its retries, so that an emit with many
+// failing publishes does not hold up the caller for much longer than that.
+func WithPublishRetries(clock clock.Clock, retries int, backoff time.Duration) NATSEmitterOption {
+	return func(n *natsEmitter) {
+		n.clock = clock
+		n.publishRetries = retries
+		n.retryBackoff = backoff
+	}
+}
+
+// WithUnregistrationCache hands HTTP route unregistrations that still fail
+// after retries to the cache, which sends them again later.
+func WithUnregistrationCache(cache UnregistrationCache) NATSEmitterOption {
+	return func(n *natsEmitter) {
+		n.unregistrationCache = cache
+	}
 }
 
-func NewNATSEmitter(natsClient diegonats.NATSClient, workPool *workpool.WorkPool, logger lager.Logger, metronClient loggingclient.IngressClient, emitInternalRoutes bool) NATSEmitter {
-	return &natsEmitter{
+func NewNATSEmitter(natsClient diegonats.NATSClient, workPool *workpool.WorkPool, logger lager.Logger, metronClient loggingclient.IngressClient, emitInternalRoutes bool, options ...NATSEmitterOption) NATSEmitter {
+	emitter := &natsEmitter{
 		natsClient:         natsClient,
 		workPool:           workPool,
 		logger:             logger.Session("nats-emitter"),
 		metronClient:       metronClient,
 		emitInternalRoutes: emitInternalRoutes,
 	}
+	for _, option := range options {
+		option(emitter)
+	}
+	return emitter
+}
+
+type natsMessage struct {
+	subject string
+	message routingtable.RegistryMessage
 }
 
 func (n *natsEmitter) Emit(messagesToEmit routingtable.MessagesToEmit) error {
-	errors := make(chan error, 1)
-	var wg sync.WaitGroup
-	wg.Add(len(messagesToEmit.RegistrationMessages))
+	messages := make([]natsMessage, 0, len(messagesToEmit.RegistrationMessages)+len(messagesToEmit.UnregistrationMessages))
 	for _, message := range messagesToEmit.RegistrationMessages {
-		n.emit("router.register", message, &wg, errors)
+		messages = append(messages, natsMessage{"router.register", message})
 	}
-
-	wg.Add(len(messagesToEmit.UnregistrationMessages))
 	for _, message := range messagesToEmit.UnregistrationMessages {
-		n.emit("router.unregister", message, &wg, errors)
+		messages = append(messages, natsMessage{"router.unregister", message})
 	}
 
-	var numberOfInternalMessages uint64
-	numberOfMessages := uint64(len(messagesToEmit.RegistrationMessages) + len(messagesToEmit.UnregistrationMessages))
-	numberOfHTTPMessages := uint64(len(messagesToEmit.RegistrationMessages) + len(messagesToEmit.UnregistrationMessages))
 	if n.emitInternalRoutes {
-		wg.Add(len(messagesToEmit.InternalRegistrationMessages))
 		for _, message := range messagesToEmit.InternalRegistrationMessages {
-			n.emit("service-discovery.register", message, &wg, errors)
+			messages = append(messages, natsMessage{"service-discovery.register", message})
 		}
-
-		wg.Add(len(messagesToEmit.InternalUnregistrationMessages))
 		for _, message := range messagesToEmit.InternalUnregistrationMessages {
-			n.emit("service-discovery.unregister", message, &wg, errors)
+			messages = append(messages, natsMessage{"service-discovery.unregister", message})
 		}
+	}
 
-		numberOfInternalMessages = uint64(len(messagesToEmit.InternalRegistrationMessages) + len(messagesToEmit.InternalUnregistrationMessages))
-		numberOfMessages += numberOfInternalMessages
+	results := newPublishResults(n.retryBudget())
+	var wg sync.WaitGroup
+	wg.Add(len(messages))
+	if n.rateLimiter == nil {
+		for _, m := range messages {
+			n.emit(m.subject, m.message, &wg, results)
+		}
+	} else {
+		n.emitRateLimited(messages, &wg, results)
 	}
 
 	wg.Wait()
 
-	select {
-	case finalError := <-errors:
-		return finalError
-	default:
-	}
-
+	// the messages that were published are counted even if others failed
+	numberOfHTTPMessages := results.publishedCount("router.register", "router.unregister")
 	err := n.metronClient.IncrementCounterWithDelta(httpRouteNATSMessagesEmittedCounter, numberOfHTTPMessages)
 	if err != nil {
 		n.logger.Error("cannot-emit-number-of-http-messages", err)
 	}
 
 	if n.emitInternalRoutes {
+		numberOfInternalMessages := results.publishedCount("service-discovery.register", "service-discovery.unregister")
 		err := n.metronClient.IncrementCounterWithDelta(internalRouteNATSMessagesEmittedCounter, numberOfInternalMessages)
 		if err != nil {
 			n.logger.Error("cannot-emit-number-of-internal-messages", err)
 		}
 	}
 
+	if err := results.err(len(messages)); err != nil {
+		n.handleFailures(results)
+		return err
+	}
 	return nil
 }
 
-func (n *natsEmitter) emit(subject string, message routingtable.RegistryMessage, wg *sync.WaitGroup, errors chan error) {
+// retryBudget is the time all publishes of an emit may spend backing off:
+// the backoffs of a single publish that is retried publishRetries times.
+func (n *natsEmitter) retryBudget() time.Duration {
+	var budget time.Duration
+	for attempt := 0; attempt < n.publishRetries; attempt++ {
+		budget += n.retryBackoff << attempt
+	}
+	return budget
+}
+
+// emitRateLimited publishes the unregistrations before the registrations, so
+// that a rate limited emit removes stale routes before it adds new ones.
+func (n *natsEmitter) emitRateLimited(messages []natsMessage, wg *sync.WaitGroup, results *publishResults) {
+	n.updateQueueDepth(int64(len(messages)))
+
+	var waited time.Duration
+	for _, unregistration := range []bool{true, false} {
+		for _, m := range messages {
+			if isUnregistration(m.subject) != unregistration {
+				continue
+			}
+			waited += n.rateLimiter.Wait(unregistration)
+			n.updateQueueDepth(-1)
+			n.emit(m.subject, m.message, wg, results)
+		}
+	}
+
+	if err := n.metronClient.SendDuration(natsEmitRateLimitWaitDuration, waited); err != nil {
+		n.logger.Error("cannot-send-rate-limit-wait-duration", err)
+	}
+}
+
+func isUnregistration(subject string) bool {
+	return subject == "router.unregister" || subject == "service-discovery.unregister"
+}
+
+// updateQueueDepth adjusts the number of messages waiting for the rate
+// limiter across all emits, and reports it when it reaches a multiple of 100
+// or zero so that large emits do not send a metric per message.
+func (n *natsEmitter) updateQueueDepth(delta int64) {
+	depth := atomic.AddInt64(&n.queued, delta)
+	if delta != -1 || depth%100 == 0 {
+		if err := n.metronClient.SendMetric(natsEmitQueueDepthMetric, int(depth)); err != nil {
+			n.logger.Error("cannot-send-queue-depth", err)
+		}
+	}
+}
+
+// handleFailures counts the failed publishes per subject and hands the
+// failed unregistrations to the unregistration cache.
+func (n *natsEmitter) handleFailures(results *publishResults) {
+	for subject, failed := range results.failedBySubject {
+		err := n.metronClient.IncrementCounterWithDelta(natsPublishFailureCounters[subject], uint64(failed))
+		if err != nil {
+			n.logger.Error("cannot-emit-number-of-publish-failures", err, lager.Data{"subject": subject})
+		}
+	}
+
+	if n.unregistrationCache != nil && len(results.unregistrations) > 0 {
+		err := n.unregistrationCache.Add(results.unregistrations)
+		if err != nil {
+			n.logger.Error("failed-to-add-unregistrations-to-cache", err)
+		}
+	}
+}
+
+func (n *natsEmitter) emit(subject string, message routingtable.RegistryMessage, wg *sync.WaitGroup, results *publishResults) {
 	n.workPool.Submit(func() {
 		var err error
 		defer func() {
 			if err != nil {
-				select {
-				case errors <- err:
-				default:
-				}
+				results.add(subject, message, err)
+			} else {
+				results.published(subject)
 			}
 			wg.Done()
 		}()
@@ -120,6 +237,21 @@ func (n *natsEmitter) emit(subject string, message routingtable.RegistryMessage,
 		}
 
 		err = n.natsClient.Publish(subject, payload)
+		for attempt := 0; err != nil && attempt < n.publishRetries; attempt++ {
+			backoff := n.retryBackoff << attempt
+			if !results.reserveRetry(backoff) {
+				n.logger.Info("retry-budget-exhausted", lager.Data{"subject": subject, "attempt": attempt + 1})
+				break
+			}
+			n.logger.Info("retrying-publish", lager.Data{
+				"subject": subject,
+				"attempt": attempt + 1,
+				"backoff": backoff.String(),
+				"error":   err.Error(),
+			})
+			n.clock.Sleep(backoff)
+			err = n.natsClient.Publish(subject, payload)
+		}
 		if err != nil {
 			n.logger.Error("failed-to-publish", err, lager.Data{
 				"message": message,
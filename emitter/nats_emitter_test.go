@@ -2,6 +2,9 @@ package emitter_test
 
 import (
 	"errors"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
 
 	mfakes "code.cloudfoundry.org/diego-logging-client/testhelpers"
 	"code.cloudfoundry.org/lager/v3/lagertest"
@@ -155,6 +158,75 @@ var _ = Describe("NatsEmitter", func() {
 			Expect(delta).To(BeEquivalentTo(4))
 		})
 
+		It("publishes registrations before unregistrations when it is not rate limited", func() {
+			var subjects []string
+			for _, subject := range []string{"router.register", "router.unregister", "service-discovery.register", "service-discovery.unregister"} {
+				natsClient.WhenPublishing(subject, func(msg *nats.Msg) error {
+					subjects = append(subjects, msg.Subject)
+					return nil
+				})
+			}
+
+			Expect(natsEmitter.Emit(messagesToEmit)).To(Succeed())
+			Expect(subjects).To(Equal([]string{
+				"router.register", "router.register",
+				"router.unregister", "router.unregister",
+				"service-discovery.register", "service-discovery.register",
+				"service-discovery.unregister", "service-discovery.unregister",
+			}))
+		})
+
+		Context("when the nats emitter is rate limited", func() {
+			var clock *fakeclock.FakeClock
+
+			BeforeEach(func() {
+				clock = fakeclock.NewFakeClock(time.Now())
+				workPool, err := workpool.NewWorkPool(1)
+				Expect(err).NotTo(HaveOccurred())
+				limiter := emitter.NewRateLimiter(clock, 10, 2)
+				natsEmitter = emitter.NewNATSEmitter(natsClient, workPool, logger, fakeMetronClient, true, emitter.WithRateLimiter(limiter))
+			})
+
+			It("publishes unregistrations before registrations at the configured rate", func() {
+				errCh := make(chan error)
+				go func() { errCh <- natsEmitter.Emit(messagesToEmit) }()
+
+				Eventually(func() int { return len(natsClient.PublishedMessages("router.unregister")) }).Should(Equal(2))
+				Consistently(func() int { return len(natsClient.PublishedMessages("service-discovery.unregister")) }).Should(Equal(0))
+
+				for i := 0; i < 6; i++ {
+					clock.WaitForWatcherAndIncrement(100 * time.Millisecond)
+				}
+				Eventually(errCh).Should(Receive(BeNil()))
+
+				Expect(natsClient.PublishedMessages("service-discovery.unregister")).To(HaveLen(2))
+				Expect(natsClient.PublishedMessages("router.register")).To(HaveLen(2))
+				Expect(natsClient.PublishedMessages("service-discovery.register")).To(HaveLen(2))
+			})
+
+			It("reports the queue depth and the time spent waiting", func() {
+				errCh := make(chan error)
+				go func() { errCh <- natsEmitter.Emit(messagesToEmit) }()
+				for i := 0; i < 6; i++ {
+					clock.WaitForWatcherAndIncrement(100 * time.Millisecond)
+				}
+				Eventually(errCh).Should(Receive(BeNil()))
+
+				Expect(fakeMetronClient.SendMetricCallCount()).To(Equal(2))
+				name, value, _ := fakeMetronClient.SendMetricArgsForCall(0)
+				Expect(name).To(Equal("NATSEmitQueueDepth"))
+				Expect(value).To(Equal(8))
+				name, value, _ = fakeMetronClient.SendMetricArgsForCall(1)
+				Expect(name).To(Equal("NATSEmitQueueDepth"))
+				Expect(value).To(Equal(0))
+
+				Expect(fakeMetronClient.SendDurationCallCount()).To(Equal(1))
+				name, duration, _ := fakeMetronClient.SendDurationArgsForCall(0)
+				Expect(name).To(Equal("NATSEmitRateLimitWait"))
+				Expect(duration).To(BeNumerically(">", 0))
+			})
+		})
+
 		Context("when the nats emitter is configured to not emit internal routes", func() {
 			BeforeEach(func() {
 				logger := lagertest.NewTestLogger("test")
@@ -229,8 +301,105 @@ var _ = Describe("NatsEmitter", func() {
 				})
 			})
 
-			It("should error", func() {
-				Expect(natsEmitter.Emit(messagesToEmit)).To(MatchError(errors.New("bam")))
+			It("reports how many messages of the emit failed", func() {
+				err := natsEmitter.Emit(messagesToEmit)
+				Expect(err).To(MatchError("failed to publish 2 of 8 NATS messages: bam"))
+
+				var publishErr *emitter.PublishError
+				Expect(errors.As(err, &publishErr)).To(BeTrue())
+				Expect(publishErr.FailedBySubject).To(Equal(map[string]int{"router.register": 2}))
+			})
+
+			It("counts the failures per subject", func() {
+				natsEmitter.Emit(messagesToEmit)
+
+				Expect(fakeMetronClient.IncrementCounterWithDeltaCallCount()).To(Equal(3))
+				name, delta := fakeMetronClient.IncrementCounterWithDeltaArgsForCall(2)
+				Expect(name).To(Equal("HTTPRouteNATSRegisterFailures"))
+				Expect(delta).To(BeEquivalentTo(2))
+			})
+
+			It("still counts the messages that were published", func() {
+				natsEmitter.Emit(messagesToEmit)
+
+				name, delta := fakeMetronClient.IncrementCounterWithDeltaArgsForCall(0)
+				Expect(name).To(Equal("HTTPRouteNATSMessagesEmitted"))
+				Expect(delta).To(BeEquivalentTo(2))
+
+				name, delta = fakeMetronClient.IncrementCounterWithDeltaArgsForCall(1)
+				Expect(name).To(Equal("InternalRouteNATSMessagesEmitted"))
+				Expect(delta).To(BeEquivalentTo(4))
+			})
+
+			Context("when publishes are retried", func() {
+				var clock *fakeclock.FakeClock
+
+				BeforeEach(func() {
+					clock = fakeclock.NewFakeClock(time.Now())
+					workPool, err := workpool.NewWorkPool(1)
+					Expect(err).NotTo(HaveOccurred())
+					natsEmitter = emitter.NewNATSEmitter(natsClient, workPool, logger, fakeMetronClient, true, emitter.WithPublishRetries(clock, 2, time.Second))
+				})
+
+				It("publishes again after backing off", func() {
+					failures := 0
+					natsClient.WhenPublishing("router.register", func(*nats.Msg) error {
+						failures++
+						if failures <= 2 {
+							return errors.New("bam")
+						}
+						return nil
+					})
+
+					errCh := make(chan error)
+					go func() { errCh <- natsEmitter.Emit(messagesToEmit) }()
+
+					clock.WaitForWatcherAndIncrement(time.Second)
+					Consistently(errCh).ShouldNot(Receive())
+					clock.WaitForWatcherAndIncrement(2 * time.Second)
+					Eventually(errCh).Should(Receive(BeNil()))
+					Expect(natsClient.PublishedMessages("router.register")).To(HaveLen(2))
+				})
+
+				It("gives up once the retries of the emit took as long as those of a single publish", func() {
+					attempts := 0
+					natsClient.WhenPublishing("router.register", func(*nats.Msg) error {
+						attempts++
+						return errors.New("bam")
+					})
+
+					errCh := make(chan error)
+					go func() { errCh <- natsEmitter.Emit(messagesToEmit) }()
+
+					clock.WaitForWatcherAndIncrement(time.Second)
+					clock.WaitForWatcherAndIncrement(2 * time.Second)
+					Eventually(errCh).Should(Receive(MatchError(ContainSubstring("failed to publish 2 of 8"))))
+					Expect(attempts).To(Equal(4))
+					Expect(logger).To(gbytes.Say("retry-budget-exhausted"))
+				})
+			})
+		})
+
+		Context("when unregistrations cannot be published", func() {
+			var cache *fakeUnregistrationCache
+
+			BeforeEach(func() {
+				cache = &fakeUnregistrationCache{}
+				workPool, err := workpool.NewWorkPool(1)
+				Expect(err).NotTo(HaveOccurred())
+				natsEmitter = emitter.NewNATSEmitter(natsClient, workPool, logger, fakeMetronClient, true, emitter.WithUnregistrationCache(cache))
+
+				natsClient.WhenPublishing("router.unregister", func(*nats.Msg) error {
+					return errors.New("bam")
+				})
+				natsClient.WhenPublishing("service-discovery.unregister", func(*nats.Msg) error {
+					return errors.New("bam")
+				})
+			})
+
+			It("hands the http route unregistrations to the unregistration cache", func() {
+				Expect(natsEmitter.Emit(messagesToEmit)).To(HaveOccurred())
+				Expect(cache.added).To(ConsistOf(messagesToEmit.UnregistrationMessages))
 			})
 		})
 
@@ -248,3 +417,12 @@ var _ = Describe("NatsEmitter", func() {
 		})
 	})
 })
+
+type fakeUnregistrationCache struct {
+	added []routingtable.RegistryMessage
+}
+
+func (c *fakeUnregistrationCache) Add(messages []routingtable.RegistryMessage) error {
+	c.added = append(c.added, messages...)
+	return nil
+}
@@ -0,0 +1,112 @@
+package emitter
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/route-emitter/routingtable"
+)
+
+var natsPublishFailureCounters = map[string]string{
+	"router.register":              "HTTPRouteNATSRegisterFailures",
+	"router.unregister":            "HTTPRouteNATSUnregisterFailures",
+	"service-discovery.register":   "InternalRouteNATSRegisterFailures",
+	"service-discovery.unregister": "InternalRouteNATSUnregisterFailures",
+}
+
+// UnregistrationCache takes unregistrations that could not be published so
+// that they are sent again later. unregistration.Cache implements it.
+type UnregistrationCache interface {
+	Add([]routingtable.RegistryMessage) error
+}
+
+// PublishError reports the messages of an emit that could not be published,
+// after retries. Err is the last error returned by NATS.
+type PublishError struct {
+	Failed          int
+	Total           int
+	FailedBySubject map[string]int
+	Err             error
+}
+
+func (e *PublishError) Error() string {
+	return fmt.Sprintf("failed to publish %d of %d NATS messages: %s", e.Failed, e.Total, e.Err)
+}
+
+func (e *PublishError) Unwrap() error {
+	return e.Err
+}
+
+// publishResults collects the outcome of the publishes of an emit from the
+// workpool, and the time its publishes may still spend retrying.
+type publishResults struct {
+	lock               sync.Mutex
+	publishedBySubject map[string]int
+	failedBySubject    map[string]int
+	unregistrations    []routingtable.RegistryMessage
+	failed             int
+	lastErr            error
+	retryBudget        time.Duration
+}
+
+func newPublishResults(retryBudget time.Duration) *publishResults {
+	return &publishResults{
+		publishedBySubject: map[string]int{},
+		failedBySubject:    map[string]int{},
+		retryBudget:        retryBudget,
+	}
+}
+
+func (r *publishResults) published(subject string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.publishedBySubject[subject]++
+}
+
+func (r *publishResults) add(subject string, message routingtable.RegistryMessage, err error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.failed++
+	r.failedBySubject[subject]++
+	r.lastErr = err
+	if subject == "router.unregister" {
+		r.unregistrations = append(r.unregistrations, message)
+	}
+}
+
+// reserveRetry takes backoff from the retry budget of the emit, and returns
+// false without taking it if the budget does not cover it.
+func (r *publishResults) reserveRetry(backoff time.Duration) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if backoff > r.retryBudget {
+		return false
+	}
+	r.retryBudget -= backoff
+	return true
+}
+
+func (r *publishResults) publishedCount(subjects ...string) uint64 {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	var count uint64
+	for _, subject := range subjects {
+		count += uint64(r.publishedBySubject[subject])
+	}
+	return count
+}
+
+func (r *publishResults) err(total int) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.failed == 0 {
+		return nil
+	}
+	return &PublishError{
+		Failed:          r.failed,
+		Total:           total,
+		FailedBySubject: r.failedBySubject,
+		Err:             r.lastErr,
+	}
+}
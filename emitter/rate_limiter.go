@@ -0,0 +1,84 @@
+package emitter
+
+import (
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+)
+
+// RateLimiter is a token bucket that refills at a fixed rate of tokens per
+// second, up to burst tokens. Priority waiters get tokens before any other
+// waiter.
+type RateLimiter struct {
+	clock clock.Clock
+	rate  float64
+	burst float64
+
+	lock            sync.Mutex
+	tokens          float64
+	last            time.Time
+	priorityWaiters int
+}
+
+// NewRateLimiter returns a limiter with a full bucket. A burst lower than one
+// is raised to one.
+func NewRateLimiter(clock clock.Clock, perSecond float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		clock:  clock,
+		rate:   perSecond,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   clock.Now(),
+	}
+}
+
+// Wait blocks until a token is available and takes it. It returns how long
+// it waited.
+func (l *RateLimiter) Wait(priority bool) time.Duration {
+	start := l.clock.Now()
+	waiting := false
+
+	for {
+		l.lock.Lock()
+		l.refill()
+		if l.tokens >= 1 && (priority || l.priorityWaiters == 0) {
+			l.tokens--
+			if waiting && priority {
+				l.priorityWaiters--
+			}
+			l.lock.Unlock()
+			return l.clock.Since(start)
+		}
+		if !waiting && priority {
+			l.priorityWaiters++
+		}
+		waiting = true
+		delay := l.delay()
+		l.lock.Unlock()
+
+		l.clock.Sleep(delay)
+	}
+}
+
+func (l *RateLimiter) refill() {
+	now := l.clock.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+}
+
+// delay is the time until the next token is available, or a tick if a
+// token is available but reserved for priority waiters.
+func (l *RateLimiter) delay() time.Duration {
+	missing := 1 - l.tokens
+	if missing <= 0 {
+		missing = 1
+	}
+	return time.Duration(missing / l.rate * float64(time.Second))
+}
@@ -0,0 +1,61 @@
+package emitter_test
+
+import (
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/route-emitter/emitter"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RateLimiter", func() {
+	var (
+		clock   *fakeclock.FakeClock
+		limiter *emitter.RateLimiter
+	)
+
+	BeforeEach(func() {
+		clock = fakeclock.NewFakeClock(time.Now())
+		limiter = emitter.NewRateLimiter(clock, 10, 2)
+	})
+
+	It("lets a burst through without waiting", func() {
+		Expect(limiter.Wait(false)).To(BeZero())
+		Expect(limiter.Wait(false)).To(BeZero())
+	})
+
+	It("waits for the bucket to refill once the burst is used", func() {
+		limiter.Wait(false)
+		limiter.Wait(false)
+
+		waited := make(chan time.Duration)
+		go func() { waited <- limiter.Wait(false) }()
+
+		Consistently(waited).ShouldNot(Receive())
+		clock.WaitForWatcherAndIncrement(100 * time.Millisecond)
+		Eventually(waited).Should(Receive(Equal(100 * time.Millisecond)))
+	})
+
+	It("hands tokens to priority waiters first", func() {
+		limiter.Wait(false)
+		limiter.Wait(false)
+
+		order := make(chan bool, 2)
+		go func() {
+			limiter.Wait(false)
+			order <- false
+		}()
+		go func() {
+			limiter.Wait(true)
+			order <- true
+		}()
+
+		clock.WaitForNWatchersAndIncrement(100*time.Millisecond, 2)
+		Eventually(order).Should(Receive(BeTrue()))
+
+		clock.WaitForWatcherAndIncrement(100 * time.Millisecond)
+		Eventually(order).Should(Receive(BeFalse()))
+	})
+})
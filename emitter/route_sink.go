@@ -0,0 +1,285 @@
+package emitter
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	loggingclient "code.cloudfoundry.org/diego-logging-client"
+	"code.cloudfoundry.org/lager/v3"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+)
+
+const (
+	routeSinkEmitDurationSuffix   = "RouteSinkEmitDuration"
+	routeSinkEmitFailuresSuffix   = "RouteSinkEmitFailures"
+	routeSinkDroppedChangesSuffix = "RouteSinkDroppedChanges"
+
+	// routeSinkQueueSize is how many changes a sink may fall behind before
+	// changes to it are dropped.
+	routeSinkQueueSize = 1024
+
+	// DefaultRouteSinkEmitTimeout is how long an emit waits for a sink
+	// unless configured otherwise.
+	DefaultRouteSinkEmitTimeout = 5 * time.Second
+)
+
+var (
+	// ErrRouteSinkTimeout is returned for a sink that did not emit a change
+	// within the timeout. The change stays queued and is emitted later.
+	ErrRouteSinkTimeout = errors.New("emit timed out")
+	// ErrRouteSinkQueueFull is returned for a sink whose queue was full. The
+	// change is dropped and the sink is resynced from the routing table.
+	ErrRouteSinkQueueFull = errors.New("queue full, resync needed")
+)
+
+// RouteSink receives every change to the routes the emitter manages: the
+// NATS messages of HTTP and internal routes and the TCP route mappings.
+//
+//go:generate counterfeiter -o fakes/fake_route_sink.go . RouteSink
+type RouteSink interface {
+	Emit(messagesToEmit routingtable.MessagesToEmit, routeMappings routingtable.TCPRouteMappings) error
+}
+
+// RouteSnapshotSink keeps the routes it is emitted. Replace gives it all
+// external routes of the routing table, which it serves instead of the ones
+// it kept, so that it does not drift from the table when it misses a change.
+//
+//go:generate counterfeiter -o fakes/fake_route_snapshot_sink.go . RouteSnapshotSink
+type RouteSnapshotSink interface {
+	Replace(messagesToEmit routingtable.MessagesToEmit, routeMappings routingtable.TCPRouteMappings) error
+}
+
+// NamedRouteSink is a sink of a fan-out sink. The name identifies the sink
+// in logs and is the prefix of its metrics.
+type NamedRouteSink struct {
+	Name string
+	Sink RouteSink
+}
+
+// RouteSinkErrors holds the errors of the sinks of a fan-out sink that
+// failed, by sink name.
+type RouteSinkErrors map[string]error
+
+func (e RouteSinkErrors) Error() string {
+	names := make([]string, 0, len(e))
+	for name := range e {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	failures := make([]string, 0, len(names))
+	for _, name := range names {
+		failures = append(failures, fmt.Sprintf("%s: %s", name, e[name]))
+	}
+	return fmt.Sprintf("%d route sinks failed: %s", len(e), strings.Join(failures, "; "))
+}
+
+type routeSinkChange struct {
+	messagesToEmit routingtable.MessagesToEmit
+	routeMappings  routingtable.TCPRouteMappings
+	results        chan<- routeSinkResult
+}
+
+type fanOutSinkState struct {
+	NamedRouteSink
+	queue chan routeSinkChange
+	// resync is set when a change was dropped because the queue was full,
+	// and cleared once the sink was emitted all routes of the table.
+	resync int32
+	// behind is set when an emit stopped waiting for the sink, and cleared
+	// once the sink emitted all changes it was queued.
+	behind int32
+}
+
+type routeSinkResult struct {
+	name string
+	err  error
+}
+
+// FanOutSink emits every change to all of its sinks concurrently. A sink that
+// fails or panics does not stop the others. Every sink emits the changes one
+// at a time, in order, from its own queue, so that concurrent emits do not
+// interleave.
+//
+// An emit waits for a sink at most the timeout, leaving the change queued,
+// and does not wait at all for a sink that is still behind from an earlier
+// timeout. Queueing never blocks: when the queue of a sink is full, the change
+// is dropped and the sink is emitted all routes of the table instead of its
+// queued changes once it catches up, so that one hung sink neither holds back
+// the others nor the emitter.
+type FanOutSink struct {
+	logger       lager.Logger
+	metronClient loggingclient.IngressClient
+	clock        clock.Clock
+	timeout      time.Duration
+	table        routingtable.RoutingTable
+	sinks        []*fanOutSinkState
+}
+
+// NewFanOutSink returns a sink emitting to the given sinks, and starts a
+// goroutine per sink that emits its queued changes. A timeout of zero uses
+// DefaultRouteSinkEmitTimeout. Sinks that fell too far behind are resynced
+// from table.
+func NewFanOutSink(
+	logger lager.Logger,
+	metronClient loggingclient.IngressClient,
+	clock clock.Clock,
+	timeout time.Duration,
+	table routingtable.RoutingTable,
+	sinks ...NamedRouteSink,
+) *FanOutSink {
+	if timeout <= 0 {
+		timeout = DefaultRouteSinkEmitTimeout
+	}
+	f := &FanOutSink{
+		logger:       logger.Session("fan-out-sink"),
+		metronClient: metronClient,
+		clock:        clock,
+		timeout:      timeout,
+		table:        table,
+	}
+	for _, sink := range sinks {
+		state := &fanOutSinkState{NamedRouteSink: sink, queue: make(chan routeSinkChange, routeSinkQueueSize)}
+		f.sinks = append(f.sinks, state)
+		go f.drain(state)
+	}
+	return f
+}
+
+// Emit returns RouteSinkErrors if any of the sinks failed, did not emit the
+// change within the timeout or had to drop it.
+func (f *FanOutSink) Emit(messagesToEmit routingtable.MessagesToEmit, routeMappings routingtable.TCPRouteMappings) error {
+	// buffered, so that sinks can report after the emit stopped waiting
+	results := make(chan routeSinkResult, len(f.sinks))
+	change := routeSinkChange{
+		messagesToEmit: messagesToEmit,
+		routeMappings:  routeMappings,
+		results:        results,
+	}
+
+	errs := RouteSinkErrors{}
+	pending := map[string]*fanOutSinkState{}
+	for _, sink := range f.sinks {
+		select {
+		case sink.queue <- change:
+			if atomic.LoadInt32(&sink.behind) == 1 {
+				errs[sink.Name] = ErrRouteSinkTimeout
+			} else {
+				pending[sink.Name] = sink
+			}
+		default:
+			f.dropChange(sink)
+			errs[sink.Name] = ErrRouteSinkQueueFull
+		}
+	}
+
+	if len(pending) > 0 {
+		timer := f.clock.NewTimer(f.timeout)
+		defer timer.Stop()
+
+		for len(pending) > 0 {
+			select {
+			case result := <-results:
+				delete(pending, result.name)
+				if result.err != nil {
+					errs[result.name] = result.err
+				}
+			case <-timer.C():
+				for name, sink := range pending {
+					f.logger.Info("emit-timed-out", lager.Data{"sink": name})
+					atomic.StoreInt32(&sink.behind, 1)
+					errs[name] = ErrRouteSinkTimeout
+				}
+				pending = nil
+			}
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func (f *FanOutSink) dropChange(sink *fanOutSinkState) {
+	if atomic.CompareAndSwapInt32(&sink.resync, 0, 1) {
+		f.logger.Info("queue-full-resync-needed", lager.Data{"sink": sink.Name})
+	}
+	err := f.metronClient.IncrementCounter(sink.Name + routeSinkDroppedChangesSuffix)
+	if err != nil {
+		f.logger.Error("failed-to-send-dropped-changes-metric", err, lager.Data{"sink": sink.Name})
+	}
+}
+
+// drain emits the queued changes of the sink one at a time, and reports the
+// duration and failures of every emit, including those an emit stopped
+// waiting for.
+func (f *FanOutSink) drain(sink *fanOutSinkState) {
+	for change := range sink.queue {
+		if atomic.SwapInt32(&sink.resync, 0) == 1 {
+			f.resync(sink, change)
+		} else {
+			err := f.emit(sink, func() error {
+				return sink.Sink.Emit(change.messagesToEmit, change.routeMappings)
+			})
+			change.results <- routeSinkResult{name: sink.Name, err: err}
+		}
+		if len(sink.queue) == 0 {
+			atomic.StoreInt32(&sink.behind, 0)
+		}
+	}
+}
+
+// resync discards the queued changes of a sink that dropped changes, as the
+// table already holds them, and emits all routes of the table instead. Sinks
+// that keep a snapshot of the routes have it replaced; all others are emitted
+// the registrations of every route, like on a broadcast.
+func (f *FanOutSink) resync(sink *fanOutSinkState, change routeSinkChange) {
+	discarded := []routeSinkChange{change}
+	for len(sink.queue) > 0 {
+		discarded = append(discarded, <-sink.queue)
+	}
+	f.logger.Info("resyncing", lager.Data{"sink": sink.Name, "discarded-changes": len(discarded)})
+
+	routeMappings, messagesToEmit := f.table.GetExternalRoutingEvents()
+	err := f.emit(sink, func() error {
+		if snapshotSink, ok := sink.Sink.(RouteSnapshotSink); ok {
+			return snapshotSink.Replace(messagesToEmit, routeMappings)
+		}
+		_, internalMessages := f.table.GetInternalRoutingEvents()
+		return sink.Sink.Emit(messagesToEmit.Merge(internalMessages), routeMappings)
+	})
+	for _, change := range discarded {
+		change.results <- routeSinkResult{name: sink.Name, err: err}
+	}
+}
+
+func (f *FanOutSink) emit(sink *fanOutSinkState, emit func() error) (err error) {
+	start := f.clock.Now()
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("sink panicked: %v", r)
+		}
+		f.sendDuration(sink.Name, f.clock.Since(start))
+		if err != nil {
+			f.logger.Error("failed-to-emit", err, lager.Data{"sink": sink.Name})
+			metricErr := f.metronClient.IncrementCounter(sink.Name + routeSinkEmitFailuresSuffix)
+			if metricErr != nil {
+				f.logger.Error("failed-to-send-emit-failures-metric", metricErr, lager.Data{"sink": sink.Name})
+			}
+		}
+	}()
+	return emit()
+}
+
+func (f *FanOutSink) sendDuration(name string, duration time.Duration) {
+	err := f.metronClient.SendDuration(name+routeSinkEmitDurationSuffix, duration)
+	if err != nil {
+		f.logger.Error("failed-to-send-emit-duration-metric", err, lager.Data{"sink": name})
+	}
+}
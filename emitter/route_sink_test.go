@@ -0,0 +1,320 @@
+package emitter_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	mfakes "code.cloudfoundry.org/diego-logging-client/testhelpers"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"code.cloudfoundry.org/route-emitter/emitter"
+	"code.cloudfoundry.org/route-emitter/emitter/fakes"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/route-emitter/routingtable/fakeroutingtable"
+	apimodels "code.cloudfoundry.org/routing-api/models"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RouteSink", func() {
+	messagesToEmit := routingtable.MessagesToEmit{
+		RegistrationMessages: []routingtable.RegistryMessage{
+			{URIs: []string{"foo.com"}, Host: "1.1.1.1", Port: 11},
+		},
+	}
+	routeMappings := routingtable.TCPRouteMappings{
+		Registrations: []apimodels.TcpRouteMapping{apimodels.NewTcpRouteMapping("123", 61000, "some-ip-1", 62003, 0)},
+	}
+
+	Describe("FanOutSink", func() {
+		var (
+			logger           *lagertest.TestLogger
+			clock            *fakeclock.FakeClock
+			fakeMetronClient *mfakes.FakeIngressClient
+			first, second    *fakes.FakeRouteSink
+			timeout          time.Duration
+			table            *fakeroutingtable.FakeRoutingTable
+			fanOut           *emitter.FanOutSink
+		)
+
+		BeforeEach(func() {
+			logger = lagertest.NewTestLogger("test")
+			clock = fakeclock.NewFakeClock(time.Now())
+			fakeMetronClient = &mfakes.FakeIngressClient{}
+			first = &fakes.FakeRouteSink{}
+			second = &fakes.FakeRouteSink{}
+			timeout = 0
+			table = &fakeroutingtable.FakeRoutingTable{}
+			table.GetExternalRoutingEventsReturns(routeMappings, messagesToEmit)
+		})
+
+		JustBeforeEach(func() {
+			fanOut = emitter.NewFanOutSink(logger, fakeMetronClient, clock, timeout, table,
+				emitter.NamedRouteSink{Name: "First", Sink: first},
+				emitter.NamedRouteSink{Name: "Second", Sink: second},
+			)
+		})
+
+		It("emits the change to every sink", func() {
+			Expect(fanOut.Emit(messagesToEmit, routeMappings)).To(Succeed())
+
+			for _, sink := range []*fakes.FakeRouteSink{first, second} {
+				Expect(sink.EmitCallCount()).To(Equal(1))
+				messages, mappings := sink.EmitArgsForCall(0)
+				Expect(messages).To(Equal(messagesToEmit))
+				Expect(mappings).To(Equal(routeMappings))
+			}
+		})
+
+		It("sends the emit duration of every sink", func() {
+			Expect(fanOut.Emit(messagesToEmit, routeMappings)).To(Succeed())
+
+			Eventually(fakeMetronClient.SendDurationCallCount).Should(Equal(2))
+			names := []string{}
+			for i := 0; i < 2; i++ {
+				name, _, _ := fakeMetronClient.SendDurationArgsForCall(i)
+				names = append(names, name)
+			}
+			Expect(names).To(ConsistOf("FirstRouteSinkEmitDuration", "SecondRouteSinkEmitDuration"))
+		})
+
+		Context("when a sink fails", func() {
+			BeforeEach(func() {
+				first.EmitReturns(errors.New("boom"))
+			})
+
+			It("still emits to the other sinks and returns the error of the failed sink", func() {
+				err := fanOut.Emit(messagesToEmit, routeMappings)
+				Expect(second.EmitCallCount()).To(Equal(1))
+
+				var sinkErrors emitter.RouteSinkErrors
+				Expect(errors.As(err, &sinkErrors)).To(BeTrue())
+				Expect(sinkErrors).To(HaveLen(1))
+				Expect(sinkErrors["First"]).To(MatchError("boom"))
+			})
+
+			It("increments the failures counter of the sink", func() {
+				fanOut.Emit(messagesToEmit, routeMappings)
+				Eventually(fakeMetronClient.IncrementCounterCallCount).Should(Equal(1))
+				Expect(fakeMetronClient.IncrementCounterArgsForCall(0)).To(Equal("FirstRouteSinkEmitFailures"))
+			})
+		})
+
+		Context("when a sink panics", func() {
+			BeforeEach(func() {
+				first.EmitStub = func(routingtable.MessagesToEmit, routingtable.TCPRouteMappings) error {
+					panic("oops")
+				}
+			})
+
+			It("reports the panic as a failure of the sink", func() {
+				err := fanOut.Emit(messagesToEmit, routeMappings)
+				Expect(err).To(MatchError(ContainSubstring("First: sink panicked: oops")))
+				Expect(second.EmitCallCount()).To(Equal(1))
+			})
+		})
+
+		Context("when changes are emitted concurrently", func() {
+			var release chan struct{}
+
+			BeforeEach(func() {
+				release = make(chan struct{})
+				first.EmitStub = func(routingtable.MessagesToEmit, routingtable.TCPRouteMappings) error {
+					<-release
+					return nil
+				}
+			})
+
+			It("emits them to each sink one at a time, in order", func() {
+				other := routingtable.MessagesToEmit{
+					UnregistrationMessages: messagesToEmit.RegistrationMessages,
+				}
+				firstErr := make(chan error, 1)
+				go func() { firstErr <- fanOut.Emit(messagesToEmit, routeMappings) }()
+				Eventually(first.EmitCallCount).Should(Equal(1))
+
+				secondErr := make(chan error, 1)
+				go func() { secondErr <- fanOut.Emit(other, routingtable.TCPRouteMappings{}) }()
+				Eventually(second.EmitCallCount).Should(Equal(2))
+				Consistently(first.EmitCallCount).Should(Equal(1))
+
+				close(release)
+				Eventually(firstErr).Should(Receive(BeNil()))
+				Eventually(secondErr).Should(Receive(BeNil()))
+				Expect(first.EmitCallCount()).To(Equal(2))
+				messages, _ := first.EmitArgsForCall(1)
+				Expect(messages).To(Equal(other))
+			})
+		})
+
+		Context("when a sink does not return within the timeout", func() {
+			var release chan struct{}
+
+			BeforeEach(func() {
+				timeout = time.Second
+				release = make(chan struct{})
+				first.EmitStub = func(routingtable.MessagesToEmit, routingtable.TCPRouteMappings) error {
+					<-release
+					return nil
+				}
+			})
+
+			It("stops waiting for it and emits the change once it returns", func() {
+				errCh := make(chan error, 1)
+				go func() {
+					errCh <- fanOut.Emit(messagesToEmit, routeMappings)
+				}()
+				clock.WaitForWatcherAndIncrement(timeout)
+
+				var err error
+				Eventually(errCh).Should(Receive(&err))
+				Expect(err).To(MatchError(ContainSubstring("First: " + emitter.ErrRouteSinkTimeout.Error())))
+				Expect(second.EmitCallCount()).To(Equal(1))
+
+				By("not waiting for it again while it is behind")
+				err = fanOut.Emit(messagesToEmit, routeMappings)
+				Expect(err).To(MatchError(ContainSubstring("First: " + emitter.ErrRouteSinkTimeout.Error())))
+				Expect(second.EmitCallCount()).To(Equal(2))
+				Expect(first.EmitCallCount()).To(Equal(1))
+
+				close(release)
+				Eventually(first.EmitCallCount).Should(Equal(2))
+				Eventually(func() error { return fanOut.Emit(messagesToEmit, routeMappings) }).Should(Succeed())
+			})
+		})
+
+		Context("when a sink never returns", func() {
+			var release chan struct{}
+
+			BeforeEach(func() {
+				release = make(chan struct{})
+				first.EmitStub = func(routingtable.MessagesToEmit, routingtable.TCPRouteMappings) error {
+					<-release
+					return nil
+				}
+			})
+
+			AfterEach(func() {
+				close(release)
+			})
+
+			It("times out after the default timeout and keeps emitting to the other sinks", func() {
+				errCh := make(chan error, 1)
+				go func() {
+					errCh <- fanOut.Emit(messagesToEmit, routeMappings)
+				}()
+				clock.WaitForWatcherAndIncrement(emitter.DefaultRouteSinkEmitTimeout)
+				Eventually(errCh).Should(Receive(MatchError(ContainSubstring("First: " + emitter.ErrRouteSinkTimeout.Error()))))
+
+				for i := 0; i < 2000; i++ {
+					Expect(fanOut.Emit(messagesToEmit, routeMappings)).NotTo(Succeed())
+				}
+				Expect(second.EmitCallCount()).To(Equal(2001))
+			})
+
+			It("drops the changes once its queue is full and counts them", func() {
+				errCh := make(chan error, 1)
+				go func() {
+					errCh <- fanOut.Emit(messagesToEmit, routeMappings)
+				}()
+				clock.WaitForWatcherAndIncrement(emitter.DefaultRouteSinkEmitTimeout)
+				Eventually(errCh).Should(Receive())
+				Eventually(first.EmitCallCount).Should(Equal(1))
+
+				var err error
+				for i := 0; i < 1025; i++ {
+					err = fanOut.Emit(messagesToEmit, routeMappings)
+				}
+				Expect(err).To(MatchError(ContainSubstring("First: " + emitter.ErrRouteSinkQueueFull.Error())))
+				Expect(fakeMetronClient.IncrementCounterCallCount()).To(Equal(1))
+				Expect(fakeMetronClient.IncrementCounterArgsForCall(0)).To(Equal("FirstRouteSinkDroppedChanges"))
+			})
+		})
+
+		Context("when a sink dropped changes", func() {
+			var release chan struct{}
+
+			BeforeEach(func() {
+				release = make(chan struct{})
+				first.EmitStub = func(routingtable.MessagesToEmit, routingtable.TCPRouteMappings) error {
+					<-release
+					return nil
+				}
+			})
+
+			It("emits all routes of the table to it instead of its queued changes", func() {
+				go fanOut.Emit(routingtable.MessagesToEmit{}, routingtable.TCPRouteMappings{})
+				clock.WaitForWatcherAndIncrement(emitter.DefaultRouteSinkEmitTimeout)
+				Eventually(first.EmitCallCount).Should(Equal(1))
+
+				for i := 0; i < 1025; i++ {
+					fanOut.Emit(routingtable.MessagesToEmit{}, routingtable.TCPRouteMappings{})
+				}
+
+				close(release)
+				Eventually(first.EmitCallCount).Should(Equal(2))
+				Consistently(first.EmitCallCount).Should(Equal(2))
+				messages, mappings := first.EmitArgsForCall(1)
+				Expect(messages).To(Equal(messagesToEmit))
+				Expect(mappings).To(Equal(routeMappings))
+			})
+		})
+	})
+
+	Describe("WebhookRouteSink", func() {
+		var (
+			server   *httptest.Server
+			status   int
+			payloads chan emitter.WebhookPayload
+		)
+
+		BeforeEach(func() {
+			status = http.StatusNoContent
+			payloads = make(chan emitter.WebhookPayload, 1)
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err := ioutil.ReadAll(r.Body)
+				Expect(err).NotTo(HaveOccurred())
+				var payload emitter.WebhookPayload
+				Expect(json.Unmarshal(body, &payload)).To(Succeed())
+				payloads <- payload
+				w.WriteHeader(status)
+			}))
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("posts the change as JSON", func() {
+			sink := emitter.WebhookRouteSink(http.DefaultClient, server.URL)
+			Expect(sink.Emit(messagesToEmit, routeMappings)).To(Succeed())
+
+			var payload emitter.WebhookPayload
+			Expect(payloads).To(Receive(&payload))
+			Expect(payload.Messages.RegistrationMessages).To(Equal(messagesToEmit.RegistrationMessages))
+			Expect(payload.RouteMappings.Registrations).To(HaveLen(1))
+		})
+
+		It("does not post empty changes", func() {
+			sink := emitter.WebhookRouteSink(http.DefaultClient, server.URL)
+			Expect(sink.Emit(routingtable.MessagesToEmit{}, routingtable.TCPRouteMappings{})).To(Succeed())
+			Expect(payloads).NotTo(Receive())
+		})
+
+		Context("when the webhook does not accept the change", func() {
+			BeforeEach(func() {
+				status = http.StatusInternalServerError
+			})
+
+			It("returns an error", func() {
+				sink := emitter.WebhookRouteSink(http.DefaultClient, server.URL)
+				Expect(sink.Emit(messagesToEmit, routeMappings)).To(MatchError(ContainSubstring("500")))
+			})
+		})
+	})
+})
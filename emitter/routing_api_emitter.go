@@ -47,23 +47,11 @@ func (t *routingAPIEmitter) Emit(tcpEvents routingtable.TCPRouteMappings) error
 }
 
 func (t *routingAPIEmitter) emit(registrationMappingRequests, unregistrationMappingRequests []models.TcpRouteMapping) error {
-	var forceUpdate bool
-
-	for count := 0; count < 2; count++ {
-		forceUpdate = count > 0
-		token, err := t.uaaTokenFetcher.FetchToken(context.Background(), forceUpdate)
-		if err != nil {
-			return err
-		}
-
-		t.routingAPIClient.SetToken(token.AccessToken)
-
-		err = t.emitRoutingAPI(registrationMappingRequests, unregistrationMappingRequests)
-		if err != nil && count > 0 {
-			return err
-		} else if err == nil {
-			break
-		}
+	err := callWithToken(t.uaaTokenFetcher, t.routingAPIClient, func() error {
+		return t.emitRoutingAPI(registrationMappingRequests, unregistrationMappingRequests)
+	})
+	if err != nil {
+		return err
 	}
 
 	t.logger.Debug("successfully-emitted-events")
@@ -97,3 +85,24 @@ func (t *routingAPIEmitter) emitRoutingAPI(regMsgs, unregMsgs []models.TcpRouteM
 	}
 	return nil
 }
+
+// callWithToken authorizes the client with a UAA token and calls it. A
+// failed call is retried once with a fresh token.
+func callWithToken(uaaTokenFetcher uaaclient.TokenFetcher, routingAPIClient routing_api.Client, call func() error) error {
+	var err error
+	for count := 0; count < 2; count++ {
+		forceUpdate := count > 0
+		token, fetchErr := uaaTokenFetcher.FetchToken(context.Background(), forceUpdate)
+		if fetchErr != nil {
+			return fetchErr
+		}
+
+		routingAPIClient.SetToken(token.AccessToken)
+
+		err = call()
+		if err == nil {
+			return nil
+		}
+	}
+	return err
+}
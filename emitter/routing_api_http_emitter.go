@@ -0,0 +1,156 @@
+package emitter
+
+import (
+	"code.cloudfoundry.org/lager/v3"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	routing_api "code.cloudfoundry.org/routing-api"
+	"code.cloudfoundry.org/routing-api/models"
+	"code.cloudfoundry.org/routing-api/uaaclient"
+)
+
+type routingAPIHTTPEmitter struct {
+	logger           lager.Logger
+	routingAPIClient routing_api.Client
+	uaaTokenFetcher  uaaclient.TokenFetcher
+	ttl              int
+	natsEmitter      NATSEmitter
+}
+
+// NewRoutingAPIHTTPEmitter returns an emitter that upserts and deletes HTTP
+// routes in the routing api instead of publishing them on NATS. Upserts
+// carry the TTL, so routes the emitter stops re-registering expire. Internal
+// route messages, and HTTP route messages with any field the routing api
+// cannot store (see requiresNATS), are passed on to natsEmitter.
+func NewRoutingAPIHTTPEmitter(
+	logger lager.Logger,
+	routingAPIClient routing_api.Client,
+	uaaTokenFetcher uaaclient.TokenFetcher,
+	routeTTL int,
+	natsEmitter NATSEmitter,
+) NATSEmitter {
+	return &routingAPIHTTPEmitter{
+		logger:           logger,
+		routingAPIClient: routingAPIClient,
+		uaaTokenFetcher:  uaaTokenFetcher,
+		ttl:              routeTTL,
+		natsEmitter:      natsEmitter,
+	}
+}
+
+func (e *routingAPIHTTPEmitter) Emit(messagesToEmit routingtable.MessagesToEmit) error {
+	natsMessages := routingtable.MessagesToEmit{
+		InternalRegistrationMessages:   messagesToEmit.InternalRegistrationMessages,
+		InternalUnregistrationMessages: messagesToEmit.InternalUnregistrationMessages,
+	}
+	var registrations, unregistrations []routingtable.RegistryMessage
+	for _, message := range messagesToEmit.RegistrationMessages {
+		if requiresNATS(message) {
+			natsMessages.RegistrationMessages = append(natsMessages.RegistrationMessages, message)
+		} else {
+			registrations = append(registrations, message)
+		}
+	}
+	for _, message := range messagesToEmit.UnregistrationMessages {
+		if requiresNATS(message) {
+			natsMessages.UnregistrationMessages = append(natsMessages.UnregistrationMessages, message)
+		} else {
+			unregistrations = append(unregistrations, message)
+		}
+	}
+
+	if diverted := len(natsMessages.RegistrationMessages) + len(natsMessages.UnregistrationMessages); diverted > 0 {
+		e.logger.Debug("publishing-unsupported-routes-on-nats", lager.Data{"number-of-messages": diverted})
+	}
+
+	var natsErr error
+	if len(natsMessages.RegistrationMessages) > 0 || len(natsMessages.UnregistrationMessages) > 0 ||
+		len(natsMessages.InternalRegistrationMessages) > 0 || len(natsMessages.InternalUnregistrationMessages) > 0 {
+		natsErr = e.natsEmitter.Emit(natsMessages)
+	}
+
+	upserts := e.routesFor(registrations)
+	deletes := withoutRoutes(e.routesFor(unregistrations), upserts)
+	if len(upserts) == 0 && len(deletes) == 0 {
+		return natsErr
+	}
+
+	// deletes go first, so that a route that is unregistered and registered
+	// again by the same emit is not deleted in the end
+	err := callWithToken(e.uaaTokenFetcher, e.routingAPIClient, func() error {
+		if len(deletes) > 0 {
+			if err := e.routingAPIClient.DeleteRoutes(deletes); err != nil {
+				e.logger.Error("unable-to-delete", err)
+				return err
+			}
+			e.logger.Debug("successfully-emitted-unregistration-events",
+				lager.Data{"number-of-unregistration-events": len(deletes)})
+		}
+
+		if len(upserts) > 0 {
+			if err := e.routingAPIClient.UpsertRoutes(upserts); err != nil {
+				e.logger.Error("unable-to-upsert", err)
+				return err
+			}
+			e.logger.Debug("successfully-emitted-registration-events",
+				lager.Data{"number-of-registration-events": len(upserts)})
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return natsErr
+}
+
+// requiresNATS reports whether the message has fields that models.Route
+// cannot store, and which would be lost if it was sent to the routing api.
+func requiresNATS(message routingtable.RegistryMessage) bool {
+	return message.TlsPort != 0 ||
+		message.IsolationSegment != "" ||
+		message.PrivateInstanceId != "" ||
+		message.PrivateInstanceIndex != "" ||
+		message.ServerCertDomainSAN != "" ||
+		message.AvailabilityZone != "" ||
+		message.EndpointUpdatedAtNs != 0 ||
+		message.Protocol != "" ||
+		len(message.Tags) > 0 ||
+		message.Options != nil
+}
+
+// routesFor returns a routing api route for every URI of the messages.
+func (e *routingAPIHTTPEmitter) routesFor(messages []routingtable.RegistryMessage) []models.Route {
+	var routes []models.Route
+	for _, message := range messages {
+		if message.Port == 0 {
+			continue
+		}
+		for _, uri := range message.URIs {
+			routes = append(routes, models.NewRoute(uri, uint16(message.Port), message.Host, message.App, message.RouteServiceUrl, e.ttl))
+		}
+	}
+	return routes
+}
+
+type routeKey struct {
+	route           string
+	ip              string
+	port            uint16
+	routeServiceURL string
+}
+
+// withoutRoutes drops the routes that are also in upserts, as deleting them
+// would briefly remove routes that stay registered.
+func withoutRoutes(routes, upserts []models.Route) []models.Route {
+	upserted := map[routeKey]struct{}{}
+	for _, route := range upserts {
+		upserted[routeKey{route.Route, route.IP, route.Port, route.RouteServiceUrl}] = struct{}{}
+	}
+
+	var remaining []models.Route
+	for _, route := range routes {
+		if _, ok := upserted[routeKey{route.Route, route.IP, route.Port, route.RouteServiceUrl}]; !ok {
+			remaining = append(remaining, route)
+		}
+	}
+	return remaining
+}
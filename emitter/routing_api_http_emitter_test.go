@@ -0,0 +1,192 @@
+package emitter_test
+
+import (
+	"errors"
+
+	"golang.org/x/oauth2"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"code.cloudfoundry.org/route-emitter/emitter"
+	"code.cloudfoundry.org/route-emitter/emitter/fakes"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/routing-api/fake_routing_api"
+	apimodels "code.cloudfoundry.org/routing-api/models"
+	fakeuaa "code.cloudfoundry.org/routing-api/uaaclient/fakes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RoutingAPIHTTPEmitter", func() {
+	var (
+		routingApiClient *fake_routing_api.FakeClient
+		uaaTokenFetcher  *fakeuaa.FakeTokenFetcher
+		natsEmitter      *fakes.FakeNATSEmitter
+		httpEmitter      emitter.NATSEmitter
+		messagesToEmit   routingtable.MessagesToEmit
+	)
+
+	BeforeEach(func() {
+		routingApiClient = new(fake_routing_api.FakeClient)
+		uaaTokenFetcher = &fakeuaa.FakeTokenFetcher{}
+		uaaTokenFetcher.FetchTokenReturns(&oauth2.Token{AccessToken: "accesstoken"}, nil)
+		natsEmitter = &fakes.FakeNATSEmitter{}
+		httpEmitter = emitter.NewRoutingAPIHTTPEmitter(lagertest.NewTestLogger("test"), routingApiClient, uaaTokenFetcher, 120, natsEmitter)
+
+		messagesToEmit = routingtable.MessagesToEmit{
+			RegistrationMessages: []routingtable.RegistryMessage{
+				{Host: "1.1.1.1", Port: 61001, URIs: []string{"foo.com", "bar.com"}, App: "log-guid", RouteServiceUrl: "https://rs.com"},
+				{Host: "1.1.1.1", Port: 0, URIs: []string{"no-port.com"}},
+			},
+			UnregistrationMessages: []routingtable.RegistryMessage{
+				{Host: "1.1.1.2", Port: 61002, URIs: []string{"baz.com"}, App: "log-guid"},
+			},
+		}
+	})
+
+	It("upserts a route with the TTL for every registered URI", func() {
+		Expect(httpEmitter.Emit(messagesToEmit)).To(Succeed())
+		Expect(routingApiClient.UpsertRoutesCallCount()).To(Equal(1))
+		Expect(routingApiClient.UpsertRoutesArgsForCall(0)).To(ConsistOf(
+			apimodels.NewRoute("foo.com", 61001, "1.1.1.1", "log-guid", "https://rs.com", 120),
+			apimodels.NewRoute("bar.com", 61001, "1.1.1.1", "log-guid", "https://rs.com", 120),
+		))
+	})
+
+	It("deletes the route for every unregistered URI", func() {
+		Expect(httpEmitter.Emit(messagesToEmit)).To(Succeed())
+		Expect(routingApiClient.DeleteRoutesCallCount()).To(Equal(1))
+		Expect(routingApiClient.DeleteRoutesArgsForCall(0)).To(ConsistOf(
+			apimodels.NewRoute("baz.com", 61002, "1.1.1.2", "log-guid", "", 120),
+		))
+	})
+
+	It("deletes before it upserts", func() {
+		var calls []string
+		routingApiClient.DeleteRoutesStub = func([]apimodels.Route) error {
+			calls = append(calls, "delete")
+			return nil
+		}
+		routingApiClient.UpsertRoutesStub = func([]apimodels.Route) error {
+			calls = append(calls, "upsert")
+			return nil
+		}
+		Expect(httpEmitter.Emit(messagesToEmit)).To(Succeed())
+		Expect(calls).To(Equal([]string{"delete", "upsert"}))
+	})
+
+	It("does not delete routes that the same emit registers again", func() {
+		messagesToEmit.UnregistrationMessages = append(messagesToEmit.UnregistrationMessages, messagesToEmit.RegistrationMessages[0])
+		Expect(httpEmitter.Emit(messagesToEmit)).To(Succeed())
+		Expect(routingApiClient.DeleteRoutesArgsForCall(0)).To(ConsistOf(
+			apimodels.NewRoute("baz.com", 61002, "1.1.1.2", "log-guid", "", 120),
+		))
+	})
+
+	It("publishes the messages with a TLS port or an isolation segment on NATS", func() {
+		tlsMessage := routingtable.RegistryMessage{Host: "1.1.1.3", Port: 61003, TlsPort: 61443, URIs: []string{"tls.com"}}
+		isolatedMessage := routingtable.RegistryMessage{Host: "1.1.1.4", Port: 61004, URIs: []string{"isolated.com"}, IsolationSegment: "is1"}
+		messagesToEmit.RegistrationMessages = append(messagesToEmit.RegistrationMessages, tlsMessage)
+		messagesToEmit.UnregistrationMessages = append(messagesToEmit.UnregistrationMessages, isolatedMessage)
+
+		Expect(httpEmitter.Emit(messagesToEmit)).To(Succeed())
+		Expect(natsEmitter.EmitCallCount()).To(Equal(1))
+		Expect(natsEmitter.EmitArgsForCall(0)).To(Equal(routingtable.MessagesToEmit{
+			RegistrationMessages:   []routingtable.RegistryMessage{tlsMessage},
+			UnregistrationMessages: []routingtable.RegistryMessage{isolatedMessage},
+		}))
+		Expect(routingApiClient.UpsertRoutesArgsForCall(0)).To(HaveLen(2))
+		Expect(routingApiClient.DeleteRoutesArgsForCall(0)).To(HaveLen(1))
+	})
+
+	It("publishes the messages with route options on NATS", func() {
+		optionsMessage := routingtable.RegistryMessage{
+			Host:    "1.1.1.5",
+			Port:    61005,
+			URIs:    []string{"hashed.com"},
+			Options: &routingtable.RouteOptions{LoadBalancingAlgorithm: "hash", HashHeaderName: "tenant-id"},
+		}
+		messagesToEmit.RegistrationMessages = append(messagesToEmit.RegistrationMessages, optionsMessage)
+
+		Expect(httpEmitter.Emit(messagesToEmit)).To(Succeed())
+		Expect(natsEmitter.EmitCallCount()).To(Equal(1))
+		Expect(natsEmitter.EmitArgsForCall(0)).To(Equal(routingtable.MessagesToEmit{
+			RegistrationMessages: []routingtable.RegistryMessage{optionsMessage},
+		}))
+		Expect(routingApiClient.UpsertRoutesArgsForCall(0)).To(ConsistOf(
+			apimodels.NewRoute("foo.com", 61001, "1.1.1.1", "log-guid", "https://rs.com", 120),
+			apimodels.NewRoute("bar.com", 61001, "1.1.1.1", "log-guid", "https://rs.com", 120),
+		))
+	})
+
+	It("publishes the messages with per-instance fields on NATS", func() {
+		instanceMessage := routingtable.RegistryMessage{
+			Host:                "1.1.1.6",
+			Port:                61006,
+			URIs:                []string{"instance.com"},
+			PrivateInstanceId:   "instance-guid",
+			ServerCertDomainSAN: "instance-guid",
+			Tags:                map[string]string{"component": "route-emitter"},
+		}
+		messagesToEmit.UnregistrationMessages = []routingtable.RegistryMessage{instanceMessage}
+
+		Expect(httpEmitter.Emit(messagesToEmit)).To(Succeed())
+		Expect(natsEmitter.EmitArgsForCall(0)).To(Equal(routingtable.MessagesToEmit{
+			UnregistrationMessages: []routingtable.RegistryMessage{instanceMessage},
+		}))
+		Expect(routingApiClient.DeleteRoutesCallCount()).To(Equal(0))
+	})
+
+	It("authorizes the routing API calls with its bearer token", func() {
+		Expect(httpEmitter.Emit(messagesToEmit)).To(Succeed())
+		Expect(uaaTokenFetcher.FetchTokenCallCount()).To(Equal(1))
+		Expect(routingApiClient.SetTokenArgsForCall(0)).To(Equal("accesstoken"))
+	})
+
+	It("does not call the routing API without HTTP route changes", func() {
+		Expect(httpEmitter.Emit(routingtable.MessagesToEmit{})).To(Succeed())
+		Expect(uaaTokenFetcher.FetchTokenCallCount()).To(Equal(0))
+		Expect(natsEmitter.EmitCallCount()).To(Equal(0))
+	})
+
+	It("passes internal routes on to the internal routes emitter", func() {
+		messagesToEmit.InternalRegistrationMessages = []routingtable.RegistryMessage{{Host: "1.1.1.1", URIs: []string{"foo.apps.internal"}}}
+		Expect(httpEmitter.Emit(messagesToEmit)).To(Succeed())
+		Expect(natsEmitter.EmitCallCount()).To(Equal(1))
+		Expect(natsEmitter.EmitArgsForCall(0)).To(Equal(routingtable.MessagesToEmit{
+			InternalRegistrationMessages: messagesToEmit.InternalRegistrationMessages,
+		}))
+	})
+
+	Context("when the routing API call fails", func() {
+		BeforeEach(func() {
+			routingApiClient.UpsertRoutesReturnsOnCall(0, errors.New("unauthorized"))
+		})
+
+		It("retries once with a refreshed token", func() {
+			Expect(httpEmitter.Emit(messagesToEmit)).To(Succeed())
+			Expect(routingApiClient.UpsertRoutesCallCount()).To(Equal(2))
+			Expect(uaaTokenFetcher.FetchTokenCallCount()).To(Equal(2))
+			_, forceUpdate := uaaTokenFetcher.FetchTokenArgsForCall(1)
+			Expect(forceUpdate).To(BeTrue())
+		})
+
+		It("returns the error when the retry fails too", func() {
+			routingApiClient.UpsertRoutesReturnsOnCall(1, errors.New("unauthorized"))
+			Expect(httpEmitter.Emit(messagesToEmit)).To(MatchError("unauthorized"))
+			Expect(routingApiClient.UpsertRoutesCallCount()).To(Equal(2))
+		})
+	})
+
+	Context("when UAA communication fails", func() {
+		BeforeEach(func() {
+			uaaTokenFetcher.FetchTokenReturns(nil, errors.New("blam"))
+		})
+
+		It("returns an error and emits nothing", func() {
+			Expect(httpEmitter.Emit(messagesToEmit)).To(MatchError("blam"))
+			Expect(routingApiClient.UpsertRoutesCallCount()).To(Equal(0))
+			Expect(routingApiClient.DeleteRoutesCallCount()).To(Equal(0))
+		})
+	})
+})
@@ -0,0 +1,156 @@
+package emitter
+
+import (
+	"code.cloudfoundry.org/lager/v3"
+	routing_api "code.cloudfoundry.org/routing-api"
+	"code.cloudfoundry.org/routing-api/models"
+	"code.cloudfoundry.org/routing-api/uaaclient"
+)
+
+// TCPRouteDrift counts the differences a reconciliation found between the
+// TCP route mappings in routing-api and the ones the emitter owns.
+type TCPRouteDrift struct {
+	// Orphaned mappings were in routing-api but not owned, and were deleted.
+	Orphaned int
+	// Missing mappings were owned but not in routing-api, and were upserted.
+	Missing int
+}
+
+//go:generate counterfeiter -o fakes/fake_tcp_route_reconciler.go . TCPRouteReconciler
+type TCPRouteReconciler interface {
+	Reconcile(owned []models.TcpRouteMapping) (TCPRouteDrift, error)
+}
+
+type tcpRouteReconciler struct {
+	logger           lager.Logger
+	routingAPIClient routing_api.Client
+	uaaTokenFetcher  uaaclient.TokenFetcher
+	ttl              int
+	routerGroupGUIDs map[string]struct{}
+}
+
+// NewTCPRouteReconciler returns a reconciler that makes the TCP route
+// mappings in routing-api match the owned ones. The emitter is assumed to own
+// every mapping of the given router groups, so only an emitter that sees
+// every TCP route of those router groups should reconcile. Mappings of other
+// router groups are left alone.
+func NewTCPRouteReconciler(
+	logger lager.Logger,
+	routingAPIClient routing_api.Client,
+	uaaTokenFetcher uaaclient.TokenFetcher,
+	routeTTL int,
+	routerGroupGUIDs []string,
+) TCPRouteReconciler {
+	guids := make(map[string]struct{}, len(routerGroupGUIDs))
+	for _, guid := range routerGroupGUIDs {
+		guids[guid] = struct{}{}
+	}
+	return &tcpRouteReconciler{
+		logger:           logger.Session("tcp-route-reconciler"),
+		routingAPIClient: routingAPIClient,
+		uaaTokenFetcher:  uaaTokenFetcher,
+		ttl:              routeTTL,
+		routerGroupGUIDs: guids,
+	}
+}
+
+// tcpRouteMappingKey identifies a mapping regardless of its TTL and
+// modification tag, which differ between the table and routing-api.
+type tcpRouteMappingKey struct {
+	routerGroupGUID string
+	externalPort    uint16
+	sniHostname     string
+	hostIP          string
+	hostPort        uint16
+	hostTLSPort     int
+}
+
+func keyForTCPRouteMapping(mapping models.TcpRouteMapping) tcpRouteMappingKey {
+	key := tcpRouteMappingKey{
+		routerGroupGUID: mapping.RouterGroupGuid,
+		externalPort:    mapping.ExternalPort,
+		hostIP:          mapping.HostIP,
+		hostPort:        mapping.HostPort,
+		hostTLSPort:     mapping.HostTLSPort,
+	}
+	if mapping.SniHostname != nil {
+		key.sniHostname = *mapping.SniHostname
+	}
+	return key
+}
+
+func (r *tcpRouteReconciler) inScope(mapping models.TcpRouteMapping) bool {
+	_, ok := r.routerGroupGUIDs[mapping.RouterGroupGuid]
+	return ok
+}
+
+func (r *tcpRouteReconciler) Reconcile(owned []models.TcpRouteMapping) (TCPRouteDrift, error) {
+	var existing []models.TcpRouteMapping
+	err := callWithToken(r.uaaTokenFetcher, r.routingAPIClient, func() error {
+		var err error
+		existing, err = r.routingAPIClient.TcpRouteMappings()
+		return err
+	})
+	if err != nil {
+		r.logger.Error("failed-to-list-tcp-route-mappings", err)
+		return TCPRouteDrift{}, err
+	}
+
+	ownedKeys := map[tcpRouteMappingKey]struct{}{}
+	for _, mapping := range owned {
+		if r.inScope(mapping) {
+			ownedKeys[keyForTCPRouteMapping(mapping)] = struct{}{}
+		}
+	}
+
+	existingKeys := map[tcpRouteMappingKey]struct{}{}
+	var orphaned []models.TcpRouteMapping
+	for _, mapping := range existing {
+		if !r.inScope(mapping) {
+			continue
+		}
+		key := keyForTCPRouteMapping(mapping)
+		existingKeys[key] = struct{}{}
+		if _, ok := ownedKeys[key]; !ok {
+			orphaned = append(orphaned, mapping)
+		}
+	}
+
+	var missing []models.TcpRouteMapping
+	for _, mapping := range owned {
+		if !r.inScope(mapping) {
+			continue
+		}
+		key := keyForTCPRouteMapping(mapping)
+		if _, ok := existingKeys[key]; !ok {
+			mapping.TTL = &r.ttl
+			missing = append(missing, mapping)
+			// duplicates in owned are upserted once
+			existingKeys[key] = struct{}{}
+		}
+	}
+
+	drift := TCPRouteDrift{Orphaned: len(orphaned), Missing: len(missing)}
+	if len(orphaned) == 0 && len(missing) == 0 {
+		r.logger.Debug("no-drift", lager.Data{"num-mappings": len(existingKeys)})
+		return drift, nil
+	}
+	r.logger.Info("reconciling", lager.Data{"orphaned": orphaned, "missing": missing})
+
+	err = callWithToken(r.uaaTokenFetcher, r.routingAPIClient, func() error {
+		if len(orphaned) > 0 {
+			if err := r.routingAPIClient.DeleteTcpRouteMappings(orphaned); err != nil {
+				r.logger.Error("unable-to-delete", err)
+				return err
+			}
+		}
+		if len(missing) > 0 {
+			if err := r.routingAPIClient.UpsertTcpRouteMappings(missing); err != nil {
+				r.logger.Error("unable-to-upsert", err)
+				return err
+			}
+		}
+		return nil
+	})
+	return drift, err
+}
@@ -0,0 +1,119 @@
+package emitter_test
+
+import (
+	"errors"
+
+	"golang.org/x/oauth2"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"code.cloudfoundry.org/route-emitter/emitter"
+	"code.cloudfoundry.org/routing-api/fake_routing_api"
+	apimodels "code.cloudfoundry.org/routing-api/models"
+	fakeuaa "code.cloudfoundry.org/routing-api/uaaclient/fakes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TCPRouteReconciler", func() {
+	var (
+		routingApiClient *fake_routing_api.FakeClient
+		uaaTokenFetcher  *fakeuaa.FakeTokenFetcher
+		routerGroupGUIDs []string
+		reconciler       emitter.TCPRouteReconciler
+
+		kept, orphan, missing, otherGroup apimodels.TcpRouteMapping
+	)
+
+	const ttl = 120
+
+	withTTL := func(mapping apimodels.TcpRouteMapping, ttl int) apimodels.TcpRouteMapping {
+		mapping.TTL = &ttl
+		return mapping
+	}
+
+	BeforeEach(func() {
+		routingApiClient = new(fake_routing_api.FakeClient)
+		uaaTokenFetcher = &fakeuaa.FakeTokenFetcher{}
+		uaaTokenFetcher.FetchTokenReturns(&oauth2.Token{AccessToken: "accesstoken"}, nil)
+		routerGroupGUIDs = []string{"router-group", "other-router-group"}
+
+		kept = apimodels.NewTcpRouteMapping("router-group", 61000, "1.1.1.1", 62000, 0)
+		orphan = apimodels.NewTcpRouteMapping("router-group", 61001, "1.1.1.1", 62001, ttl)
+		missing = apimodels.NewTcpRouteMapping("router-group", 61002, "1.1.1.1", 62002, 0)
+		otherGroup = apimodels.NewTcpRouteMapping("other-router-group", 61003, "1.1.1.1", 62003, ttl)
+
+		routingApiClient.TcpRouteMappingsReturns([]apimodels.TcpRouteMapping{withTTL(kept, ttl), orphan, otherGroup}, nil)
+	})
+
+	JustBeforeEach(func() {
+		reconciler = emitter.NewTCPRouteReconciler(lagertest.NewTestLogger("test"), routingApiClient, uaaTokenFetcher, ttl, routerGroupGUIDs)
+	})
+
+	It("deletes orphaned mappings and upserts missing ones with the TTL", func() {
+		drift, err := reconciler.Reconcile([]apimodels.TcpRouteMapping{kept, missing})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(drift).To(Equal(emitter.TCPRouteDrift{Orphaned: 2, Missing: 1}))
+
+		Expect(routingApiClient.DeleteTcpRouteMappingsCallCount()).To(Equal(1))
+		Expect(routingApiClient.DeleteTcpRouteMappingsArgsForCall(0)).To(ConsistOf(orphan, otherGroup))
+		Expect(routingApiClient.UpsertTcpRouteMappingsCallCount()).To(Equal(1))
+		Expect(routingApiClient.UpsertTcpRouteMappingsArgsForCall(0)).To(ConsistOf(withTTL(missing, ttl)))
+	})
+
+	It("authorizes the routing API calls with its bearer token", func() {
+		_, err := reconciler.Reconcile([]apimodels.TcpRouteMapping{kept, missing})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(routingApiClient.SetTokenCallCount()).To(Equal(2))
+		Expect(routingApiClient.SetTokenArgsForCall(0)).To(Equal("accesstoken"))
+	})
+
+	Context("when there is no drift", func() {
+		It("changes nothing", func() {
+			drift, err := reconciler.Reconcile([]apimodels.TcpRouteMapping{kept, orphan, otherGroup})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(drift).To(Equal(emitter.TCPRouteDrift{}))
+			Expect(routingApiClient.DeleteTcpRouteMappingsCallCount()).To(Equal(0))
+			Expect(routingApiClient.UpsertTcpRouteMappingsCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when scoped to fewer router groups", func() {
+		BeforeEach(func() {
+			routerGroupGUIDs = []string{"router-group"}
+		})
+
+		It("leaves mappings of other router groups alone", func() {
+			drift, err := reconciler.Reconcile([]apimodels.TcpRouteMapping{kept, missing})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(drift).To(Equal(emitter.TCPRouteDrift{Orphaned: 1, Missing: 1}))
+			Expect(routingApiClient.DeleteTcpRouteMappingsArgsForCall(0)).To(ConsistOf(orphan))
+		})
+	})
+
+	Context("when listing the mappings fails", func() {
+		BeforeEach(func() {
+			routingApiClient.TcpRouteMappingsReturns(nil, errors.New("boom"))
+		})
+
+		It("returns the error and changes nothing", func() {
+			_, err := reconciler.Reconcile([]apimodels.TcpRouteMapping{kept, missing})
+			Expect(err).To(MatchError("boom"))
+			Expect(routingApiClient.TcpRouteMappingsCallCount()).To(Equal(2))
+			Expect(routingApiClient.DeleteTcpRouteMappingsCallCount()).To(Equal(0))
+			Expect(routingApiClient.UpsertTcpRouteMappingsCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when deleting the orphans fails", func() {
+		BeforeEach(func() {
+			routingApiClient.DeleteTcpRouteMappingsReturns(errors.New("boom"))
+		})
+
+		It("returns the drift and the error", func() {
+			drift, err := reconciler.Reconcile([]apimodels.TcpRouteMapping{kept, missing})
+			Expect(err).To(MatchError("boom"))
+			Expect(drift).To(Equal(emitter.TCPRouteDrift{Orphaned: 2, Missing: 1}))
+		})
+	})
+})
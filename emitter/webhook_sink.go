@@ -0,0 +1,54 @@
+package emitter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"code.cloudfoundry.org/route-emitter/routingtable"
+)
+
+// WebhookPayload is the body a webhook sink posts for every change.
+type WebhookPayload struct {
+	Messages      routingtable.MessagesToEmit   `json:"messages"`
+	RouteMappings routingtable.TCPRouteMappings `json:"route_mappings"`
+}
+
+// WebhookRouteSink posts every change to a URL as JSON. Changes without any
+// messages or route mappings are not posted.
+func WebhookRouteSink(client *http.Client, url string) RouteSink {
+	return &webhookRouteSink{client: client, url: url}
+}
+
+type webhookRouteSink struct {
+	client *http.Client
+	url    string
+}
+
+func (s *webhookRouteSink) Emit(messagesToEmit routingtable.MessagesToEmit, routeMappings routingtable.TCPRouteMappings) error {
+	if len(messagesToEmit.RegistrationMessages) == 0 &&
+		len(messagesToEmit.UnregistrationMessages) == 0 &&
+		len(messagesToEmit.InternalRegistrationMessages) == 0 &&
+		len(messagesToEmit.InternalUnregistrationMessages) == 0 &&
+		len(routeMappings.Registrations) == 0 &&
+		len(routeMappings.Unregistrations) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(WebhookPayload{Messages: messagesToEmit, RouteMappings: routeMappings})
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.client.Post(s.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+package internaldns_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestInternalDNS(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Internal DNS Suite")
+}
@@ -0,0 +1 @@
+package internaldns // import "code.cloudfoundry.org/route-emitter/internaldns"
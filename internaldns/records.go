@@ -0,0 +1,97 @@
+package internaldns
+
+import (
+	"net"
+	"strings"
+
+	"code.cloudfoundry.org/route-emitter/routingtable"
+)
+
+// instance is one registration of an internal route: the container address
+// of an instance and the port of the route, if it has one.
+type instance struct {
+	ip     net.IP
+	port   uint32
+	names  []string
+	target string
+}
+
+// records maps every internal route hostname and instance alias to the
+// instances it resolves to.
+type records map[string][]*instance
+
+func newRecords(messages []routingtable.RegistryMessage) records {
+	r := records{}
+	for _, message := range messages {
+		ip := net.ParseIP(message.Host)
+		if ip == nil {
+			continue
+		}
+		inst := &instance{ip: ip, port: message.Port}
+		for _, uri := range message.URIs {
+			name := normalize(uri)
+			inst.names = append(inst.names, name)
+			r[name] = append(r[name], inst)
+		}
+	}
+
+	// an SRV record needs a target resolving to the instance alone, which
+	// is the first alias of the instance no other instance shares
+	for _, instances := range r {
+		for _, inst := range instances {
+			if inst.target != "" {
+				continue
+			}
+			for _, name := range inst.names {
+				if r.resolvesOnlyTo(name, inst.ip) {
+					inst.target = name
+					break
+				}
+			}
+		}
+	}
+	return r
+}
+
+func (r records) resolvesOnlyTo(name string, ip net.IP) bool {
+	for _, inst := range r[name] {
+		if !inst.ip.Equal(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// ips returns the distinct addresses of a name.
+func (r records) ips(name string) []net.IP {
+	var ips []net.IP
+	for _, inst := range r[name] {
+		duplicate := false
+		for _, ip := range ips {
+			if ip.Equal(inst.ip) {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			ips = append(ips, inst.ip)
+		}
+	}
+	return ips
+}
+
+// normalize lower cases a name and drops the trailing dot of a fully
+// qualified one.
+func normalize(name string) string {
+	return strings.TrimSuffix(strings.ToLower(name), ".")
+}
+
+// srvName returns the hostname an SRV query is for, dropping the
+// _service._proto labels if present.
+func srvName(name string) string {
+	labels := strings.Split(name, ".")
+	for len(labels) > 1 && strings.HasPrefix(labels[0], "_") {
+		labels = labels[1:]
+	}
+	return strings.Join(labels, ".")
+}
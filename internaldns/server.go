@@ -0,0 +1,201 @@
+package internaldns
+
+import (
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3"
+	"code.cloudfoundry.org/route-emitter/emitter"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"github.com/miekg/dns"
+)
+
+// Server answers DNS queries for internal route hostnames and their instance
+// aliases from the routing table. A and AAAA queries resolve to the container
+// addresses of the instances. SRV queries resolve to the instances of routes
+// with a port, targeting an alias of each instance.
+//
+// The records are rebuilt from the table after it changed. The server learns
+// about changes as a route sink.
+type Server struct {
+	logger  lager.Logger
+	table   routingtable.RoutingTable
+	address string
+	ttl     uint32
+
+	lock    sync.Mutex
+	stale   bool
+	records records
+}
+
+var _ emitter.RouteSink = new(Server)
+
+func NewServer(logger lager.Logger, table routingtable.RoutingTable, address string, ttl time.Duration) *Server {
+	return &Server{
+		logger:  logger.Session("internal-dns"),
+		table:   table,
+		address: address,
+		ttl:     uint32(ttl.Seconds()),
+		stale:   true,
+	}
+}
+
+// Emit marks the records stale if internal routes changed.
+func (s *Server) Emit(messagesToEmit routingtable.MessagesToEmit, _ routingtable.TCPRouteMappings) error {
+	if len(messagesToEmit.InternalRegistrationMessages) == 0 && len(messagesToEmit.InternalUnregistrationMessages) == 0 {
+		return nil
+	}
+	s.lock.Lock()
+	s.stale = true
+	s.lock.Unlock()
+	return nil
+}
+
+func (s *Server) currentRecords() records {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.stale {
+		_, messages := s.table.GetInternalRoutingEvents()
+		s.records = newRecords(messages.InternalRegistrationMessages)
+		s.stale = false
+	}
+	return s.records
+}
+
+func (s *Server) ServeDNS(w dns.ResponseWriter, req *dns.Msg) {
+	resp := new(dns.Msg)
+	resp.SetReply(req)
+	resp.Authoritative = true
+
+	if len(req.Question) != 1 {
+		resp.Rcode = dns.RcodeFormatError
+		s.write(w, resp)
+		return
+	}
+
+	question := req.Question[0]
+	name := normalize(question.Name)
+	records := s.currentRecords()
+
+	switch question.Qtype {
+	case dns.TypeA, dns.TypeAAAA:
+		if _, ok := records[name]; !ok {
+			resp.Rcode = dns.RcodeNameError
+			break
+		}
+		for _, ip := range records.ips(name) {
+			if rr := s.addressRecord(question.Name, ip, question.Qtype); rr != nil {
+				resp.Answer = append(resp.Answer, rr)
+			}
+		}
+	case dns.TypeSRV:
+		instances, ok := records[srvName(name)]
+		if !ok {
+			resp.Rcode = dns.RcodeNameError
+			break
+		}
+		for _, inst := range instances {
+			if inst.port == 0 || inst.target == "" {
+				continue
+			}
+			target := dns.Fqdn(inst.target)
+			resp.Answer = append(resp.Answer, &dns.SRV{
+				Hdr:    s.header(question.Name, dns.TypeSRV),
+				Port:   uint16(inst.port),
+				Target: target,
+			})
+			if rr := s.addressRecord(target, inst.ip, addressType(inst.ip)); rr != nil {
+				resp.Extra = append(resp.Extra, rr)
+			}
+		}
+	default:
+		if _, ok := records[name]; !ok {
+			resp.Rcode = dns.RcodeNameError
+		}
+	}
+
+	s.write(w, resp)
+}
+
+func (s *Server) header(name string, rrtype uint16) dns.RR_Header {
+	return dns.RR_Header{Name: name, Rrtype: rrtype, Class: dns.ClassINET, Ttl: s.ttl}
+}
+
+// addressRecord returns an A or AAAA record for the ip, or nil if the ip is
+// not of the type.
+func (s *Server) addressRecord(name string, ip net.IP, qtype uint16) dns.RR {
+	if addressType(ip) != qtype {
+		return nil
+	}
+	if qtype == dns.TypeA {
+		return &dns.A{Hdr: s.header(name, dns.TypeA), A: ip.To4()}
+	}
+	return &dns.AAAA{Hdr: s.header(name, dns.TypeAAAA), AAAA: ip}
+}
+
+func addressType(ip net.IP) uint16 {
+	if ip.To4() != nil {
+		return dns.TypeA
+	}
+	return dns.TypeAAAA
+}
+
+func (s *Server) write(w dns.ResponseWriter, resp *dns.Msg) {
+	err := w.WriteMsg(resp)
+	if err != nil {
+		s.logger.Error("failed-to-write-response", err)
+	}
+}
+
+// Run serves DNS over UDP and TCP on the address.
+func (s *Server) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	servers := []*dns.Server{
+		{Addr: s.address, Net: "udp", Handler: s},
+		{Addr: s.address, Net: "tcp", Handler: s},
+	}
+
+	errCh := make(chan error, len(servers))
+	var started sync.WaitGroup
+	for _, server := range servers {
+		started.Add(1)
+		server.NotifyStartedFunc = started.Done
+		go func(server *dns.Server) {
+			errCh <- server.ListenAndServe()
+		}(server)
+	}
+
+	startedCh := make(chan struct{})
+	go func() {
+		started.Wait()
+		close(startedCh)
+	}()
+
+	select {
+	case <-startedCh:
+	case err := <-errCh:
+		shutdown(servers)
+		return err
+	}
+
+	s.logger.Info("started", lager.Data{"address": s.address})
+	close(ready)
+
+	select {
+	case <-signals:
+		shutdown(servers)
+		s.logger.Info("stopped")
+		return nil
+	case err := <-errCh:
+		shutdown(servers)
+		return err
+	}
+}
+
+func shutdown(servers []*dns.Server) {
+	for _, server := range servers {
+		server.Shutdown()
+	}
+}
@@ -0,0 +1,117 @@
+package internaldns_test
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"code.cloudfoundry.org/route-emitter/internaldns"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/route-emitter/routingtable/fakeroutingtable"
+	"github.com/miekg/dns"
+	"github.com/tedsuo/ifrit"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Server", func() {
+	var (
+		table   *fakeroutingtable.FakeRoutingTable
+		server  *internaldns.Server
+		address string
+		process ifrit.Process
+	)
+
+	setMessages := func(messages ...routingtable.RegistryMessage) {
+		table.GetInternalRoutingEventsReturns(routingtable.TCPRouteMappings{}, routingtable.MessagesToEmit{
+			InternalRegistrationMessages: messages,
+		})
+	}
+
+	query := func(name string, qtype uint16) *dns.Msg {
+		req := new(dns.Msg)
+		req.SetQuestion(dns.Fqdn(name), qtype)
+		resp, _, err := new(dns.Client).Exchange(req, address)
+		Expect(err).NotTo(HaveOccurred())
+		return resp
+	}
+
+	BeforeEach(func() {
+		table = &fakeroutingtable.FakeRoutingTable{}
+		setMessages(
+			routingtable.RegistryMessage{Host: "10.0.0.1", URIs: []string{"app.apps.internal", "0.app.apps.internal"}},
+			routingtable.RegistryMessage{Host: "10.0.0.2", URIs: []string{"app.apps.internal", "1.app.apps.internal"}},
+			routingtable.RegistryMessage{Host: "10.0.0.1", Port: 8080, URIs: []string{"api.apps.internal", "0.api.apps.internal"}},
+		)
+
+		address = fmt.Sprintf("127.0.0.1:%d", 23053+GinkgoParallelProcess())
+		server = internaldns.NewServer(lagertest.NewTestLogger("test"), table, address, 10*time.Second)
+		process = ifrit.Background(server)
+		Eventually(process.Ready()).Should(BeClosed())
+	})
+
+	AfterEach(func() {
+		process.Signal(os.Interrupt)
+		Eventually(process.Wait()).Should(Receive())
+	})
+
+	It("answers A queries for route hostnames with every instance", func() {
+		resp := query("app.apps.internal", dns.TypeA)
+		Expect(resp.Rcode).To(Equal(dns.RcodeSuccess))
+		Expect(resp.Answer).To(HaveLen(2))
+
+		ips := []string{}
+		for _, rr := range resp.Answer {
+			a := rr.(*dns.A)
+			Expect(a.Hdr.Ttl).To(Equal(uint32(10)))
+			ips = append(ips, a.A.String())
+		}
+		Expect(ips).To(ConsistOf("10.0.0.1", "10.0.0.2"))
+	})
+
+	It("answers A queries for instance aliases with the instance", func() {
+		resp := query("1.App.apps.internal", dns.TypeA)
+		Expect(resp.Answer).To(HaveLen(1))
+		Expect(resp.Answer[0].(*dns.A).A.String()).To(Equal("10.0.0.2"))
+	})
+
+	It("answers SRV queries for routes with a port", func() {
+		resp := query("_http._tcp.api.apps.internal", dns.TypeSRV)
+		Expect(resp.Answer).To(HaveLen(1))
+		srv := resp.Answer[0].(*dns.SRV)
+		Expect(srv.Port).To(Equal(uint16(8080)))
+		Expect(srv.Target).To(Equal("api.apps.internal."))
+		Expect(resp.Extra).To(HaveLen(1))
+		Expect(resp.Extra[0].(*dns.A).A.String()).To(Equal("10.0.0.1"))
+	})
+
+	It("does not answer SRV queries for routes without a port", func() {
+		resp := query("app.apps.internal", dns.TypeSRV)
+		Expect(resp.Rcode).To(Equal(dns.RcodeSuccess))
+		Expect(resp.Answer).To(BeEmpty())
+	})
+
+	It("answers unknown names with NXDOMAIN", func() {
+		resp := query("other.apps.internal", dns.TypeA)
+		Expect(resp.Rcode).To(Equal(dns.RcodeNameError))
+	})
+
+	It("reads the table only after internal routes changed", func() {
+		query("app.apps.internal", dns.TypeA)
+		query("app.apps.internal", dns.TypeA)
+		Expect(table.GetInternalRoutingEventsCallCount()).To(Equal(1))
+
+		setMessages(routingtable.RegistryMessage{Host: "10.0.0.3", URIs: []string{"app.apps.internal"}})
+		Expect(server.Emit(routingtable.MessagesToEmit{}, routingtable.TCPRouteMappings{})).To(Succeed())
+		Expect(query("app.apps.internal", dns.TypeA).Answer).To(HaveLen(2))
+
+		Expect(server.Emit(routingtable.MessagesToEmit{
+			InternalRegistrationMessages: []routingtable.RegistryMessage{{Host: "10.0.0.3"}},
+		}, routingtable.TCPRouteMappings{})).To(Succeed())
+		resp := query("app.apps.internal", dns.TypeA)
+		Expect(resp.Answer).To(HaveLen(1))
+		Expect(resp.Answer[0].(*dns.A).A.String()).To(Equal("10.0.0.3"))
+	})
+})
@@ -0,0 +1,315 @@
+package introspection
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"strconv"
+
+	"code.cloudfoundry.org/lager/v3"
+	"code.cloudfoundry.org/route-emitter/routehandlers"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+)
+
+const (
+	RoutesPath       = "/v1/routes"
+	HeldSyncPath     = "/v1/sync/held"
+	SyncOverridePath = "/v1/sync/override"
+	SyncReportPath   = "/v1/sync/report"
+)
+
+// SyncReporter provides the report of the most recent sync.
+type SyncReporter interface {
+	LastSyncReport() (routingtable.SyncReport, bool)
+}
+
+// Response lists the entries of each routing table that match the request
+// filters. Every filter that is present must match for an entry to be listed.
+type Response struct {
+	HTTP     []Entry `json:"http"`
+	TCP      []Entry `json:"tcp"`
+	Internal []Entry `json:"internal"`
+}
+
+type Entry struct {
+	routingtable.SnapshotEntry
+	Endpoints []Endpoint `json:"endpoints"`
+}
+
+type Endpoint struct {
+	routingtable.Endpoint
+	Presence              string `json:"presence"`
+	DirectInstanceAddress bool   `json:"direct_instance_address"`
+}
+
+type filter struct {
+	processGUID     string
+	hostname        string
+	routerGroupGUID string
+	externalPort    *uint32
+	instanceGUID    string
+}
+
+type routesHandler struct {
+	logger lager.Logger
+	table  routingtable.RoutingTable
+}
+
+// HeldSyncResponse describes the sync held back by the unregistration guard.
+type HeldSyncResponse struct {
+	Held      bool                         `json:"held"`
+	Shrinkage *routehandlers.SyncShrinkage `json:"shrinkage,omitempty"`
+}
+
+type syncReportHandler struct {
+	logger   lager.Logger
+	reporter SyncReporter
+}
+
+type syncGuardHandler struct {
+	logger lager.Logger
+	guard  *routehandlers.UnregistrationGuard
+}
+
+// NewHandler serves the routing table and, when they are not nil, the report
+// of the most recent sync and the sync held by the guard, which operators can
+// override from the same host.
+func NewHandler(logger lager.Logger, table routingtable.RoutingTable, reporter SyncReporter, guard *routehandlers.UnregistrationGuard) http.Handler {
+	logger = logger.Session("introspection")
+	mux := http.NewServeMux()
+	mux.Handle(RoutesPath, &routesHandler{
+		logger: logger,
+		table:  table,
+	})
+	if reporter != nil {
+		mux.Handle(SyncReportPath, &syncReportHandler{logger: logger, reporter: reporter})
+	}
+	if guard != nil {
+		guardHandler := &syncGuardHandler{logger: logger, guard: guard}
+		mux.HandleFunc(HeldSyncPath, guardHandler.held)
+		mux.HandleFunc(SyncOverridePath, guardHandler.override)
+	}
+	return mux
+}
+
+func (h *syncReportHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	logger := h.logger.Session("sync-report")
+
+	if req.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	report, ok := h.reporter.LastSyncReport()
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(report)
+	if err != nil {
+		logger.Error("failed-to-encode-response", err)
+	}
+}
+
+func (h *syncGuardHandler) held(w http.ResponseWriter, req *http.Request) {
+	logger := h.logger.Session("held-sync")
+
+	if req.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	response := HeldSyncResponse{}
+	if shrinkage, ok := h.guard.Held(); ok {
+		response.Held = true
+		response.Shrinkage = &shrinkage
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(response)
+	if err != nil {
+		logger.Error("failed-to-encode-response", err)
+	}
+}
+
+// override forces the held sync through. As it can unregister most routes
+// and has no authentication, it is only accepted from loopback addresses.
+func (h *syncGuardHandler) override(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !IsLoopbackAddress(req.RemoteAddr) {
+		h.logger.Info("rejecting-non-loopback-override", lager.Data{"remote-addr": req.RemoteAddr})
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	h.logger.Info("overriding-unregistration-guard")
+	h.guard.Override()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// IsLoopbackAddress reports whether the host of a host:port address is
+// localhost or a loopback IP.
+func IsLoopbackAddress(address string) bool {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func (h *routesHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	logger := h.logger.Session("list-routes", lager.Data{"query": req.URL.RawQuery})
+
+	if req.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	f, err := parseFilter(req)
+	if err != nil {
+		logger.Error("invalid-filter", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	snapshot := h.snapshot(f)
+	response := Response{
+		HTTP:     filterEntries(snapshot.HTTP, f, snapshot.DirectInstanceRoutes),
+		TCP:      filterEntries(snapshot.TCP, f, snapshot.DirectInstanceRoutes),
+		Internal: filterEntries(snapshot.Internal, f, snapshot.DirectInstanceRoutes),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(response)
+	if err != nil {
+		logger.Error("failed-to-encode-response", err)
+	}
+}
+
+// snapshot uses the routing table indexes to avoid copying the whole table
+// when the filter names a hostname, an instance or a router group port.
+func (h *routesHandler) snapshot(f filter) routingtable.Snapshot {
+	var keys routingtable.RoutingKeys
+	indexed := false
+	narrow := func(candidates routingtable.RoutingKeys) {
+		if !indexed {
+			keys = candidates
+			indexed = true
+			return
+		}
+		keys = keys.Remove(keys.Remove(candidates))
+	}
+
+	if f.hostname != "" {
+		narrow(h.table.RoutingKeysForHostname(f.hostname))
+	}
+	if f.instanceGUID != "" {
+		narrow(h.table.RoutingKeysForInstance(f.instanceGUID))
+	}
+	if f.routerGroupGUID != "" && f.externalPort != nil {
+		narrow(h.table.RoutingKeysForRouterGroupPort(f.routerGroupGUID, *f.externalPort))
+	}
+
+	if !indexed {
+		return h.table.Snapshot()
+	}
+	return h.table.SnapshotForRoutingKeys(keys)
+}
+
+func parseFilter(req *http.Request) (filter, error) {
+	query := req.URL.Query()
+	f := filter{
+		processGUID:     query.Get("process_guid"),
+		hostname:        query.Get("hostname"),
+		routerGroupGUID: query.Get("router_group_guid"),
+		instanceGUID:    query.Get("instance_guid"),
+	}
+
+	if port := query.Get("external_port"); port != "" {
+		parsed, err := strconv.ParseUint(port, 10, 16)
+		if err != nil {
+			return filter{}, err
+		}
+		externalPort := uint32(parsed)
+		f.externalPort = &externalPort
+	}
+
+	return f, nil
+}
+
+func filterEntries(snapshotEntries []routingtable.SnapshotEntry, f filter, directInstanceRoutes bool) []Entry {
+	entries := []Entry{}
+	for _, snapshotEntry := range snapshotEntries {
+		if !f.matchesRoutes(snapshotEntry) {
+			continue
+		}
+
+		endpoints := []Endpoint{}
+		for _, endpoint := range snapshotEntry.Endpoints {
+			if f.instanceGUID != "" && endpoint.InstanceGUID != f.instanceGUID {
+				continue
+			}
+			endpoints = append(endpoints, Endpoint{
+				Endpoint:              endpoint,
+				Presence:              endpoint.Presence.String(),
+				DirectInstanceAddress: endpoint.IsDirectInstanceRoute(directInstanceRoutes),
+			})
+		}
+		if f.instanceGUID != "" && len(endpoints) == 0 {
+			continue
+		}
+
+		entries = append(entries, Entry{
+			SnapshotEntry: snapshotEntry,
+			Endpoints:     endpoints,
+		})
+	}
+	return entries
+}
+
+func (f filter) matchesRoutes(entry routingtable.SnapshotEntry) bool {
+	if f.processGUID != "" && entry.ProcessGUID != f.processGUID {
+		return false
+	}
+
+	if f.hostname != "" {
+		found := false
+		for _, route := range entry.HTTPRoutes {
+			found = found || route.Hostname == f.hostname
+		}
+		for _, route := range entry.InternalRoutes {
+			found = found || route.Hostname == f.hostname
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if f.routerGroupGUID != "" || f.externalPort != nil {
+		found := false
+		for _, route := range entry.TCPRoutes {
+			if f.routerGroupGUID != "" && route.RouterGroupGUID != f.routerGroupGUID {
+				continue
+			}
+			if f.externalPort != nil && route.Port != *f.externalPort {
+				continue
+			}
+			found = true
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,295 @@
+package introspection_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"code.cloudfoundry.org/route-emitter/introspection"
+	"code.cloudfoundry.org/route-emitter/routehandlers"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/route-emitter/routingtable/fakeroutingtable"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type fakeSyncReporter struct {
+	report *routingtable.SyncReport
+}
+
+func (r *fakeSyncReporter) LastSyncReport() (routingtable.SyncReport, bool) {
+	if r.report == nil {
+		return routingtable.SyncReport{}, false
+	}
+	return *r.report, true
+}
+
+var _ = Describe("Handler", func() {
+	var (
+		table    *fakeroutingtable.FakeRoutingTable
+		handler  http.Handler
+		guard    *routehandlers.UnregistrationGuard
+		reporter introspection.SyncReporter
+		recorder *httptest.ResponseRecorder
+		response introspection.Response
+	)
+
+	BeforeEach(func() {
+		guard = nil
+		reporter = nil
+		tag := &models.ModificationTag{Epoch: "abc", Index: 1}
+		table = &fakeroutingtable.FakeRoutingTable{}
+		snapshot := routingtable.Snapshot{
+			Version: routingtable.SnapshotVersion,
+			HTTP: []routingtable.SnapshotEntry{
+				{
+					ProcessGUID:   "process-guid-1",
+					ContainerPort: 8080,
+					Domain:        "domain",
+					HTTPRoutes:    []routingtable.Route{{Hostname: "foo.example.com"}},
+					Endpoints: []routingtable.Endpoint{
+						{InstanceGUID: "ig-1", Host: "1.1.1.1", Port: 61000, ModificationTag: tag, Presence: models.ActualLRP_Ordinary},
+						{
+							InstanceGUID:     "ig-2",
+							Host:             "1.1.1.2",
+							Port:             61001,
+							ModificationTag:  tag,
+							Presence:         models.ActualLRP_Evacuating,
+							PreferredAddress: models.ActualLRPNetInfo_PreferredAddressInstance,
+						},
+					},
+				},
+				{
+					ProcessGUID:   "process-guid-2",
+					ContainerPort: 8080,
+					Domain:        "domain",
+					HTTPRoutes:    []routingtable.Route{{Hostname: "bar.example.com"}},
+				},
+			},
+			TCP: []routingtable.SnapshotEntry{
+				{
+					ProcessGUID:   "process-guid-1",
+					ContainerPort: 8080,
+					Domain:        "domain",
+					TCPRoutes:     []routingtable.ExternalEndpointInfo{{RouterGroupGUID: "rg-1", Port: 1234}},
+					Endpoints: []routingtable.Endpoint{
+						{InstanceGUID: "ig-1", Host: "1.1.1.1", Port: 61000, ModificationTag: tag},
+					},
+				},
+			},
+			Internal: []routingtable.SnapshotEntry{
+				{
+					ProcessGUID:    "process-guid-2",
+					Domain:         "domain",
+					InternalRoutes: []routingtable.InternalRoute{{Hostname: "bar.apps.internal"}},
+				},
+			},
+		}
+		table.SnapshotReturns(snapshot)
+		table.SnapshotForRoutingKeysReturns(snapshot)
+
+		recorder = httptest.NewRecorder()
+		response = introspection.Response{}
+	})
+
+	JustBeforeEach(func() {
+		handler = introspection.NewHandler(lagertest.NewTestLogger("test"), table, reporter, guard)
+	})
+
+	get := func(query string) {
+		req := httptest.NewRequest(http.MethodGet, introspection.RoutesPath+query, nil)
+		handler.ServeHTTP(recorder, req)
+		if recorder.Code == http.StatusOK {
+			Expect(json.Unmarshal(recorder.Body.Bytes(), &response)).To(Succeed())
+		}
+	}
+
+	processGUIDs := func(entries []introspection.Entry) []string {
+		guids := []string{}
+		for _, entry := range entries {
+			guids = append(guids, entry.ProcessGUID)
+		}
+		return guids
+	}
+
+	It("lists every table when no filter is given", func() {
+		get("")
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(recorder.Header().Get("Content-Type")).To(Equal("application/json"))
+		Expect(processGUIDs(response.HTTP)).To(ConsistOf("process-guid-1", "process-guid-2"))
+		Expect(processGUIDs(response.TCP)).To(ConsistOf("process-guid-1"))
+		Expect(processGUIDs(response.Internal)).To(ConsistOf("process-guid-2"))
+	})
+
+	It("reports endpoint presence and whether the direct instance address is used", func() {
+		get("?process_guid=process-guid-1")
+		Expect(response.HTTP).To(HaveLen(1))
+		endpoints := response.HTTP[0].Endpoints
+		Expect(endpoints).To(HaveLen(2))
+		Expect(endpoints[0].Presence).To(Equal(models.ActualLRP_Ordinary.String()))
+		Expect(endpoints[0].DirectInstanceAddress).To(BeFalse())
+		Expect(endpoints[0].ModificationTag).To(Equal(&models.ModificationTag{Epoch: "abc", Index: 1}))
+		Expect(endpoints[1].Presence).To(Equal(models.ActualLRP_Evacuating.String()))
+		Expect(endpoints[1].DirectInstanceAddress).To(BeTrue())
+		Expect(response.HTTP[0].Domain).To(Equal("domain"))
+	})
+
+	It("filters by process guid", func() {
+		get("?process_guid=process-guid-2")
+		Expect(table.SnapshotCallCount()).To(Equal(1))
+		Expect(processGUIDs(response.HTTP)).To(ConsistOf("process-guid-2"))
+		Expect(response.TCP).To(BeEmpty())
+		Expect(processGUIDs(response.Internal)).To(ConsistOf("process-guid-2"))
+	})
+
+	It("filters by hostname using the hostname index", func() {
+		get("?hostname=bar.apps.internal")
+		Expect(table.RoutingKeysForHostnameCallCount()).To(Equal(1))
+		Expect(table.RoutingKeysForHostnameArgsForCall(0)).To(Equal("bar.apps.internal"))
+		Expect(table.SnapshotForRoutingKeysCallCount()).To(Equal(1))
+		Expect(table.SnapshotCallCount()).To(Equal(0))
+		Expect(response.HTTP).To(BeEmpty())
+		Expect(response.TCP).To(BeEmpty())
+		Expect(processGUIDs(response.Internal)).To(ConsistOf("process-guid-2"))
+	})
+
+	It("filters by router group and external port using the router group index", func() {
+		get("?router_group_guid=rg-1&external_port=1234")
+		Expect(table.RoutingKeysForRouterGroupPortCallCount()).To(Equal(1))
+		routerGroupGUID, port := table.RoutingKeysForRouterGroupPortArgsForCall(0)
+		Expect(routerGroupGUID).To(Equal("rg-1"))
+		Expect(port).To(Equal(uint32(1234)))
+		Expect(response.HTTP).To(BeEmpty())
+		Expect(processGUIDs(response.TCP)).To(ConsistOf("process-guid-1"))
+
+		response = introspection.Response{}
+		recorder = httptest.NewRecorder()
+		get("?router_group_guid=rg-1&external_port=4321")
+		Expect(response.TCP).To(BeEmpty())
+	})
+
+	It("filters by instance guid and only lists that instance", func() {
+		get("?instance_guid=ig-2")
+		Expect(table.RoutingKeysForInstanceCallCount()).To(Equal(1))
+		Expect(table.RoutingKeysForInstanceArgsForCall(0)).To(Equal("ig-2"))
+		Expect(processGUIDs(response.HTTP)).To(ConsistOf("process-guid-1"))
+		Expect(response.HTTP[0].Endpoints).To(HaveLen(1))
+		Expect(response.HTTP[0].Endpoints[0].InstanceGUID).To(Equal("ig-2"))
+		Expect(response.TCP).To(BeEmpty())
+	})
+
+	Context("when the external port is invalid", func() {
+		It("responds with bad request", func() {
+			get("?external_port=not-a-port")
+			Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+		})
+	})
+
+	Context("when the method is not GET", func() {
+		It("responds with method not allowed", func() {
+			req := httptest.NewRequest(http.MethodPost, introspection.RoutesPath, nil)
+			handler.ServeHTTP(recorder, req)
+			Expect(recorder.Code).To(Equal(http.StatusMethodNotAllowed))
+		})
+	})
+
+	Describe("the sync report", func() {
+		getReport := func() {
+			req := httptest.NewRequest(http.MethodGet, introspection.SyncReportPath, nil)
+			handler.ServeHTTP(recorder, req)
+		}
+
+		Context("when there has been no sync", func() {
+			BeforeEach(func() {
+				reporter = &fakeSyncReporter{}
+			})
+
+			It("responds with not found", func() {
+				getReport()
+				Expect(recorder.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+
+		Context("when there has been a sync", func() {
+			BeforeEach(func() {
+				reporter = &fakeSyncReporter{report: &routingtable.SyncReport{
+					RouteTypes: map[string]map[string]routingtable.SyncCounts{
+						routingtable.HTTPRouteProvider: {"domain": {Added: 1, Held: 2}},
+					},
+					TopProcessGUIDs: []routingtable.ProcessGUIDChanges{{ProcessGUID: "process-guid-1", Changes: 3}},
+				}}
+			})
+
+			It("responds with the most recent report", func() {
+				getReport()
+				Expect(recorder.Code).To(Equal(http.StatusOK))
+
+				var report routingtable.SyncReport
+				Expect(json.Unmarshal(recorder.Body.Bytes(), &report)).To(Succeed())
+				Expect(report.RouteTypes[routingtable.HTTPRouteProvider]["domain"]).To(Equal(routingtable.SyncCounts{Added: 1, Held: 2}))
+				Expect(report.TopProcessGUIDs).To(ConsistOf(routingtable.ProcessGUIDChanges{ProcessGUID: "process-guid-1", Changes: 3}))
+			})
+		})
+	})
+
+	Describe("IsLoopbackAddress", func() {
+		It("accepts localhost and loopback IPs only", func() {
+			Expect(introspection.IsLoopbackAddress("127.0.0.1:17011")).To(BeTrue())
+			Expect(introspection.IsLoopbackAddress("[::1]:17011")).To(BeTrue())
+			Expect(introspection.IsLoopbackAddress("localhost:17011")).To(BeTrue())
+			Expect(introspection.IsLoopbackAddress("0.0.0.0:17011")).To(BeFalse())
+			Expect(introspection.IsLoopbackAddress(":17011")).To(BeFalse())
+			Expect(introspection.IsLoopbackAddress("10.0.0.1:17011")).To(BeFalse())
+		})
+	})
+
+	Describe("the unregistration guard", func() {
+		Context("when no guard is configured", func() {
+			It("does not serve the guard endpoints", func() {
+				req := httptest.NewRequest(http.MethodPost, introspection.SyncOverridePath, nil)
+				handler.ServeHTTP(recorder, req)
+				Expect(recorder.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+
+		Context("when a guard is configured", func() {
+			BeforeEach(func() {
+				guard = routehandlers.NewUnregistrationGuard(50, 0)
+			})
+
+			It("reports that no sync is held", func() {
+				req := httptest.NewRequest(http.MethodGet, introspection.HeldSyncPath, nil)
+				handler.ServeHTTP(recorder, req)
+				Expect(recorder.Code).To(Equal(http.StatusOK))
+
+				var heldSync introspection.HeldSyncResponse
+				Expect(json.Unmarshal(recorder.Body.Bytes(), &heldSync)).To(Succeed())
+				Expect(heldSync.Held).To(BeFalse())
+				Expect(heldSync.Shrinkage).To(BeNil())
+			})
+
+			It("accepts overrides from loopback addresses", func() {
+				req := httptest.NewRequest(http.MethodPost, introspection.SyncOverridePath, nil)
+				req.RemoteAddr = "127.0.0.1:51234"
+				handler.ServeHTTP(recorder, req)
+				Expect(recorder.Code).To(Equal(http.StatusNoContent))
+			})
+
+			It("rejects overrides from other addresses", func() {
+				req := httptest.NewRequest(http.MethodPost, introspection.SyncOverridePath, nil)
+				req.RemoteAddr = "10.0.0.1:51234"
+				handler.ServeHTTP(recorder, req)
+				Expect(recorder.Code).To(Equal(http.StatusForbidden))
+			})
+
+			It("only accepts overrides with POST", func() {
+				req := httptest.NewRequest(http.MethodGet, introspection.SyncOverridePath, nil)
+				handler.ServeHTTP(recorder, req)
+				Expect(recorder.Code).To(Equal(http.StatusMethodNotAllowed))
+			})
+		})
+	})
+})
@@ -0,0 +1 @@
+package introspection // import "code.cloudfoundry.org/route-emitter/introspection"
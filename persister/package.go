@@ -0,0 +1 @@
+package persister // import "code.cloudfoundry.org/route-emitter/persister"
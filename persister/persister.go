@@ -0,0 +1,76 @@
+package persister
+
+import (
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	"code.cloudfoundry.org/lager/v3"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+)
+
+// Persister periodically writes a snapshot of the routing table to disk, and
+// once more on shutdown, so that a restarted emitter can start broadcasting
+// before its first sync completes. Tables that were never synced or restored
+// are not written, so that they do not replace a snapshot worth restoring.
+type Persister struct {
+	logger   lager.Logger
+	clock    clock.Clock
+	table    routingtable.RoutingTable
+	path     string
+	interval time.Duration
+}
+
+func NewPersister(
+	logger lager.Logger,
+	clock clock.Clock,
+	table routingtable.RoutingTable,
+	path string,
+	interval time.Duration,
+) *Persister {
+	return &Persister{
+		logger:   logger.Session("persister", lager.Data{"path": path}),
+		clock:    clock,
+		table:    table,
+		path:     path,
+		interval: interval,
+	}
+}
+
+func (p *Persister) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	p.logger.Info("starting")
+	close(ready)
+	defer p.logger.Info("exiting")
+
+	ticker := p.clock.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C():
+			p.persist()
+		case <-signals:
+			p.logger.Info("stopping")
+			p.persist()
+			return nil
+		}
+	}
+}
+
+func (p *Persister) persist() {
+	snapshot := p.table.Snapshot()
+	if snapshot.SyncedAt.IsZero() {
+		p.logger.Debug("skipping-unsynced-table")
+		return
+	}
+	err := routingtable.WriteSnapshot(p.path, snapshot)
+	if err != nil {
+		p.logger.Error("failed-to-write-snapshot", err)
+		return
+	}
+	p.logger.Debug("wrote-snapshot", lager.Data{
+		"http-entries":     len(snapshot.HTTP),
+		"tcp-entries":      len(snapshot.TCP),
+		"internal-entries": len(snapshot.Internal),
+	})
+}
@@ -0,0 +1,120 @@
+package persister_test
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"code.cloudfoundry.org/route-emitter/persister"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/route-emitter/routingtable/fakeroutingtable"
+	"github.com/tedsuo/ifrit"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Persister", func() {
+	var (
+		table         *fakeroutingtable.FakeRoutingTable
+		clock         *fakeclock.FakeClock
+		interval      time.Duration
+		snapshotPath  string
+		process       ifrit.Process
+		snapshotEntry routingtable.SnapshotEntry
+	)
+
+	BeforeEach(func() {
+		tmpDir, err := os.MkdirTemp("", "persister")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(os.RemoveAll, tmpDir)
+		snapshotPath = filepath.Join(tmpDir, "routing-table.json")
+
+		snapshotEntry = routingtable.SnapshotEntry{
+			ProcessGUID:   "process-guid-1",
+			ContainerPort: 8080,
+			Domain:        "domain",
+			HTTPRoutes:    []routingtable.Route{{Hostname: "foo.example.com", LogGUID: "log-guid"}},
+			Endpoints: []routingtable.Endpoint{
+				{InstanceGUID: "instance-guid-1", Host: "1.1.1.1", Port: 61000, ContainerPort: 8080},
+			},
+		}
+
+		clock = fakeclock.NewFakeClock(time.Now())
+
+		table = &fakeroutingtable.FakeRoutingTable{}
+		table.SnapshotReturns(routingtable.Snapshot{
+			Version:  routingtable.SnapshotVersion,
+			SyncedAt: clock.Now(),
+			HTTP:     []routingtable.SnapshotEntry{snapshotEntry},
+		})
+
+		interval = 10 * time.Second
+	})
+
+	JustBeforeEach(func() {
+		logger := lagertest.NewTestLogger("test")
+		process = ifrit.Background(persister.NewPersister(logger, clock, table, snapshotPath, interval))
+		Eventually(process.Ready()).Should(BeClosed())
+	})
+
+	AfterEach(func() {
+		if process != nil {
+			ginkgoSignal(process)
+		}
+	})
+
+	It("writes a snapshot every interval", func() {
+		clock.WaitForWatcherAndIncrement(interval)
+		Eventually(table.SnapshotCallCount).Should(Equal(1))
+
+		Eventually(func() error {
+			_, err := routingtable.ReadSnapshot(snapshotPath)
+			return err
+		}).Should(Succeed())
+
+		snapshot, err := routingtable.ReadSnapshot(snapshotPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(snapshot.HTTP).To(ConsistOf(snapshotEntry))
+
+		clock.WaitForWatcherAndIncrement(interval)
+		Eventually(table.SnapshotCallCount).Should(Equal(2))
+	})
+
+	It("writes a final snapshot when signalled", func() {
+		Consistently(table.SnapshotCallCount).Should(Equal(0))
+
+		ginkgoSignal(process)
+		process = nil
+		Expect(table.SnapshotCallCount()).To(Equal(1))
+
+		_, err := routingtable.ReadSnapshot(snapshotPath)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Context("when the table was never synced", func() {
+		BeforeEach(func() {
+			table.SnapshotReturns(routingtable.Snapshot{
+				Version: routingtable.SnapshotVersion,
+				HTTP:    []routingtable.SnapshotEntry{snapshotEntry},
+			})
+		})
+
+		It("does not write a snapshot", func() {
+			clock.WaitForWatcherAndIncrement(interval)
+			Eventually(table.SnapshotCallCount).Should(Equal(1))
+
+			ginkgoSignal(process)
+			process = nil
+			_, err := routingtable.ReadSnapshot(snapshotPath)
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+	})
+})
+
+func ginkgoSignal(process ifrit.Process) {
+	process.Signal(os.Interrupt)
+	Eventually(process.Wait()).Should(Receive())
+}
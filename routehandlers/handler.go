@@ -2,6 +2,7 @@ package routehandlers
 
 import (
 	"errors"
+	"sync"
 
 	"code.cloudfoundry.org/bbs/models"
 	"code.cloudfoundry.org/bbs/trace"
@@ -20,15 +21,82 @@ const (
 	routesUnregisteredCounter = "RoutesUnregistered"
 	httpRouteCount            = "HTTPRouteCount"
 	tcpRouteCount             = "TCPRouteCount"
+	addressCollisionsMetric   = "AddressCollisionsActive"
+	suppressedEndpointsMetric = "SuppressedEndpoints"
+	syncKeysAddedMetric       = "SyncRoutingKeysAdded"
+	syncKeysRemovedMetric     = "SyncRoutingKeysRemoved"
+	syncKeysChangedMetric     = "SyncRoutingKeysChanged"
+	syncKeysHeldMetric        = "SyncRoutingKeysHeld"
+	tcpMappingsOrphanedMetric = "TCPRouteMappingsOrphaned"
+	tcpMappingsMissingMetric  = "TCPRouteMappingsMissing"
 )
 
 type Handler struct {
 	routingTable        routingtable.RoutingTable
 	natsEmitter         emitter.NATSEmitter
 	routingAPIEmitter   emitter.RoutingAPIEmitter
+	routeSink           emitter.RouteSink
+	routeSnapshotSinks  []emitter.RouteSnapshotSink
+	syncListeners       []SyncListener
 	localMode           bool
 	metronClient        loggingclient.IngressClient
 	unregistrationCache unregistration.Cache
+	unregistrationGuard *UnregistrationGuard
+	tcpRouteReconciler  emitter.TCPRouteReconciler
+
+	syncReportLock sync.Mutex
+	lastSyncReport *routingtable.SyncReport
+}
+
+type Option func(*Handler)
+
+// SyncListener is told when a sync was applied to the routing table.
+type SyncListener interface {
+	SyncCompleted()
+}
+
+// WithUnregistrationGuard holds back syncs that would unregister more route
+// associations than the guard allows.
+func WithUnregistrationGuard(guard *UnregistrationGuard) Option {
+	return func(handler *Handler) {
+		handler.unregistrationGuard = guard
+	}
+}
+
+// WithRouteSink also emits every change to the sink, e.g. an
+// emitter.FanOutSink of further outputs. Changes are emitted to the sink after
+// the NATS and routing api emitters, so that a failing sink never holds them
+// back.
+func WithRouteSink(sink emitter.RouteSink) Option {
+	return func(handler *Handler) {
+		handler.routeSink = sink
+	}
+}
+
+// WithRouteSnapshotSink gives the sink all external routes of the routing
+// table after every sync and every broadcast of the routes, so that it
+// repairs changes it missed.
+func WithRouteSnapshotSink(sink emitter.RouteSnapshotSink) Option {
+	return func(handler *Handler) {
+		handler.routeSnapshotSinks = append(handler.routeSnapshotSinks, sink)
+	}
+}
+
+// WithSyncListener tells the listener after every sync that was applied to
+// the routing table. Syncs held by the unregistration guard are not applied.
+func WithSyncListener(listener SyncListener) Option {
+	return func(handler *Handler) {
+		handler.syncListeners = append(handler.syncListeners, listener)
+	}
+}
+
+// WithTCPRouteReconciler reconciles the TCP route mappings in routing-api
+// with the routing table after every sync, so that mappings whose
+// unregistration was lost are deleted.
+func WithTCPRouteReconciler(reconciler emitter.TCPRouteReconciler) Option {
+	return func(handler *Handler) {
+		handler.tcpRouteReconciler = reconciler
+	}
 }
 
 var _ watcher.RouteHandler = new(Handler)
@@ -40,8 +108,9 @@ func NewHandler(
 	localMode bool,
 	metronClient loggingclient.IngressClient,
 	unregistrationCache unregistration.Cache,
+	options ...Option,
 ) *Handler {
-	return &Handler{
+	handler := &Handler{
 		routingTable:        routingTable,
 		natsEmitter:         natsEmitter,
 		routingAPIEmitter:   routingAPIEmitter,
@@ -49,6 +118,10 @@ func NewHandler(
 		metronClient:        metronClient,
 		unregistrationCache: unregistrationCache,
 	}
+	for _, option := range options {
+		option(handler)
+	}
+	return handler
 }
 
 func (handler *Handler) HandleEvent(logger lager.Logger, event models.Event) {
@@ -118,6 +191,9 @@ func (handler *Handler) EmitExternal(logger lager.Logger) {
 		}
 	}
 
+	handler.emitToRouteSink(logger, messagesToEmit, routingEvents)
+	handler.replaceRouteSnapshots(logger, messagesToEmit, routingEvents)
+
 	err := handler.metronClient.IncrementCounterWithDelta(routesSyncedCounter, messagesToEmit.RouteRegistrationCount())
 	if err != nil {
 		logger.Error("failed-send-routes-synced-count-metric", err)
@@ -126,6 +202,28 @@ func (handler *Handler) EmitExternal(logger lager.Logger) {
 	if err != nil {
 		logger.Error("failed-to-send-total-route-count-metric", err)
 	}
+
+	handler.emitAddressCollisions(logger)
+}
+
+func (handler *Handler) emitAddressCollisions(logger lager.Logger) {
+	collisions := handler.routingTable.AddressCollisions()
+	suppressed := 0
+	for _, collision := range collisions {
+		suppressed += len(collision.SuppressedInstanceGUIDs)
+	}
+	if len(collisions) > 0 {
+		logger.Info("address-collisions", lager.Data{"collisions": collisions})
+	}
+
+	err := handler.metronClient.SendMetric(addressCollisionsMetric, len(collisions))
+	if err != nil {
+		logger.Error("failed-to-send-address-collisions-metric", err)
+	}
+	err = handler.metronClient.SendMetric(suppressedEndpointsMetric, suppressed)
+	if err != nil {
+		logger.Error("failed-to-send-suppressed-endpoints-metric", err)
+	}
 }
 
 func (handler *Handler) EmitInternal(logger lager.Logger) {
@@ -138,6 +236,19 @@ func (handler *Handler) EmitInternal(logger lager.Logger) {
 			logger.Error("failed-to-emit-nats-routes", err)
 		}
 	}
+
+	handler.emitToRouteSink(logger, messagesToEmit, routingtable.TCPRouteMappings{})
+}
+
+func (handler *Handler) emitToRouteSink(logger lager.Logger, messagesToEmit routingtable.MessagesToEmit, routeMappings routingtable.TCPRouteMappings) {
+	if handler.routeSink == nil {
+		return
+	}
+	logger.Debug("emitting-to-route-sink", lager.Data{"messages": messagesToEmit, "route-mappings": routeMappings})
+	err := handler.routeSink.Emit(messagesToEmit, routeMappings)
+	if err != nil {
+		logger.Error("failed-to-emit-to-route-sink", err)
+	}
 }
 
 func (handler *Handler) Sync(
@@ -153,6 +264,9 @@ func (handler *Handler) Sync(
 
 	nullLogger := lager.NewLogger("null-logger") // ignore log messsages from the routing table
 	newTable := routingtable.NewRoutingTable(false, handler.metronClient)
+	for _, provider := range handler.routingTable.RouteProviders() {
+		newTable.RegisterRouteProvider(provider)
+	}
 
 	for _, lrp := range desired {
 		newTable.SetRoutes(nullLogger, nil, lrp)
@@ -164,10 +278,12 @@ func (handler *Handler) Sync(
 
 	natsEmitter := handler.natsEmitter
 	routingAPIEmitter := handler.routingAPIEmitter
+	routeSink := handler.routeSink
 	table := handler.routingTable
 
 	handler.natsEmitter = nil
 	handler.routingAPIEmitter = nil
+	handler.routeSink = nil
 	handler.routingTable = newTable
 
 	for _, event := range cachedEvents {
@@ -177,8 +293,26 @@ func (handler *Handler) Sync(
 	handler.routingTable = table
 	handler.natsEmitter = natsEmitter
 	handler.routingAPIEmitter = routingAPIEmitter
+	handler.routeSink = routeSink
+
+	if handler.unregistrationGuard != nil {
+		shrinkage := handler.previewSwap(newTable, domains)
+		if !handler.unregistrationGuard.allow(logger, shrinkage) {
+			err := handler.metronClient.IncrementCounter(syncsHeldCounter)
+			if err != nil {
+				logger.Error("failed-to-send-syncs-held-metric", err)
+			}
+			// the new table is dropped, so the events received during the
+			// sync are applied to the table that is kept
+			for _, event := range cachedEvents {
+				handler.HandleEvent(logger, event)
+			}
+			return
+		}
+	}
 
-	routeMappings, messages := handler.routingTable.Swap(nullLogger, newTable, domains)
+	routeMappings, messages, report := handler.routingTable.Swap(nullLogger, newTable, domains)
+	handler.emitSyncReport(logger, report)
 	logger.Debug("start-emitting-messages", lager.Data{
 		"num-registration-messages":            len(messages.RegistrationMessages),
 		"num-unregistration-messages":          len(messages.UnregistrationMessages),
@@ -201,6 +335,23 @@ func (handler *Handler) Sync(
 		"num-internal-unregistration-messages": len(messages.InternalUnregistrationMessages),
 	})
 
+	if len(handler.routeSnapshotSinks) > 0 {
+		routingEvents, messagesToEmit := handler.routingTable.GetExternalRoutingEvents()
+		handler.replaceRouteSnapshots(logger, messagesToEmit, routingEvents)
+	}
+
+	for _, listener := range handler.syncListeners {
+		listener.SyncCompleted()
+	}
+
+	if handler.tcpRouteReconciler != nil {
+		if domainsFresh(desired, domains) {
+			handler.reconcileTCPRoutes(logger)
+		} else {
+			logger.Info("skipping-tcp-route-reconciliation", lager.Data{"fresh-domains": domains})
+		}
+	}
+
 	if handler.localMode {
 		err := handler.metronClient.SendMetric(httpRouteCount, handler.routingTable.HTTPAssociationsCount())
 		if err != nil {
@@ -213,6 +364,80 @@ func (handler *Handler) Sync(
 	}
 }
 
+// LastSyncReport returns the report of the most recent sync, if there was one.
+func (handler *Handler) LastSyncReport() (routingtable.SyncReport, bool) {
+	handler.syncReportLock.Lock()
+	defer handler.syncReportLock.Unlock()
+	if handler.lastSyncReport == nil {
+		return routingtable.SyncReport{}, false
+	}
+	return *handler.lastSyncReport, true
+}
+
+func (handler *Handler) emitSyncReport(logger lager.Logger, report routingtable.SyncReport) {
+	handler.syncReportLock.Lock()
+	handler.lastSyncReport = &report
+	handler.syncReportLock.Unlock()
+
+	logger.Info("sync-report", lager.Data{"report": report})
+
+	totals := report.Totals()
+	metrics := []struct {
+		name  string
+		value int
+	}{
+		{syncKeysAddedMetric, totals.Added},
+		{syncKeysRemovedMetric, totals.Removed},
+		{syncKeysChangedMetric, totals.Changed},
+		{syncKeysHeldMetric, totals.Held},
+	}
+	for _, metric := range metrics {
+		err := handler.metronClient.SendMetric(metric.name, metric.value)
+		if err != nil {
+			logger.Error("failed-to-send-sync-report-metric", err, lager.Data{"metric": metric.name})
+		}
+	}
+}
+
+// domainsFresh reports whether the domains of all desired LRPs are fresh. The
+// routes of LRPs in other domains may be missing from the BBS, so mappings
+// reconciliation would take as orphaned could still be in use.
+func domainsFresh(desired []*models.DesiredLRP, domains models.DomainSet) bool {
+	if len(domains) == 0 {
+		return false
+	}
+	for _, lrp := range desired {
+		if !domains.Contains(lrp.Domain) {
+			return false
+		}
+	}
+	return true
+}
+
+func (handler *Handler) reconcileTCPRoutes(logger lager.Logger) {
+	routeMappings, _ := handler.routingTable.GetExternalRoutingEvents()
+	drift, err := handler.tcpRouteReconciler.Reconcile(routeMappings.Registrations)
+	if err != nil {
+		logger.Error("failed-to-reconcile-tcp-routes", err)
+		if drift == (emitter.TCPRouteDrift{}) {
+			// listing the mappings failed, so the drift is unknown
+			return
+		}
+	}
+	if drift.Orphaned > 0 || drift.Missing > 0 {
+		logger.Info("tcp-route-drift", lager.Data{"orphaned": drift.Orphaned, "missing": drift.Missing})
+	}
+
+	err = handler.metronClient.SendMetric(tcpMappingsOrphanedMetric, drift.Orphaned)
+	if err != nil {
+		logger.Error("failed-to-send-tcp-route-mappings-orphaned-metric", err)
+	}
+	err = handler.metronClient.SendMetric(tcpMappingsMissingMetric, drift.Missing)
+	if err != nil {
+		logger.Error("failed-to-send-tcp-route-mappings-missing-metric", err)
+	}
+}
+
 func (handler *Handler) RefreshDesired(logger lager.Logger, desiredLRPs []*models.DesiredLRP) {
 	for _, desiredLRP := range desiredLRPs {
 		routeMappings, messagesToEmit := handler.routingTable.SetRoutes(logger, nil, desiredLRP)
@@ -300,6 +525,9 @@ func (handler *Handler) handleActualDelete(logger lager.Logger, actualLRP *model
 	handler.emitMessages(logger, messagesToEmit, routeMappings)
 }
 
+// emitMessages emits a change to NATS and routing-api, and then to the route
+// sink. NATS and routing-api are not sinks of the route sink: they get every
+// change first, whatever the sinks do, and report through their own metrics.
 func (handler *Handler) emitMessages(logger lager.Logger, messagesToEmit routingtable.MessagesToEmit, routeMappings routingtable.TCPRouteMappings) {
 	if handler.natsEmitter != nil {
 		logger.Debug("emit-messages", lager.Data{"messages": messagesToEmit})
@@ -307,14 +535,7 @@ func (handler *Handler) emitMessages(logger lager.Logger, messagesToEmit routing
 		if err != nil {
 			logger.Error("failed-to-emit-http-routes", err)
 		}
-		err = handler.metronClient.IncrementCounterWithDelta(routesRegisteredCounter, messagesToEmit.RouteRegistrationCount())
-		if err != nil {
-			logger.Error("failed-to-emit-registration-message-count", err)
-		}
-		err = handler.metronClient.IncrementCounterWithDelta(routesUnregisteredCounter, messagesToEmit.RouteUnregistrationCount())
-		if err != nil {
-			logger.Error("failed-to-emit-unregistration-message-count", err)
-		}
+		handler.emitRouteCounts(logger, messagesToEmit)
 	} else {
 		logger.Info("no-emitter-configured-skipping-emit-messages", lager.Data{"messages": messagesToEmit})
 	}
@@ -325,4 +546,26 @@ func (handler *Handler) emitMessages(logger lager.Logger, messagesToEmit routing
 			logger.Error("failed-to-emit-http-routes", err)
 		}
 	}
+
+	handler.emitToRouteSink(logger, messagesToEmit, routeMappings)
+}
+
+func (handler *Handler) replaceRouteSnapshots(logger lager.Logger, messagesToEmit routingtable.MessagesToEmit, routeMappings routingtable.TCPRouteMappings) {
+	for _, sink := range handler.routeSnapshotSinks {
+		err := sink.Replace(messagesToEmit, routeMappings)
+		if err != nil {
+			logger.Error("failed-to-replace-route-snapshot", err)
+		}
+	}
+}
+
+func (handler *Handler) emitRouteCounts(logger lager.Logger, messagesToEmit routingtable.MessagesToEmit) {
+	err := handler.metronClient.IncrementCounterWithDelta(routesRegisteredCounter, messagesToEmit.RouteRegistrationCount())
+	if err != nil {
+		logger.Error("failed-to-emit-registration-message-count", err)
+	}
+	err = handler.metronClient.IncrementCounterWithDelta(routesUnregisteredCounter, messagesToEmit.RouteUnregistrationCount())
+	if err != nil {
+		logger.Error("failed-to-emit-unregistration-message-count", err)
+	}
 }
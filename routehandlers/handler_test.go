@@ -2,6 +2,7 @@ package routehandlers_test
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	mfakes "code.cloudfoundry.org/diego-logging-client/testhelpers"
@@ -189,6 +190,29 @@ var _ = Describe("Handler", func() {
 				Expect(messagesToEmit).To(Equal(dummyMessagesToEmit))
 			})
 
+			Context("when a route sink is configured", func() {
+				var routeSink *fakes.FakeRouteSink
+
+				BeforeEach(func() {
+					routeSink = &fakes.FakeRouteSink{}
+					routeHandler = routehandlers.NewHandler(fakeTable, natsEmitter, fakeRoutingAPIEmitter, false, fakeMetronClient, fakeUnregistrationCache, routehandlers.WithRouteSink(routeSink))
+				})
+
+				It("emits whatever the table tells it to emit to the emitter and the sink", func() {
+					Expect(routeSink.EmitCallCount()).To(Equal(1))
+					messagesToEmit, _ := routeSink.EmitArgsForCall(0)
+					Expect(messagesToEmit).To(Equal(dummyMessagesToEmit))
+					Expect(natsEmitter.EmitCallCount()).To(Equal(1))
+				})
+
+				It("sends a 'routes registered' metric", func() {
+					Eventually(counterChan).Should(Receive(Equal(counter{
+						name:  "RoutesRegistered",
+						delta: 2,
+					})))
+				})
+			})
+
 			Context("when there are diego ssh-keys on the route", func() {
 				BeforeEach(func() {
 					diegoSSHInfo := json.RawMessage([]byte(`{"ssh-key": "ssh-value"}`))
@@ -1172,7 +1196,7 @@ var _ = Describe("Handler", func() {
 					return byRoutingKey
 				}
 
-				fakeTable.SwapStub = func(l lager.Logger, t routingtable.RoutingTable, d models.DomainSet) (routingtable.TCPRouteMappings, routingtable.MessagesToEmit) {
+				fakeTable.SwapStub = func(l lager.Logger, t routingtable.RoutingTable, d models.DomainSet) (routingtable.TCPRouteMappings, routingtable.MessagesToEmit, routingtable.SyncReport) {
 
 					routes := routesByRoutingKey(desiredLRPs)
 					routesList := make([]routingtable.Route, 3)
@@ -1186,7 +1210,7 @@ var _ = Describe("Handler", func() {
 							routingtable.RegistryMessageFor(endpoint2, routesList[1], true),
 							routingtable.RegistryMessageFor(endpoint3, routesList[2], true),
 						},
-					}
+					}, routingtable.SyncReport{}
 				}
 			})
 
@@ -1200,6 +1224,88 @@ var _ = Describe("Handler", func() {
 				Expect(natsEmitter.EmitCallCount()).Should(Equal(1))
 			})
 
+			Context("when a route snapshot sink is configured", func() {
+				var snapshotSink *fakes.FakeRouteSnapshotSink
+
+				BeforeEach(func() {
+					snapshotSink = &fakes.FakeRouteSnapshotSink{}
+					routeHandler = routehandlers.NewHandler(fakeTable, natsEmitter, fakeRoutingAPIEmitter, false, fakeMetronClient, fakeUnregistrationCache, routehandlers.WithRouteSnapshotSink(snapshotSink))
+					fakeTable.GetExternalRoutingEventsReturns(emptyTCPRouteMappings, routingtable.MessagesToEmit{
+						RegistrationMessages: []routingtable.RegistryMessage{routingtable.RegistryMessageFor(endpoint1, routingtable.Route{}, true)},
+					})
+				})
+
+				It("replaces its routes with those of the table after the sync", func() {
+					routeHandler.Sync(logger, desiredLRPs, actualLRPs, domains, nil)
+					Expect(snapshotSink.ReplaceCallCount()).To(Equal(1))
+					messages, _ := snapshotSink.ReplaceArgsForCall(0)
+					Expect(messages.RegistrationMessages).To(HaveLen(1))
+				})
+			})
+
+			Context("when a sync listener is configured", func() {
+				var listener *syncListener
+
+				BeforeEach(func() {
+					listener = &syncListener{}
+					routeHandler = routehandlers.NewHandler(fakeTable, natsEmitter, fakeRoutingAPIEmitter, false, fakeMetronClient, fakeUnregistrationCache, routehandlers.WithSyncListener(listener))
+				})
+
+				It("tells it that the sync completed", func() {
+					routeHandler.Sync(logger, desiredLRPs, actualLRPs, domains, nil)
+					Expect(listener.completed).To(Equal(1))
+				})
+			})
+
+			Context("when the routing table has custom route providers", func() {
+				BeforeEach(func() {
+					fakeTable.RouteProvidersReturns([]routingtable.RouteProvider{{Name: "custom"}})
+				})
+
+				It("registers them with the new routing table", func() {
+					routeHandler.Sync(logger, desiredLRPs, actualLRPs, domains, nil)
+					Expect(fakeTable.SwapCallCount()).Should(Equal(1))
+					_, tempRoutingTable, _ := fakeTable.SwapArgsForCall(0)
+
+					names := []string{}
+					for _, provider := range tempRoutingTable.RouteProviders() {
+						names = append(names, provider.Name)
+					}
+					Expect(names).To(ContainElement("custom"))
+					Expect(tempRoutingTable.HTTPAssociationsCount()).To(Equal(3))
+				})
+			})
+
+			Context("when the swap reports changes", func() {
+				var report routingtable.SyncReport
+
+				BeforeEach(func() {
+					report = routingtable.SyncReport{
+						RouteTypes: map[string]map[string]routingtable.SyncCounts{
+							routingtable.HTTPRouteProvider: {"tests": {Added: 2, Held: 1}},
+						},
+					}
+					fakeTable.SwapReturns(emptyTCPRouteMappings, routingtable.MessagesToEmit{}, report)
+				})
+
+				It("makes the report available", func() {
+					_, ok := routeHandler.LastSyncReport()
+					Expect(ok).To(BeFalse())
+
+					routeHandler.Sync(logger, desiredLRPs, actualLRPs, domains, nil)
+					lastReport, ok := routeHandler.LastSyncReport()
+					Expect(ok).To(BeTrue())
+					Expect(lastReport).To(Equal(report))
+					Expect(logger).To(gbytes.Say("sync-report"))
+				})
+
+				It("sends the report totals as metrics", func() {
+					routeHandler.Sync(logger, desiredLRPs, actualLRPs, domains, nil)
+					Eventually(metricChan).Should(Receive(Equal(metric{name: "SyncRoutingKeysAdded", value: 2})))
+					Eventually(metricChan).Should(Receive(Equal(metric{name: "SyncRoutingKeysHeld", value: 1})))
+				})
+			})
+
 			Context("swapping the new route table", func() {
 				var (
 					registrationMessages, unregistrationMessages []routingtable.RegistryMessage
@@ -1210,11 +1316,11 @@ var _ = Describe("Handler", func() {
 				)
 
 				JustBeforeEach(func() {
-					fakeTable.SwapStub = func(l lager.Logger, t routingtable.RoutingTable, d models.DomainSet) (routingtable.TCPRouteMappings, routingtable.MessagesToEmit) {
+					fakeTable.SwapStub = func(l lager.Logger, t routingtable.RoutingTable, d models.DomainSet) (routingtable.TCPRouteMappings, routingtable.MessagesToEmit, routingtable.SyncReport) {
 						return emptyTCPRouteMappings, routingtable.MessagesToEmit{
 							RegistrationMessages:   registrationMessages,
 							UnregistrationMessages: unregistrationMessages,
-						}
+						}, routingtable.SyncReport{}
 					}
 				})
 
@@ -1380,6 +1486,55 @@ var _ = Describe("Handler", func() {
 			Expect(natsEmitter.EmitArgsForCall(0)).To(Equal(registrationMsgs))
 		})
 
+		Context("when a route snapshot sink is configured", func() {
+			var snapshotSink *fakes.FakeRouteSnapshotSink
+
+			BeforeEach(func() {
+				snapshotSink = &fakes.FakeRouteSnapshotSink{}
+				routeHandler = routehandlers.NewHandler(fakeTable, natsEmitter, fakeRoutingAPIEmitter, false, fakeMetronClient, fakeUnregistrationCache, routehandlers.WithRouteSnapshotSink(snapshotSink))
+			})
+
+			It("replaces its routes with the registration events", func() {
+				routeHandler.EmitExternal(logger)
+				Expect(snapshotSink.ReplaceCallCount()).To(Equal(1))
+				messages, mappings := snapshotSink.ReplaceArgsForCall(0)
+				Expect(messages).To(Equal(registrationMsgs))
+				Expect(mappings).To(Equal(emptyTCPRouteMappings))
+			})
+		})
+
+		Context("when a route sink is configured", func() {
+			var routeSink *fakes.FakeRouteSink
+
+			BeforeEach(func() {
+				routeSink = &fakes.FakeRouteSink{}
+				routeHandler = routehandlers.NewHandler(fakeTable, natsEmitter, fakeRoutingAPIEmitter, false, fakeMetronClient, fakeUnregistrationCache, routehandlers.WithRouteSink(routeSink))
+			})
+
+			It("emits the registration events to the emitters and the sink", func() {
+				routeHandler.EmitExternal(logger)
+				Expect(routeSink.EmitCallCount()).To(Equal(1))
+				messages, mappings := routeSink.EmitArgsForCall(0)
+				Expect(messages).To(Equal(registrationMsgs))
+				Expect(mappings).To(Equal(emptyTCPRouteMappings))
+				Expect(natsEmitter.EmitCallCount()).To(Equal(1))
+				Expect(fakeRoutingAPIEmitter.EmitCallCount()).To(Equal(1))
+			})
+
+			Context("when the sink fails", func() {
+				BeforeEach(func() {
+					routeSink.EmitReturns(errors.New("boom"))
+				})
+
+				It("logs the error and still emits to the emitters", func() {
+					routeHandler.EmitExternal(logger)
+					Expect(logger).To(gbytes.Say("failed-to-emit-to-route-sink"))
+					Expect(natsEmitter.EmitCallCount()).To(Equal(1))
+					Expect(fakeRoutingAPIEmitter.EmitCallCount()).To(Equal(1))
+				})
+			})
+		})
+
 		It("sends a 'routes total' metric", func() {
 			routeHandler.EmitExternal(logger)
 			Eventually(metricChan).Should(Receive(Equal(metric{
@@ -1395,6 +1550,35 @@ var _ = Describe("Handler", func() {
 				delta: 3,
 			})))
 		})
+
+		Context("when there are address collisions", func() {
+			BeforeEach(func() {
+				fakeTable.AddressCollisionsReturns([]routingtable.AddressCollision{
+					{
+						Address:                 routingtable.Address{Host: "1.1.1.1", Port: 61000},
+						InstanceGUIDs:           []string{"ig-1", "ig-2"},
+						SuppressedInstanceGUIDs: []string{"ig-1"},
+					},
+				})
+			})
+
+			It("sends metrics about the current collisions", func() {
+				routeHandler.EmitExternal(logger)
+				Eventually(metricChan).Should(Receive(Equal(metric{
+					name:  "AddressCollisionsActive",
+					value: 1,
+				})))
+				Eventually(metricChan).Should(Receive(Equal(metric{
+					name:  "SuppressedEndpoints",
+					value: 1,
+				})))
+			})
+
+			It("logs the collisions", func() {
+				routeHandler.EmitExternal(logger)
+				Expect(logger).To(gbytes.Say("address-collisions"))
+			})
+		})
 	})
 
 	Describe("EmitInternal", func() {
@@ -1535,3 +1719,11 @@ var _ = Describe("Handler", func() {
 		})
 	})
 })
+
+type syncListener struct {
+	completed int
+}
+
+func (l *syncListener) SyncCompleted() {
+	l.completed++
+}
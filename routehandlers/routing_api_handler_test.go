@@ -1,11 +1,14 @@
 package routehandlers_test
 
 import (
+	"errors"
+
 	"code.cloudfoundry.org/bbs/models"
 	mfakes "code.cloudfoundry.org/diego-logging-client/testhelpers"
 	loggregator "code.cloudfoundry.org/go-loggregator/v8"
 	"code.cloudfoundry.org/lager/v3"
 	"code.cloudfoundry.org/lager/v3/lagertest"
+	"code.cloudfoundry.org/route-emitter/emitter"
 	emitterfakes "code.cloudfoundry.org/route-emitter/emitter/fakes"
 	"code.cloudfoundry.org/route-emitter/routehandlers"
 	"code.cloudfoundry.org/route-emitter/routingtable"
@@ -16,6 +19,7 @@ import (
 	"code.cloudfoundry.org/routing-info/tcp_routes"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
 )
 
 var _ = Describe("RoutingAPIHandler", func() {
@@ -548,8 +552,8 @@ var _ = Describe("RoutingAPIHandler", func() {
 					},
 				}
 
-				fakeRoutingTable.SwapStub = func(l lager.Logger, t routingtable.RoutingTable, domains models.DomainSet) (routingtable.TCPRouteMappings, routingtable.MessagesToEmit) {
-					return routingtable.TCPRouteMappings{}, emptyNatsMessages
+				fakeRoutingTable.SwapStub = func(l lager.Logger, t routingtable.RoutingTable, domains models.DomainSet) (routingtable.TCPRouteMappings, routingtable.MessagesToEmit, routingtable.SyncReport) {
+					return routingtable.TCPRouteMappings{}, emptyNatsMessages, routingtable.SyncReport{}
 				}
 			})
 
@@ -608,6 +612,72 @@ var _ = Describe("RoutingAPIHandler", func() {
 				Expect(fakeRoutingAPIEmitter.EmitCallCount()).Should(Equal(1))
 			})
 
+			Context("when a TCP route reconciler is configured", func() {
+				var (
+					fakeReconciler *emitterfakes.FakeTCPRouteReconciler
+					owned          routingtable.TCPRouteMappings
+					domains        models.DomainSet
+				)
+
+				BeforeEach(func() {
+					desiredLRPs[0].Domain = "domain"
+					domains = models.NewDomainSet([]string{"domain"})
+					fakeReconciler = new(emitterfakes.FakeTCPRouteReconciler)
+					fakeReconciler.ReconcileReturns(emitter.TCPRouteDrift{Orphaned: 2, Missing: 1}, nil)
+					owned = routingtable.TCPRouteMappings{
+						Registrations: []tcpmodels.TcpRouteMapping{tcpmodels.NewTcpRouteMapping("router-group-guid", 61000, "some-ip", 61006, 0)},
+					}
+					fakeRoutingTable.GetExternalRoutingEventsReturns(owned, emptyNatsMessages)
+					routeHandler = routehandlers.NewHandler(fakeRoutingTable, nil, fakeRoutingAPIEmitter, false, fakeMetronClient, fakeUnregistrationCache,
+						routehandlers.WithTCPRouteReconciler(fakeReconciler))
+				})
+
+				It("reconciles routing-api with the TCP routes of the table after the swap", func() {
+					routeHandler.Sync(logger, desiredLRPs, actualLRPs, domains, nil)
+					Expect(fakeRoutingTable.SwapCallCount()).To(Equal(1))
+					Expect(fakeReconciler.ReconcileCallCount()).To(Equal(1))
+					Expect(fakeReconciler.ReconcileArgsForCall(0)).To(Equal(owned.Registrations))
+				})
+
+				It("reports the drift", func() {
+					routeHandler.Sync(logger, desiredLRPs, actualLRPs, domains, nil)
+					metrics := map[string]int{}
+					for i := 0; i < fakeMetronClient.SendMetricCallCount(); i++ {
+						name, value, _ := fakeMetronClient.SendMetricArgsForCall(i)
+						metrics[name] = value
+					}
+					Expect(metrics).To(HaveKeyWithValue("TCPRouteMappingsOrphaned", 2))
+					Expect(metrics).To(HaveKeyWithValue("TCPRouteMappingsMissing", 1))
+				})
+
+				Context("when the domain of a desired LRP is not fresh", func() {
+					BeforeEach(func() {
+						domains = models.NewDomainSet([]string{"other-domain"})
+					})
+
+					It("does not reconcile", func() {
+						routeHandler.Sync(logger, desiredLRPs, actualLRPs, domains, nil)
+						Expect(fakeRoutingTable.SwapCallCount()).To(Equal(1))
+						Expect(fakeReconciler.ReconcileCallCount()).To(Equal(0))
+						Expect(logger).To(gbytes.Say("skipping-tcp-route-reconciliation"))
+					})
+				})
+
+				Context("when listing the mappings fails", func() {
+					BeforeEach(func() {
+						fakeReconciler.ReconcileReturns(emitter.TCPRouteDrift{}, errors.New("boom"))
+					})
+
+					It("does not report the drift", func() {
+						routeHandler.Sync(logger, desiredLRPs, actualLRPs, domains, nil)
+						for i := 0; i < fakeMetronClient.SendMetricCallCount(); i++ {
+							name, _, _ := fakeMetronClient.SendMetricArgsForCall(i)
+							Expect(name).NotTo(HavePrefix("TCPRouteMappings"))
+						}
+					})
+				})
+			})
+
 			Context("when events are cached", func() {
 				BeforeEach(func() {
 					tcpRoutes := tcp_routes.TCPRoutes{
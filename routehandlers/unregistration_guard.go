@@ -0,0 +1,132 @@
+package routehandlers
+
+import (
+	"fmt"
+	"sync"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/lager/v3"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+)
+
+const syncsHeldCounter = "SyncsHeld"
+
+// SyncShrinkage describes how many route associations a sync would
+// unregister from a routing table.
+type SyncShrinkage struct {
+	Unregistrations int `json:"unregistrations"`
+	Associations    int `json:"associations"`
+	TableSize       int `json:"table_size"`
+}
+
+// confirmTolerancePercent is how much two shrinkages may differ, relative to
+// the size of the table, and still confirm each other.
+const confirmTolerancePercent = 5
+
+// confirms reports whether other shrinks the table about as much as s, so that
+// churn between two syncs does not keep a mass unregistration held forever.
+func (s SyncShrinkage) confirms(other SyncShrinkage) bool {
+	scale := s.Associations
+	if other.Associations > scale {
+		scale = other.Associations
+	}
+	within := func(a, b int) bool {
+		diff := a - b
+		if diff < 0 {
+			diff = -diff
+		}
+		return diff*100 <= confirmTolerancePercent*scale
+	}
+	return within(s.Unregistrations, other.Unregistrations) && within(s.TableSize, other.TableSize)
+}
+
+// UnregistrationGuard holds back syncs that would unregister more than a
+// percentage or an absolute number of route associations at once, e.g.
+// because the BBS returned a truncated result. A held sync is applied once
+// the next sync results in about the same table, or after Override is called.
+type UnregistrationGuard struct {
+	maxPercent int
+	maxCount   int
+
+	lock       sync.Mutex
+	held       *SyncShrinkage
+	overridden bool
+}
+
+// NewUnregistrationGuard returns a guard for the given thresholds. A
+// threshold of zero is disabled.
+func NewUnregistrationGuard(maxPercent, maxCount int) *UnregistrationGuard {
+	return &UnregistrationGuard{
+		maxPercent: maxPercent,
+		maxCount:   maxCount,
+	}
+}
+
+// Override lets the next sync through regardless of the thresholds.
+func (g *UnregistrationGuard) Override() {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.overridden = true
+}
+
+// Held returns the shrinkage of the sync currently being held, if any.
+func (g *UnregistrationGuard) Held() (SyncShrinkage, bool) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	if g.held == nil {
+		return SyncShrinkage{}, false
+	}
+	return *g.held, true
+}
+
+func (g *UnregistrationGuard) exceeded(shrinkage SyncShrinkage) bool {
+	if g.maxCount > 0 && shrinkage.Unregistrations > g.maxCount {
+		return true
+	}
+	return g.maxPercent > 0 && shrinkage.Associations > 0 &&
+		shrinkage.Unregistrations*100 > g.maxPercent*shrinkage.Associations
+}
+
+// allow decides whether a sync with the given shrinkage may be applied.
+func (g *UnregistrationGuard) allow(logger lager.Logger, shrinkage SyncShrinkage) bool {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	held := g.held
+	overridden := g.overridden
+	g.held = nil
+	g.overridden = false
+
+	if !g.exceeded(shrinkage) {
+		return true
+	}
+
+	data := lager.Data{"shrinkage": shrinkage}
+	switch {
+	case overridden:
+		logger.Info("applying-mass-unregistration-after-override", data)
+		return true
+	case held != nil && held.confirms(shrinkage):
+		logger.Info("applying-confirmed-mass-unregistration", data)
+		return true
+	}
+
+	g.held = &shrinkage
+	err := fmt.Errorf("sync would unregister %d of %d route associations", shrinkage.Unregistrations, shrinkage.Associations)
+	logger.Error("holding-mass-unregistration", err, data)
+	return false
+}
+
+// previewSwap computes the shrinkage of swapping newTable into the routing
+// table without changing the routing table.
+func (handler *Handler) previewSwap(newTable routingtable.RoutingTable, domains models.DomainSet) SyncShrinkage {
+	routeMappings, messages := handler.routingTable.PreviewSwap(newTable, domains)
+	return SyncShrinkage{
+		Unregistrations: int(messages.RouteUnregistrationCount()+messages.InternalRouteUnregistrationCount()) +
+			len(routeMappings.Unregistrations),
+		Associations: handler.routingTable.HTTPAssociationsCount() +
+			handler.routingTable.TCPAssociationsCount() +
+			handler.routingTable.InternalAssociationsCount(),
+		TableSize: newTable.TableSize(),
+	}
+}
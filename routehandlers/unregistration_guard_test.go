@@ -0,0 +1,226 @@
+package routehandlers_test
+
+import (
+	"fmt"
+
+	"code.cloudfoundry.org/bbs/models"
+	mfakes "code.cloudfoundry.org/diego-logging-client/testhelpers"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"code.cloudfoundry.org/route-emitter/emitter/fakes"
+	"code.cloudfoundry.org/route-emitter/routehandlers"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	ufakes "code.cloudfoundry.org/route-emitter/unregistration/fakes"
+	"code.cloudfoundry.org/routing-info/cfroutes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("UnregistrationGuard", func() {
+	const lrpCount = 4
+
+	var (
+		logger           *lagertest.TestLogger
+		table            routingtable.RoutingTable
+		natsEmitter      *fakes.FakeNATSEmitter
+		fakeMetronClient *mfakes.FakeIngressClient
+		guard            *routehandlers.UnregistrationGuard
+		routeHandler     *routehandlers.Handler
+		desiredLRPs      []*models.DesiredLRP
+		actualLRPs       []*models.ActualLRP
+		domains          models.DomainSet
+	)
+
+	lrp := func(i int) (*models.DesiredLRP, *models.ActualLRP) {
+		processGUID := fmt.Sprintf("pg-%d", i)
+		routes := cfroutes.CFRoutes{
+			cfroutes.CFRoute{Hostnames: []string{fmt.Sprintf("app-%d.example.com", i)}, Port: 8080},
+		}.RoutingInfo()
+		desired := &models.DesiredLRP{
+			ProcessGuid: processGUID,
+			Domain:      "domain",
+			LogGuid:     "lg",
+			Routes:      &routes,
+			Instances:   1,
+		}
+		actual := &models.ActualLRP{
+			ActualLRPKey:         models.NewActualLRPKey(processGUID, 0, "domain"),
+			ActualLRPInstanceKey: models.NewActualLRPInstanceKey(fmt.Sprintf("ig-%d", i), "cell-id"),
+			ActualLRPNetInfo:     models.NewActualLRPNetInfo("1.1.1.1", "container-ip", models.ActualLRPNetInfo_PreferredAddressHost, models.NewPortMapping(uint32(61000+i), 8080)),
+			State:                models.ActualLRPStateRunning,
+		}
+		return desired, actual
+	}
+
+	lrps := func(count int) ([]*models.DesiredLRP, []*models.ActualLRP) {
+		desired := []*models.DesiredLRP{}
+		actual := []*models.ActualLRP{}
+		for i := 0; i < count; i++ {
+			d, a := lrp(i)
+			desired = append(desired, d)
+			actual = append(actual, a)
+		}
+		return desired, actual
+	}
+
+	sync := func() {
+		routeHandler.Sync(logger, desiredLRPs, actualLRPs, domains, nil)
+	}
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+		natsEmitter = &fakes.FakeNATSEmitter{}
+		fakeMetronClient = &mfakes.FakeIngressClient{}
+		guard = routehandlers.NewUnregistrationGuard(50, 0)
+		domains = models.NewDomainSet([]string{"domain"})
+
+		table = routingtable.NewRoutingTable(false, fakeMetronClient)
+		routeHandler = routehandlers.NewHandler(table, natsEmitter, nil, false, fakeMetronClient, &ufakes.FakeCache{}, routehandlers.WithUnregistrationGuard(guard))
+
+		desiredLRPs, actualLRPs = lrps(lrpCount)
+		sync()
+		Expect(table.HTTPAssociationsCount()).To(Equal(lrpCount))
+		natsEmitter = &fakes.FakeNATSEmitter{}
+		routeHandler = routehandlers.NewHandler(table, natsEmitter, nil, false, fakeMetronClient, &ufakes.FakeCache{}, routehandlers.WithUnregistrationGuard(guard))
+	})
+
+	Context("when a sync unregisters fewer associations than the threshold", func() {
+		BeforeEach(func() {
+			desiredLRPs, actualLRPs = lrps(lrpCount - 1)
+		})
+
+		It("applies the sync", func() {
+			sync()
+			Expect(natsEmitter.EmitCallCount()).To(Equal(1))
+			Expect(natsEmitter.EmitArgsForCall(0).UnregistrationMessages).To(HaveLen(1))
+			Expect(table.HTTPAssociationsCount()).To(Equal(lrpCount - 1))
+
+			_, held := guard.Held()
+			Expect(held).To(BeFalse())
+		})
+	})
+
+	Context("when a sync unregisters more associations than the threshold", func() {
+		BeforeEach(func() {
+			desiredLRPs, actualLRPs = nil, nil
+		})
+
+		It("holds the sync", func() {
+			sync()
+			Expect(natsEmitter.EmitCallCount()).To(Equal(0))
+			Expect(table.HTTPAssociationsCount()).To(Equal(lrpCount))
+			Expect(logger).To(gbytes.Say("holding-mass-unregistration"))
+
+			shrinkage, held := guard.Held()
+			Expect(held).To(BeTrue())
+			Expect(shrinkage).To(Equal(routehandlers.SyncShrinkage{
+				Unregistrations: lrpCount,
+				Associations:    lrpCount,
+				TableSize:       0,
+			}))
+
+			Expect(fakeMetronClient.IncrementCounterCallCount()).To(Equal(1))
+			Expect(fakeMetronClient.IncrementCounterArgsForCall(0)).To(Equal("SyncsHeld"))
+		})
+
+		It("applies the events received during the sync to the old table", func() {
+			desired, actual := lrp(lrpCount)
+			routeHandler.Sync(logger, desiredLRPs, actualLRPs, domains, map[string]models.Event{
+				"desired": models.NewDesiredLRPCreatedEvent(desired, "trace-id"),
+				"actual":  models.NewActualLRPInstanceCreatedEvent(actual, "trace-id"),
+			})
+			Expect(table.HTTPAssociationsCount()).To(Equal(lrpCount + 1))
+			Expect(natsEmitter.EmitCallCount()).To(BeNumerically(">=", 1))
+			lastEmit := natsEmitter.EmitArgsForCall(natsEmitter.EmitCallCount() - 1)
+			Expect(lastEmit.RegistrationMessages).To(HaveLen(1))
+			Expect(lastEmit.UnregistrationMessages).To(BeEmpty())
+		})
+
+		It("does not tell sync listeners that the sync completed", func() {
+			listener := &syncListener{}
+			routeHandler = routehandlers.NewHandler(table, natsEmitter, nil, false, fakeMetronClient, &ufakes.FakeCache{}, routehandlers.WithUnregistrationGuard(guard), routehandlers.WithSyncListener(listener))
+			sync()
+			Expect(listener.completed).To(Equal(0))
+		})
+
+		It("keeps emitting the old table", func() {
+			sync()
+			routeHandler.EmitExternal(logger)
+			Expect(natsEmitter.EmitCallCount()).To(Equal(1))
+			Expect(natsEmitter.EmitArgsForCall(0).RegistrationMessages).To(HaveLen(lrpCount))
+		})
+
+		Context("when the next sync confirms the shrinkage", func() {
+			It("applies the sync", func() {
+				sync()
+				sync()
+				Expect(natsEmitter.EmitCallCount()).To(Equal(1))
+				Expect(natsEmitter.EmitArgsForCall(0).UnregistrationMessages).To(HaveLen(lrpCount))
+				Expect(table.HTTPAssociationsCount()).To(Equal(0))
+				Expect(logger).To(gbytes.Say("applying-confirmed-mass-unregistration"))
+
+				_, held := guard.Held()
+				Expect(held).To(BeFalse())
+			})
+		})
+
+		Context("when the next sync shrinks the table differently", func() {
+			It("keeps holding", func() {
+				sync()
+				desiredLRPs, actualLRPs = lrps(1)
+				sync()
+				Expect(natsEmitter.EmitCallCount()).To(Equal(0))
+				Expect(table.HTTPAssociationsCount()).To(Equal(lrpCount))
+
+				shrinkage, held := guard.Held()
+				Expect(held).To(BeTrue())
+				Expect(shrinkage.Unregistrations).To(Equal(lrpCount - 1))
+			})
+		})
+
+		Context("when the next sync shrinks a large table by about as much", func() {
+			BeforeEach(func() {
+				desiredLRPs, actualLRPs = lrps(100)
+				sync()
+				Expect(table.HTTPAssociationsCount()).To(Equal(100))
+				desiredLRPs, actualLRPs = lrps(2)
+			})
+
+			It("applies the sync", func() {
+				sync()
+				emitsBefore := natsEmitter.EmitCallCount()
+				desiredLRPs, actualLRPs = lrps(3)
+				sync()
+				Expect(natsEmitter.EmitCallCount()).To(Equal(emitsBefore + 1))
+				Expect(table.HTTPAssociationsCount()).To(Equal(3))
+				Expect(logger).To(gbytes.Say("applying-confirmed-mass-unregistration"))
+			})
+		})
+
+		Context("when an operator overrides the guard", func() {
+			It("applies the next sync", func() {
+				guard.Override()
+				sync()
+				Expect(natsEmitter.EmitCallCount()).To(Equal(1))
+				Expect(table.HTTPAssociationsCount()).To(Equal(0))
+				Expect(logger).To(gbytes.Say("applying-mass-unregistration-after-override"))
+			})
+		})
+	})
+
+	Context("when the absolute threshold is exceeded", func() {
+		BeforeEach(func() {
+			guard = routehandlers.NewUnregistrationGuard(0, 1)
+			routeHandler = routehandlers.NewHandler(table, natsEmitter, nil, false, fakeMetronClient, &ufakes.FakeCache{}, routehandlers.WithUnregistrationGuard(guard))
+			desiredLRPs, actualLRPs = lrps(lrpCount - 2)
+		})
+
+		It("holds the sync", func() {
+			sync()
+			Expect(natsEmitter.EmitCallCount()).To(Equal(0))
+			_, held := guard.Held()
+			Expect(held).To(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,251 @@
+package routingtable
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// AddressCollisionPolicy decides which endpoints are registered when
+// instances with different instance guids share an address.
+type AddressCollisionPolicy string
+
+const (
+	// ReportAddressCollisions registers every colliding endpoint and only logs
+	// and counts the collision.
+	ReportAddressCollisions AddressCollisionPolicy = "report-only"
+	// PreferNewestOnAddressCollision only registers the endpoint of the
+	// instance that started most recently.
+	PreferNewestOnAddressCollision AddressCollisionPolicy = "prefer-newest"
+	// QuarantineAddressCollisions registers none of the colliding endpoints
+	// until the collision is resolved.
+	QuarantineAddressCollisions AddressCollisionPolicy = "quarantine"
+)
+
+func (policy AddressCollisionPolicy) Validate() error {
+	switch policy {
+	case ReportAddressCollisions, PreferNewestOnAddressCollision, QuarantineAddressCollisions:
+		return nil
+	}
+	return fmt.Errorf("unknown address collision policy %q", policy)
+}
+
+// AddressCollision describes instances currently sharing an address.
+type AddressCollision struct {
+	Address                 Address  `json:"address"`
+	InstanceGUIDs           []string `json:"instance_guids"`
+	SuppressedInstanceGUIDs []string `json:"suppressed_instance_guids,omitempty"`
+}
+
+type addressEntry struct {
+	processGUID string
+	endpoint    Endpoint
+}
+
+// endpointRef identifies an endpoint across the shards of a table.
+type endpointRef struct {
+	processGUID string
+	key         EndpointKey
+	address     Address
+}
+
+// collisionState is the set of endpoints at some addresses, and which of them
+// are suppressed, at one point in time.
+type collisionState struct {
+	members    map[endpointRef]addressEntry
+	suppressed map[endpointRef]addressEntry
+}
+
+// transitions returns the endpoints that were known in both states and whose
+// registration has to be withdrawn or restored to go from before to after.
+func (before collisionState) transitions(after collisionState) (suppressed, released map[endpointRef]addressEntry) {
+	suppressed = map[endpointRef]addressEntry{}
+	released = map[endpointRef]addressEntry{}
+	for ref, entry := range after.suppressed {
+		_, wasSuppressed := before.suppressed[ref]
+		_, wasMember := before.members[ref]
+		if !wasSuppressed && wasMember {
+			suppressed[ref] = entry
+		}
+	}
+	for ref, entry := range before.suppressed {
+		_, isSuppressed := after.suppressed[ref]
+		_, isMember := after.members[ref]
+		if !isSuppressed && isMember {
+			released[ref] = entry
+		}
+	}
+	return suppressed, released
+}
+
+// addressCollisions tracks the endpoints registered at every address so that
+// colliding endpoints can be suppressed according to the policy.
+type addressCollisions struct {
+	policy    AddressCollisionPolicy
+	addresses map[Address]map[EndpointKey]addressEntry
+	sync.Locker
+}
+
+func newAddressCollisions(policy AddressCollisionPolicy) *addressCollisions {
+	if policy == "" {
+		policy = ReportAddressCollisions
+	}
+	return &addressCollisions{
+		policy:    policy,
+		addresses: make(map[Address]map[EndpointKey]addressEntry),
+		Locker:    &sync.Mutex{},
+	}
+}
+
+func (c *addressCollisions) reset() {
+	c.addresses = make(map[Address]map[EndpointKey]addressEntry)
+}
+
+func (c *addressCollisions) add(address Address, processGUID string, endpoint Endpoint) {
+	entries, ok := c.addresses[address]
+	if !ok {
+		entries = make(map[EndpointKey]addressEntry)
+		c.addresses[address] = entries
+	}
+	entries[endpoint.key()] = addressEntry{processGUID: processGUID, endpoint: endpoint}
+}
+
+func (c *addressCollisions) remove(address Address, endpoint Endpoint) {
+	entries, ok := c.addresses[address]
+	if !ok {
+		return
+	}
+	delete(entries, endpoint.key())
+	if len(entries) == 0 {
+		delete(c.addresses, address)
+	}
+}
+
+// collidingInstance returns the guid of another instance at the address, if
+// there is one.
+func (c *addressCollisions) collidingInstance(address Address, instanceGUID string) (string, bool) {
+	guids := instanceGUIDs(c.addresses[address])
+	for _, guid := range guids {
+		if guid != instanceGUID {
+			return guid, true
+		}
+	}
+	return "", false
+}
+
+func (c *addressCollisions) suppressed(address Address, instanceGUID string) bool {
+	_, ok := c.suppressedInstances(address)[instanceGUID]
+	return ok
+}
+
+func (c *addressCollisions) suppressedInstances(address Address) map[string]struct{} {
+	entries := c.addresses[address]
+	guids := instanceGUIDs(entries)
+	if len(guids) < 2 {
+		return nil
+	}
+
+	suppressed := map[string]struct{}{}
+	switch c.policy {
+	case QuarantineAddressCollisions:
+		for _, guid := range guids {
+			suppressed[guid] = struct{}{}
+		}
+	case PreferNewestOnAddressCollision:
+		newest := newestInstance(entries)
+		for _, guid := range guids {
+			if guid != newest {
+				suppressed[guid] = struct{}{}
+			}
+		}
+	}
+	return suppressed
+}
+
+func (c *addressCollisions) state(addresses map[Address]struct{}) collisionState {
+	state := collisionState{
+		members:    map[endpointRef]addressEntry{},
+		suppressed: map[endpointRef]addressEntry{},
+	}
+	for address := range addresses {
+		suppressed := c.suppressedInstances(address)
+		for key, entry := range c.addresses[address] {
+			ref := endpointRef{processGUID: entry.processGUID, key: key, address: address}
+			state.members[ref] = entry
+			if _, ok := suppressed[key.InstanceGUID]; ok {
+				state.suppressed[ref] = entry
+			}
+		}
+	}
+	return state
+}
+
+func (c *addressCollisions) allAddresses() map[Address]struct{} {
+	addresses := make(map[Address]struct{}, len(c.addresses))
+	for address := range c.addresses {
+		addresses[address] = struct{}{}
+	}
+	return addresses
+}
+
+func (c *addressCollisions) collisions() []AddressCollision {
+	collisions := []AddressCollision{}
+	for address, entries := range c.addresses {
+		guids := instanceGUIDs(entries)
+		if len(guids) < 2 {
+			continue
+		}
+
+		collision := AddressCollision{Address: address, InstanceGUIDs: guids}
+		for guid := range c.suppressedInstances(address) {
+			collision.SuppressedInstanceGUIDs = append(collision.SuppressedInstanceGUIDs, guid)
+		}
+		sort.Strings(collision.SuppressedInstanceGUIDs)
+		collisions = append(collisions, collision)
+	}
+	return collisions
+}
+
+func instanceGUIDs(entries map[EndpointKey]addressEntry) []string {
+	seen := map[string]struct{}{}
+	guids := []string{}
+	for key := range entries {
+		if _, ok := seen[key.InstanceGUID]; ok {
+			continue
+		}
+		seen[key.InstanceGUID] = struct{}{}
+		guids = append(guids, key.InstanceGUID)
+	}
+	sort.Strings(guids)
+	return guids
+}
+
+// newestInstance picks the instance with the latest Since. Ties, and
+// endpoints without a Since, are decided by instance guid, so that every
+// emitter picks the same winner. Modification tags are not compared, as their
+// indexes count the changes of each instance separately.
+func newestInstance(entries map[EndpointKey]addressEntry) string {
+	endpoints := make([]Endpoint, 0, len(entries))
+	for _, entry := range entries {
+		endpoints = append(endpoints, entry.endpoint)
+	}
+	// the order is fixed, as newer is not transitive with missing Sinces
+	sort.Slice(endpoints, func(i, j int) bool {
+		return endpoints[i].InstanceGUID < endpoints[j].InstanceGUID
+	})
+
+	var newest Endpoint
+	for _, endpoint := range endpoints {
+		if newest.InstanceGUID == "" || newer(endpoint, newest) {
+			newest = endpoint
+		}
+	}
+	return newest.InstanceGUID
+}
+
+func newer(a, b Endpoint) bool {
+	if a.Since != 0 && b.Since != 0 && a.Since != b.Since {
+		return a.Since > b.Since
+	}
+	return a.InstanceGUID > b.InstanceGUID
+}
@@ -0,0 +1,208 @@
+package routingtable_test
+
+import (
+	"code.cloudfoundry.org/bbs/models"
+	mfakes "code.cloudfoundry.org/diego-logging-client/testhelpers"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("Address collision policies", func() {
+	var (
+		table            routingtable.RoutingTable
+		logger           *lagertest.TestLogger
+		fakeMetronClient *mfakes.FakeIngressClient
+		policy           routingtable.AddressCollisionPolicy
+		olderLRP         *models.ActualLRP
+		newerLRP         *models.ActualLRP
+		messagesToEmit   routingtable.MessagesToEmit
+	)
+
+	tag := models.ModificationTag{Epoch: "abc", Index: 1}
+	keyA := routingtable.RoutingKey{ProcessGUID: "process-guid-a", ContainerPort: 8080}
+	keyB := routingtable.RoutingKey{ProcessGUID: "process-guid-b", ContainerPort: 8080}
+
+	urisOf := func(messages []routingtable.RegistryMessage) []string {
+		uris := []string{}
+		for _, message := range messages {
+			uris = append(uris, message.URIs...)
+		}
+		return uris
+	}
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test-route-emitter")
+		fakeMetronClient = &mfakes.FakeIngressClient{}
+
+		olderLRP = createActualLRP(keyA, routingtable.Endpoint{
+			InstanceGUID:    "ig-a",
+			Host:            "1.1.1.1",
+			Port:            61000,
+			ContainerPort:   8080,
+			Since:           1,
+			ModificationTag: &tag,
+		}, "domain")
+		newerLRP = createActualLRP(keyB, routingtable.Endpoint{
+			InstanceGUID:    "ig-b",
+			Host:            "1.1.1.1",
+			Port:            61000,
+			ContainerPort:   8080,
+			Since:           2,
+			ModificationTag: &tag,
+		}, "domain")
+	})
+
+	JustBeforeEach(func() {
+		table = routingtable.NewRoutingTable(false, fakeMetronClient, routingtable.WithAddressCollisionPolicy(policy))
+		table.SetRoutes(logger, nil, createDesiredLRP("process-guid-a", 1, 8080, "log-guid-a", "", tag, models.DesiredLRPRunInfo{}, "a.example.com"))
+		table.SetRoutes(logger, nil, createDesiredLRP("process-guid-b", 1, 8080, "log-guid-b", "", tag, models.DesiredLRPRunInfo{}, "b.example.com"))
+		table.AddEndpoint(logger, olderLRP)
+		_, messagesToEmit = table.AddEndpoint(logger, newerLRP)
+	})
+
+	Context("when the policy only reports collisions", func() {
+		BeforeEach(func() {
+			policy = routingtable.ReportAddressCollisions
+		})
+
+		It("registers both endpoints", func() {
+			Expect(urisOf(messagesToEmit.RegistrationMessages)).To(ConsistOf("b.example.com"))
+			Expect(messagesToEmit.UnregistrationMessages).To(BeEmpty())
+			_, messages := table.GetExternalRoutingEvents()
+			Expect(urisOf(messages.RegistrationMessages)).To(ConsistOf("a.example.com", "b.example.com"))
+		})
+
+		It("reports the collision", func() {
+			Expect(table.AddressCollisions()).To(ConsistOf(routingtable.AddressCollision{
+				Address:       routingtable.Address{Host: "1.1.1.1", Port: 61000},
+				InstanceGUIDs: []string{"ig-a", "ig-b"},
+			}))
+		})
+	})
+
+	Context("when the policy prefers the newest instance", func() {
+		BeforeEach(func() {
+			policy = routingtable.PreferNewestOnAddressCollision
+		})
+
+		It("unregisters the older endpoint and registers the newer one", func() {
+			Expect(urisOf(messagesToEmit.RegistrationMessages)).To(ConsistOf("b.example.com"))
+			Expect(urisOf(messagesToEmit.UnregistrationMessages)).To(ConsistOf("a.example.com"))
+			Expect(logger).To(Say("suppressing-colliding-endpoint"))
+		})
+
+		It("does not register the older endpoint on broadcasts", func() {
+			_, messages := table.GetExternalRoutingEvents()
+			Expect(urisOf(messages.RegistrationMessages)).To(ConsistOf("b.example.com"))
+		})
+
+		It("reports the suppressed instance", func() {
+			collisions := table.AddressCollisions()
+			Expect(collisions).To(HaveLen(1))
+			Expect(collisions[0].SuppressedInstanceGUIDs).To(Equal([]string{"ig-a"}))
+		})
+
+		Context("when the instances have the same Since", func() {
+			BeforeEach(func() {
+				olderLRP.Since = 2
+				olderLRP.ModificationTag = models.ModificationTag{Epoch: "def", Index: 5}
+			})
+
+			It("falls back to the instance guid rather than comparing modification tags", func() {
+				collisions := table.AddressCollisions()
+				Expect(collisions).To(HaveLen(1))
+				Expect(collisions[0].SuppressedInstanceGUIDs).To(Equal([]string{"ig-a"}))
+			})
+		})
+
+		Context("when the instances have no Since", func() {
+			BeforeEach(func() {
+				olderLRP.Since = 0
+				newerLRP.Since = 0
+			})
+
+			It("falls back to the instance guid", func() {
+				collisions := table.AddressCollisions()
+				Expect(collisions).To(HaveLen(1))
+				Expect(collisions[0].SuppressedInstanceGUIDs).To(Equal([]string{"ig-a"}))
+			})
+		})
+
+		Context("when the instances have TCP routes", func() {
+			var mappings routingtable.TCPRouteMappings
+
+			JustBeforeEach(func() {
+				table = routingtable.NewRoutingTable(false, fakeMetronClient, routingtable.WithAddressCollisionPolicy(policy))
+				routesA := createRoutingInfo(8080, []string{"a.example.com"}, nil, "", []uint32{5222}, "router-group-guid")
+				routesB := createRoutingInfo(8080, []string{"b.example.com"}, nil, "", []uint32{5223}, "router-group-guid")
+				table.SetRoutes(logger, nil, createDesiredLRPWithRoutes("process-guid-a", 1, routesA, "log-guid-a", tag, models.DesiredLRPRunInfo{}))
+				table.SetRoutes(logger, nil, createDesiredLRPWithRoutes("process-guid-b", 1, routesB, "log-guid-b", tag, models.DesiredLRPRunInfo{}))
+				table.AddEndpoint(logger, olderLRP)
+				mappings, _ = table.AddEndpoint(logger, newerLRP)
+			})
+
+			It("unregisters the TCP route mappings of the older endpoint too", func() {
+				Expect(mappings.Registrations).To(HaveLen(1))
+				Expect(mappings.Registrations[0].ExternalPort).To(BeEquivalentTo(5223))
+				Expect(mappings.Unregistrations).To(HaveLen(1))
+				Expect(mappings.Unregistrations[0].ExternalPort).To(BeEquivalentTo(5222))
+
+				tcpMappings, _ := table.GetExternalRoutingEvents()
+				Expect(tcpMappings.Registrations).To(HaveLen(1))
+				Expect(tcpMappings.Registrations[0].ExternalPort).To(BeEquivalentTo(5223))
+			})
+
+			It("reports the collision once", func() {
+				Expect(table.AddressCollisions()).To(HaveLen(1))
+				Expect(fakeMetronClient.IncrementCounterCallCount()).To(Equal(1))
+			})
+		})
+
+		Context("when the newer endpoint goes away", func() {
+			It("registers the older endpoint again", func() {
+				_, messages := table.RemoveEndpoint(logger, newerLRP)
+				Expect(urisOf(messages.UnregistrationMessages)).To(ConsistOf("b.example.com"))
+				Expect(urisOf(messages.RegistrationMessages)).To(ConsistOf("a.example.com"))
+				Expect(table.AddressCollisions()).To(BeEmpty())
+			})
+		})
+	})
+
+	Context("when the policy quarantines colliding instances", func() {
+		BeforeEach(func() {
+			policy = routingtable.QuarantineAddressCollisions
+		})
+
+		It("registers neither endpoint", func() {
+			Expect(messagesToEmit.RegistrationMessages).To(BeEmpty())
+			Expect(urisOf(messagesToEmit.UnregistrationMessages)).To(ConsistOf("a.example.com"))
+
+			_, messages := table.GetExternalRoutingEvents()
+			Expect(messages.RegistrationMessages).To(BeEmpty())
+		})
+
+		Context("when the table is swapped with one without the collision", func() {
+			It("registers the remaining endpoint", func() {
+				tempTable := routingtable.NewRoutingTable(false, fakeMetronClient)
+				tempTable.SetRoutes(logger, nil, createDesiredLRP("process-guid-a", 1, 8080, "log-guid-a", "", tag, models.DesiredLRPRunInfo{}, "a.example.com"))
+				tempTable.SetRoutes(logger, nil, createDesiredLRP("process-guid-b", 1, 8080, "log-guid-b", "", tag, models.DesiredLRPRunInfo{}, "b.example.com"))
+				tempTable.AddEndpoint(logger, olderLRP)
+
+				_, messages, _ := table.Swap(logger, tempTable, models.NewDomainSet([]string{"domain"}))
+				Expect(urisOf(messages.RegistrationMessages)).To(ConsistOf("a.example.com"))
+				Expect(table.AddressCollisions()).To(BeEmpty())
+			})
+		})
+	})
+
+	Describe("Validate", func() {
+		It("rejects unknown policies", func() {
+			Expect(routingtable.AddressCollisionPolicy("first-wins").Validate()).To(HaveOccurred())
+			Expect(routingtable.PreferNewestOnAddressCollision.Validate()).To(Succeed())
+		})
+	})
+})
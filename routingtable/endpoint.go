@@ -2,6 +2,7 @@ package routingtable
 
 import (
 	"fmt"
+	"strings"
 
 	"code.cloudfoundry.org/bbs/models"
 	"code.cloudfoundry.org/lager/v3"
@@ -31,20 +32,21 @@ type Address struct {
 }
 
 type Endpoint struct {
-	InstanceGUID          string
-	Index                 int32
-	Host                  string
-	ContainerIP           string
-	Port                  uint32
-	ContainerPort         uint32
-	TlsProxyPort          uint32
-	ContainerTlsProxyPort uint32
-	Presence              models.ActualLRP_Presence
-	IsolationSegment      string
-	Since                 int64
-	ModificationTag       *models.ModificationTag
-	PreferredAddress      models.ActualLRPNetInfo_PreferredAddress
-	AvailabilityZone      string
+	InstanceGUID          string                                   `json:"instance_guid"`
+	Index                 int32                                    `json:"index"`
+	Host                  string                                   `json:"host"`
+	ContainerIP           string                                   `json:"container_ip"`
+	Port                  uint32                                   `json:"port"`
+	ContainerPort         uint32                                   `json:"container_port"`
+	TlsProxyPort          uint32                                   `json:"tls_proxy_port,omitempty"`
+	ContainerTlsProxyPort uint32                                   `json:"container_tls_proxy_port,omitempty"`
+	Presence              models.ActualLRP_Presence                `json:"presence"`
+	IsolationSegment      string                                   `json:"isolation_segment,omitempty"`
+	Since                 int64                                    `json:"since"`
+	ModificationTag       *models.ModificationTag                  `json:"modification_tag,omitempty"`
+	PreferredAddress      models.ActualLRPNetInfo_PreferredAddress `json:"preferred_address"`
+	AvailabilityZone      string                                   `json:"availability_zone,omitempty"`
+	CellID                string                                   `json:"cell_id,omitempty"`
 }
 
 func (e Endpoint) key() EndpointKey {
@@ -86,9 +88,15 @@ func NewEndpoint(
 	}
 }
 
+// ExternalEndpointInfo is a TCP route. Routes with an SniHostname share the
+// external port with other routes and are selected by the TLS SNI of the
+// client. TLSProxy routes target the TLS proxy port of instances that have
+// one, and ask the TCP router to verify the instance guid of the backend.
 type ExternalEndpointInfo struct {
-	RouterGroupGUID string
-	Port            uint32
+	RouterGroupGUID string `json:"router_group_guid"`
+	Port            uint32 `json:"port"`
+	SniHostname     string `json:"sni_hostname,omitempty"`
+	TLSProxy        bool   `json:"tls_proxy,omitempty"`
 }
 
 func (info ExternalEndpointInfo) Hash() interface{} {
@@ -96,21 +104,33 @@ func (info ExternalEndpointInfo) Hash() interface{} {
 }
 
 func (info ExternalEndpointInfo) MessageFor(e Endpoint, directInstanceRoute, _ bool) (*RegistryMessage, *tcpmodels.TcpRouteMapping, *RegistryMessage) {
-	mapping := tcpmodels.NewTcpRouteMapping(
+	host, port, tlsPort := e.Host, e.Port, e.TlsProxyPort
+	if e.IsDirectInstanceRoute(directInstanceRoute) {
+		host, port, tlsPort = e.ContainerIP, e.ContainerPort, e.ContainerTlsProxyPort
+	}
+
+	useTLS := info.TLSProxy && tlsPort != 0
+	if useTLS {
+		port = tlsPort
+	}
+
+	var sniHostname *string
+	if info.SniHostname != "" {
+		sni := info.SniHostname
+		sniHostname = &sni
+	}
+
+	mapping := tcpmodels.NewSniTcpRouteMapping(
 		info.RouterGroupGUID,
 		uint16(info.Port),
-		e.Host,
-		uint16(e.Port),
+		sniHostname,
+		host,
+		uint16(port),
 		0,
 	)
-	if e.IsDirectInstanceRoute(directInstanceRoute) {
-		mapping = tcpmodels.NewTcpRouteMapping(
-			info.RouterGroupGUID,
-			uint16(info.Port),
-			e.ContainerIP,
-			uint16(e.ContainerPort),
-			0,
-		)
+	if useTLS {
+		mapping.HostTLSPort = int(tlsPort)
+		mapping.InstanceId = e.InstanceGUID
 	}
 	return nil, &mapping, nil
 }
@@ -125,12 +145,14 @@ func NewExternalEndpointInfo(routerGroupGUID string, port uint32) ExternalEndpoi
 }
 
 type Route struct {
-	Hostname         string
-	RouteServiceUrl  string
-	IsolationSegment string
-	LogGUID          string
-	Protocol         string
-	MetricTags       map[string]*models.MetricTagValue
+	Hostname         string                            `json:"hostname"`
+	RouteServiceUrl  string                            `json:"route_service_url,omitempty"`
+	IsolationSegment string                            `json:"isolation_segment,omitempty"`
+	LogGUID          string                            `json:"log_guid"`
+	Protocol         string                            `json:"protocol,omitempty"`
+	MetricTags       map[string]*models.MetricTagValue `json:"metric_tags,omitempty"`
+	MetricTagSources map[string]MetricTagSource        `json:"metric_tag_sources,omitempty"`
+	Options          *RouteOptions                     `json:"options,omitempty"`
 }
 
 type routeHash struct {
@@ -139,6 +161,7 @@ type routeHash struct {
 	IsolationSegment string
 	LogGUID          string
 	Protocol         string
+	Options          RouteOptions
 }
 
 // route hash is used to find route differences
@@ -150,9 +173,48 @@ func (r Route) Hash() interface{} {
 		IsolationSegment: r.IsolationSegment,
 		LogGUID:          r.LogGUID,
 		Protocol:         r.Protocol,
+		Options:          r.options(),
 	}
 }
 
+// updates reports whether the metric tags of the route differ from those of
+// the same route before, which the route hash deliberately ignores.
+func (r Route) updates(before RouteMapping) bool {
+	other, ok := before.(Route)
+	if !ok {
+		return false
+	}
+	if len(r.MetricTagSources) != len(other.MetricTagSources) || len(r.MetricTags) != len(other.MetricTags) {
+		return true
+	}
+	for name, source := range r.MetricTagSources {
+		if otherSource, ok := other.MetricTagSources[name]; !ok || otherSource != source {
+			return true
+		}
+	}
+	for name, value := range r.MetricTags {
+		otherValue, ok := other.MetricTags[name]
+		if !ok || !metricTagValueEqual(value, otherValue) {
+			return true
+		}
+	}
+	return false
+}
+
+func metricTagValueEqual(a, b *models.MetricTagValue) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Static == b.Static && a.Dynamic == b.Dynamic
+}
+
+func (r Route) options() RouteOptions {
+	if r.Options == nil {
+		return RouteOptions{}
+	}
+	return *r.Options
+}
+
 func (r Route) MessageFor(endpoint Endpoint, directInstanceAddress, emitEndpointUpdatedAt bool) (*RegistryMessage, *tcpmodels.TcpRouteMapping, *RegistryMessage) {
 	generator := RegistryMessageFor
 	if endpoint.IsDirectInstanceRoute(directInstanceAddress) {
@@ -162,14 +224,35 @@ func (r Route) MessageFor(endpoint Endpoint, directInstanceAddress, emitEndpoint
 	return &msg, nil, nil
 }
 
+// InternalRoute is a service discovery hostname. Routes with a Port resolve
+// to that container port of every instance, and to its TLS proxy port if it
+// has one. InstanceHostnameTemplates are the templates the per-instance
+// aliases of the hostname were rendered with; the route keeps them so that
+// aliases are unregistered the way they were registered.
 type InternalRoute struct {
-	Hostname    string
-	ContainerIP string
-	LogGUID     string
+	Hostname                  string   `json:"hostname"`
+	ContainerIP               string   `json:"container_ip,omitempty"`
+	Port                      uint32   `json:"port,omitempty"`
+	LogGUID                   string   `json:"log_guid"`
+	InstanceHostnameTemplates []string `json:"instance_hostname_templates,omitempty"`
+}
+
+type internalRouteHash struct {
+	Hostname                  string
+	ContainerIP               string
+	Port                      uint32
+	LogGUID                   string
+	InstanceHostnameTemplates string
 }
 
 func (r InternalRoute) Hash() interface{} {
-	return r
+	return internalRouteHash{
+		Hostname:                  r.Hostname,
+		ContainerIP:               r.ContainerIP,
+		Port:                      r.Port,
+		LogGUID:                   r.LogGUID,
+		InstanceHostnameTemplates: strings.Join(r.InstanceHostnameTemplates, "\n"),
+	}
 }
 
 func (r InternalRoute) MessageFor(endpoint Endpoint, _, emitEndpointUpdatedAt bool) (*RegistryMessage, *tcpmodels.TcpRouteMapping, *RegistryMessage) {
@@ -183,7 +266,7 @@ func (entry RoutableEndpoints) copy() RoutableEndpoints {
 	clone := RoutableEndpoints{
 		Domain:           entry.Domain,
 		Endpoints:        map[EndpointKey]Endpoint{},
-		Routes:           make([]routeMapping, len(entry.Routes)),
+		Routes:           make([]RouteMapping, len(entry.Routes)),
 		DesiredInstances: entry.DesiredInstances,
 		ModificationTag:  entry.ModificationTag,
 	}
@@ -199,7 +282,7 @@ func (entry RoutableEndpoints) copy() RoutableEndpoints {
 
 type RoutableEndpoints struct {
 	Domain           string
-	Routes           []routeMapping
+	Routes           []RouteMapping
 	Endpoints        map[EndpointKey]Endpoint
 	DesiredInstances int32
 	ModificationTag  *models.ModificationTag
@@ -230,6 +313,7 @@ func NewEndpointsFromActual(actualLRP *models.ActualLRP) []Endpoint {
 				Since:                 actualLRP.Since,
 				PreferredAddress:      actualLRP.PreferredAddress,
 				AvailabilityZone:      actualLRP.AvailabilityZone,
+				CellID:                actualLRP.CellId,
 			}
 			endpoints = append(endpoints, endpoint)
 		}
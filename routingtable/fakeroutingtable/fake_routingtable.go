@@ -24,6 +24,16 @@ type FakeRoutingTable struct {
 		result1 routingtable.TCPRouteMappings
 		result2 routingtable.MessagesToEmit
 	}
+	AddressCollisionsStub        func() []routingtable.AddressCollision
+	addressCollisionsMutex       sync.RWMutex
+	addressCollisionsArgsForCall []struct {
+	}
+	addressCollisionsReturns struct {
+		result1 []routingtable.AddressCollision
+	}
+	addressCollisionsReturnsOnCall map[int]struct {
+		result1 []routingtable.AddressCollision
+	}
 	GetExternalRoutingEventsStub        func() (routingtable.TCPRouteMappings, routingtable.MessagesToEmit)
 	getExternalRoutingEventsMutex       sync.RWMutex
 	getExternalRoutingEventsArgsForCall []struct {
@@ -79,6 +89,25 @@ type FakeRoutingTable struct {
 	internalAssociationsCountReturnsOnCall map[int]struct {
 		result1 int
 	}
+	PreviewSwapStub        func(routingtable.RoutingTable, models.DomainSet) (routingtable.TCPRouteMappings, routingtable.MessagesToEmit)
+	previewSwapMutex       sync.RWMutex
+	previewSwapArgsForCall []struct {
+		arg1 routingtable.RoutingTable
+		arg2 models.DomainSet
+	}
+	previewSwapReturns struct {
+		result1 routingtable.TCPRouteMappings
+		result2 routingtable.MessagesToEmit
+	}
+	previewSwapReturnsOnCall map[int]struct {
+		result1 routingtable.TCPRouteMappings
+		result2 routingtable.MessagesToEmit
+	}
+	RegisterRouteProviderStub        func(routingtable.RouteProvider)
+	registerRouteProviderMutex       sync.RWMutex
+	registerRouteProviderArgsForCall []struct {
+		arg1 routingtable.RouteProvider
+	}
 	RemoveEndpointStub        func(lager.Logger, *models.ActualLRP) (routingtable.TCPRouteMappings, routingtable.MessagesToEmit)
 	removeEndpointMutex       sync.RWMutex
 	removeEndpointArgsForCall []struct {
@@ -107,6 +136,56 @@ type FakeRoutingTable struct {
 		result1 routingtable.TCPRouteMappings
 		result2 routingtable.MessagesToEmit
 	}
+	RestoreStub        func(lager.Logger, routingtable.Snapshot)
+	restoreMutex       sync.RWMutex
+	restoreArgsForCall []struct {
+		arg1 lager.Logger
+		arg2 routingtable.Snapshot
+	}
+	RouteProvidersStub        func() []routingtable.RouteProvider
+	routeProvidersMutex       sync.RWMutex
+	routeProvidersArgsForCall []struct {
+	}
+	routeProvidersReturns struct {
+		result1 []routingtable.RouteProvider
+	}
+	routeProvidersReturnsOnCall map[int]struct {
+		result1 []routingtable.RouteProvider
+	}
+	RoutingKeysForHostnameStub        func(string) routingtable.RoutingKeys
+	routingKeysForHostnameMutex       sync.RWMutex
+	routingKeysForHostnameArgsForCall []struct {
+		arg1 string
+	}
+	routingKeysForHostnameReturns struct {
+		result1 routingtable.RoutingKeys
+	}
+	routingKeysForHostnameReturnsOnCall map[int]struct {
+		result1 routingtable.RoutingKeys
+	}
+	RoutingKeysForInstanceStub        func(string) routingtable.RoutingKeys
+	routingKeysForInstanceMutex       sync.RWMutex
+	routingKeysForInstanceArgsForCall []struct {
+		arg1 string
+	}
+	routingKeysForInstanceReturns struct {
+		result1 routingtable.RoutingKeys
+	}
+	routingKeysForInstanceReturnsOnCall map[int]struct {
+		result1 routingtable.RoutingKeys
+	}
+	RoutingKeysForRouterGroupPortStub        func(string, uint32) routingtable.RoutingKeys
+	routingKeysForRouterGroupPortMutex       sync.RWMutex
+	routingKeysForRouterGroupPortArgsForCall []struct {
+		arg1 string
+		arg2 uint32
+	}
+	routingKeysForRouterGroupPortReturns struct {
+		result1 routingtable.RoutingKeys
+	}
+	routingKeysForRouterGroupPortReturnsOnCall map[int]struct {
+		result1 routingtable.RoutingKeys
+	}
 	SetRoutesStub        func(lager.Logger, *models.DesiredLRP, *models.DesiredLRP) (routingtable.TCPRouteMappings, routingtable.MessagesToEmit)
 	setRoutesMutex       sync.RWMutex
 	setRoutesArgsForCall []struct {
@@ -122,7 +201,28 @@ type FakeRoutingTable struct {
 		result1 routingtable.TCPRouteMappings
 		result2 routingtable.MessagesToEmit
 	}
-	SwapStub        func(lager.Logger, routingtable.RoutingTable, models.DomainSet) (routingtable.TCPRouteMappings, routingtable.MessagesToEmit)
+	SnapshotStub        func() routingtable.Snapshot
+	snapshotMutex       sync.RWMutex
+	snapshotArgsForCall []struct {
+	}
+	snapshotReturns struct {
+		result1 routingtable.Snapshot
+	}
+	snapshotReturnsOnCall map[int]struct {
+		result1 routingtable.Snapshot
+	}
+	SnapshotForRoutingKeysStub        func(routingtable.RoutingKeys) routingtable.Snapshot
+	snapshotForRoutingKeysMutex       sync.RWMutex
+	snapshotForRoutingKeysArgsForCall []struct {
+		arg1 routingtable.RoutingKeys
+	}
+	snapshotForRoutingKeysReturns struct {
+		result1 routingtable.Snapshot
+	}
+	snapshotForRoutingKeysReturnsOnCall map[int]struct {
+		result1 routingtable.Snapshot
+	}
+	SwapStub        func(lager.Logger, routingtable.RoutingTable, models.DomainSet) (routingtable.TCPRouteMappings, routingtable.MessagesToEmit, routingtable.SyncReport)
 	swapMutex       sync.RWMutex
 	swapArgsForCall []struct {
 		arg1 lager.Logger
@@ -132,10 +232,12 @@ type FakeRoutingTable struct {
 	swapReturns struct {
 		result1 routingtable.TCPRouteMappings
 		result2 routingtable.MessagesToEmit
+		result3 routingtable.SyncReport
 	}
 	swapReturnsOnCall map[int]struct {
 		result1 routingtable.TCPRouteMappings
 		result2 routingtable.MessagesToEmit
+		result3 routingtable.SyncReport
 	}
 	TCPAssociationsCountStub        func() int
 	tCPAssociationsCountMutex       sync.RWMutex
@@ -225,6 +327,58 @@ func (fake *FakeRoutingTable) AddEndpointReturnsOnCall(i int, result1 routingtab
 	}{result1, result2}
 }
 
+func (fake *FakeRoutingTable) AddressCollisions() []routingtable.AddressCollision {
+	fake.addressCollisionsMutex.Lock()
+	ret, specificReturn := fake.addressCollisionsReturnsOnCall[len(fake.addressCollisionsArgsForCall)]
+	fake.addressCollisionsArgsForCall = append(fake.addressCollisionsArgsForCall, struct {
+	}{})
+	fake.recordInvocation("AddressCollisions", []interface{}{})
+	fake.addressCollisionsMutex.Unlock()
+	if fake.AddressCollisionsStub != nil {
+		return fake.AddressCollisionsStub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.addressCollisionsReturns
+	return fakeReturns.result1
+}
+
+func (fake *FakeRoutingTable) AddressCollisionsCallCount() int {
+	fake.addressCollisionsMutex.RLock()
+	defer fake.addressCollisionsMutex.RUnlock()
+	return len(fake.addressCollisionsArgsForCall)
+}
+
+func (fake *FakeRoutingTable) AddressCollisionsCalls(stub func() []routingtable.AddressCollision) {
+	fake.addressCollisionsMutex.Lock()
+	defer fake.addressCollisionsMutex.Unlock()
+	fake.AddressCollisionsStub = stub
+}
+
+func (fake *FakeRoutingTable) AddressCollisionsReturns(result1 []routingtable.AddressCollision) {
+	fake.addressCollisionsMutex.Lock()
+	defer fake.addressCollisionsMutex.Unlock()
+	fake.AddressCollisionsStub = nil
+	fake.addressCollisionsReturns = struct {
+		result1 []routingtable.AddressCollision
+	}{result1}
+}
+
+func (fake *FakeRoutingTable) AddressCollisionsReturnsOnCall(i int, result1 []routingtable.AddressCollision) {
+	fake.addressCollisionsMutex.Lock()
+	defer fake.addressCollisionsMutex.Unlock()
+	fake.AddressCollisionsStub = nil
+	if fake.addressCollisionsReturnsOnCall == nil {
+		fake.addressCollisionsReturnsOnCall = make(map[int]struct {
+			result1 []routingtable.AddressCollision
+		})
+	}
+	fake.addressCollisionsReturnsOnCall[i] = struct {
+		result1 []routingtable.AddressCollision
+	}{result1}
+}
+
 func (fake *FakeRoutingTable) GetExternalRoutingEvents() (routingtable.TCPRouteMappings, routingtable.MessagesToEmit) {
 	fake.getExternalRoutingEventsMutex.Lock()
 	ret, specificReturn := fake.getExternalRoutingEventsReturnsOnCall[len(fake.getExternalRoutingEventsArgsForCall)]
@@ -499,6 +653,101 @@ func (fake *FakeRoutingTable) InternalAssociationsCountReturnsOnCall(i int, resu
 	}{result1}
 }
 
+func (fake *FakeRoutingTable) PreviewSwap(arg1 routingtable.RoutingTable, arg2 models.DomainSet) (routingtable.TCPRouteMappings, routingtable.MessagesToEmit) {
+	fake.previewSwapMutex.Lock()
+	ret, specificReturn := fake.previewSwapReturnsOnCall[len(fake.previewSwapArgsForCall)]
+	fake.previewSwapArgsForCall = append(fake.previewSwapArgsForCall, struct {
+		arg1 routingtable.RoutingTable
+		arg2 models.DomainSet
+	}{arg1, arg2})
+	fake.recordInvocation("PreviewSwap", []interface{}{arg1, arg2})
+	fake.previewSwapMutex.Unlock()
+	if fake.PreviewSwapStub != nil {
+		return fake.PreviewSwapStub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.previewSwapReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeRoutingTable) PreviewSwapCallCount() int {
+	fake.previewSwapMutex.RLock()
+	defer fake.previewSwapMutex.RUnlock()
+	return len(fake.previewSwapArgsForCall)
+}
+
+func (fake *FakeRoutingTable) PreviewSwapCalls(stub func(routingtable.RoutingTable, models.DomainSet) (routingtable.TCPRouteMappings, routingtable.MessagesToEmit)) {
+	fake.previewSwapMutex.Lock()
+	defer fake.previewSwapMutex.Unlock()
+	fake.PreviewSwapStub = stub
+}
+
+func (fake *FakeRoutingTable) PreviewSwapArgsForCall(i int) (routingtable.RoutingTable, models.DomainSet) {
+	fake.previewSwapMutex.RLock()
+	defer fake.previewSwapMutex.RUnlock()
+	argsForCall := fake.previewSwapArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeRoutingTable) PreviewSwapReturns(result1 routingtable.TCPRouteMappings, result2 routingtable.MessagesToEmit) {
+	fake.previewSwapMutex.Lock()
+	defer fake.previewSwapMutex.Unlock()
+	fake.PreviewSwapStub = nil
+	fake.previewSwapReturns = struct {
+		result1 routingtable.TCPRouteMappings
+		result2 routingtable.MessagesToEmit
+	}{result1, result2}
+}
+
+func (fake *FakeRoutingTable) PreviewSwapReturnsOnCall(i int, result1 routingtable.TCPRouteMappings, result2 routingtable.MessagesToEmit) {
+	fake.previewSwapMutex.Lock()
+	defer fake.previewSwapMutex.Unlock()
+	fake.PreviewSwapStub = nil
+	if fake.previewSwapReturnsOnCall == nil {
+		fake.previewSwapReturnsOnCall = make(map[int]struct {
+			result1 routingtable.TCPRouteMappings
+			result2 routingtable.MessagesToEmit
+		})
+	}
+	fake.previewSwapReturnsOnCall[i] = struct {
+		result1 routingtable.TCPRouteMappings
+		result2 routingtable.MessagesToEmit
+	}{result1, result2}
+}
+
+func (fake *FakeRoutingTable) RegisterRouteProvider(arg1 routingtable.RouteProvider) {
+	fake.registerRouteProviderMutex.Lock()
+	fake.registerRouteProviderArgsForCall = append(fake.registerRouteProviderArgsForCall, struct {
+		arg1 routingtable.RouteProvider
+	}{arg1})
+	fake.recordInvocation("RegisterRouteProvider", []interface{}{arg1})
+	fake.registerRouteProviderMutex.Unlock()
+	if fake.RegisterRouteProviderStub != nil {
+		fake.RegisterRouteProviderStub(arg1)
+	}
+}
+
+func (fake *FakeRoutingTable) RegisterRouteProviderCallCount() int {
+	fake.registerRouteProviderMutex.RLock()
+	defer fake.registerRouteProviderMutex.RUnlock()
+	return len(fake.registerRouteProviderArgsForCall)
+}
+
+func (fake *FakeRoutingTable) RegisterRouteProviderCalls(stub func(routingtable.RouteProvider)) {
+	fake.registerRouteProviderMutex.Lock()
+	defer fake.registerRouteProviderMutex.Unlock()
+	fake.RegisterRouteProviderStub = stub
+}
+
+func (fake *FakeRoutingTable) RegisterRouteProviderArgsForCall(i int) routingtable.RouteProvider {
+	fake.registerRouteProviderMutex.RLock()
+	defer fake.registerRouteProviderMutex.RUnlock()
+	argsForCall := fake.registerRouteProviderArgsForCall[i]
+	return argsForCall.arg1
+}
+
 func (fake *FakeRoutingTable) RemoveEndpoint(arg1 lager.Logger, arg2 *models.ActualLRP) (routingtable.TCPRouteMappings, routingtable.MessagesToEmit) {
 	fake.removeEndpointMutex.Lock()
 	ret, specificReturn := fake.removeEndpointReturnsOnCall[len(fake.removeEndpointArgsForCall)]
@@ -627,6 +876,271 @@ func (fake *FakeRoutingTable) RemoveRoutesReturnsOnCall(i int, result1 routingta
 	}{result1, result2}
 }
 
+func (fake *FakeRoutingTable) Restore(arg1 lager.Logger, arg2 routingtable.Snapshot) {
+	fake.restoreMutex.Lock()
+	fake.restoreArgsForCall = append(fake.restoreArgsForCall, struct {
+		arg1 lager.Logger
+		arg2 routingtable.Snapshot
+	}{arg1, arg2})
+	fake.recordInvocation("Restore", []interface{}{arg1, arg2})
+	fake.restoreMutex.Unlock()
+	if fake.RestoreStub != nil {
+		fake.RestoreStub(arg1, arg2)
+	}
+}
+
+func (fake *FakeRoutingTable) RestoreCallCount() int {
+	fake.restoreMutex.RLock()
+	defer fake.restoreMutex.RUnlock()
+	return len(fake.restoreArgsForCall)
+}
+
+func (fake *FakeRoutingTable) RestoreCalls(stub func(lager.Logger, routingtable.Snapshot)) {
+	fake.restoreMutex.Lock()
+	defer fake.restoreMutex.Unlock()
+	fake.RestoreStub = stub
+}
+
+func (fake *FakeRoutingTable) RestoreArgsForCall(i int) (lager.Logger, routingtable.Snapshot) {
+	fake.restoreMutex.RLock()
+	defer fake.restoreMutex.RUnlock()
+	argsForCall := fake.restoreArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeRoutingTable) RouteProviders() []routingtable.RouteProvider {
+	fake.routeProvidersMutex.Lock()
+	ret, specificReturn := fake.routeProvidersReturnsOnCall[len(fake.routeProvidersArgsForCall)]
+	fake.routeProvidersArgsForCall = append(fake.routeProvidersArgsForCall, struct {
+	}{})
+	fake.recordInvocation("RouteProviders", []interface{}{})
+	fake.routeProvidersMutex.Unlock()
+	if fake.RouteProvidersStub != nil {
+		return fake.RouteProvidersStub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.routeProvidersReturns
+	return fakeReturns.result1
+}
+
+func (fake *FakeRoutingTable) RouteProvidersCallCount() int {
+	fake.routeProvidersMutex.RLock()
+	defer fake.routeProvidersMutex.RUnlock()
+	return len(fake.routeProvidersArgsForCall)
+}
+
+func (fake *FakeRoutingTable) RouteProvidersCalls(stub func() []routingtable.RouteProvider) {
+	fake.routeProvidersMutex.Lock()
+	defer fake.routeProvidersMutex.Unlock()
+	fake.RouteProvidersStub = stub
+}
+
+func (fake *FakeRoutingTable) RouteProvidersReturns(result1 []routingtable.RouteProvider) {
+	fake.routeProvidersMutex.Lock()
+	defer fake.routeProvidersMutex.Unlock()
+	fake.RouteProvidersStub = nil
+	fake.routeProvidersReturns = struct {
+		result1 []routingtable.RouteProvider
+	}{result1}
+}
+
+func (fake *FakeRoutingTable) RouteProvidersReturnsOnCall(i int, result1 []routingtable.RouteProvider) {
+	fake.routeProvidersMutex.Lock()
+	defer fake.routeProvidersMutex.Unlock()
+	fake.RouteProvidersStub = nil
+	if fake.routeProvidersReturnsOnCall == nil {
+		fake.routeProvidersReturnsOnCall = make(map[int]struct {
+			result1 []routingtable.RouteProvider
+		})
+	}
+	fake.routeProvidersReturnsOnCall[i] = struct {
+		result1 []routingtable.RouteProvider
+	}{result1}
+}
+
+func (fake *FakeRoutingTable) RoutingKeysForHostname(arg1 string) routingtable.RoutingKeys {
+	fake.routingKeysForHostnameMutex.Lock()
+	ret, specificReturn := fake.routingKeysForHostnameReturnsOnCall[len(fake.routingKeysForHostnameArgsForCall)]
+	fake.routingKeysForHostnameArgsForCall = append(fake.routingKeysForHostnameArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	fake.recordInvocation("RoutingKeysForHostname", []interface{}{arg1})
+	fake.routingKeysForHostnameMutex.Unlock()
+	if fake.RoutingKeysForHostnameStub != nil {
+		return fake.RoutingKeysForHostnameStub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.routingKeysForHostnameReturns
+	return fakeReturns.result1
+}
+
+func (fake *FakeRoutingTable) RoutingKeysForHostnameCallCount() int {
+	fake.routingKeysForHostnameMutex.RLock()
+	defer fake.routingKeysForHostnameMutex.RUnlock()
+	return len(fake.routingKeysForHostnameArgsForCall)
+}
+
+func (fake *FakeRoutingTable) RoutingKeysForHostnameCalls(stub func(string) routingtable.RoutingKeys) {
+	fake.routingKeysForHostnameMutex.Lock()
+	defer fake.routingKeysForHostnameMutex.Unlock()
+	fake.RoutingKeysForHostnameStub = stub
+}
+
+func (fake *FakeRoutingTable) RoutingKeysForHostnameArgsForCall(i int) string {
+	fake.routingKeysForHostnameMutex.RLock()
+	defer fake.routingKeysForHostnameMutex.RUnlock()
+	argsForCall := fake.routingKeysForHostnameArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeRoutingTable) RoutingKeysForHostnameReturns(result1 routingtable.RoutingKeys) {
+	fake.routingKeysForHostnameMutex.Lock()
+	defer fake.routingKeysForHostnameMutex.Unlock()
+	fake.RoutingKeysForHostnameStub = nil
+	fake.routingKeysForHostnameReturns = struct {
+		result1 routingtable.RoutingKeys
+	}{result1}
+}
+
+func (fake *FakeRoutingTable) RoutingKeysForHostnameReturnsOnCall(i int, result1 routingtable.RoutingKeys) {
+	fake.routingKeysForHostnameMutex.Lock()
+	defer fake.routingKeysForHostnameMutex.Unlock()
+	fake.RoutingKeysForHostnameStub = nil
+	if fake.routingKeysForHostnameReturnsOnCall == nil {
+		fake.routingKeysForHostnameReturnsOnCall = make(map[int]struct {
+			result1 routingtable.RoutingKeys
+		})
+	}
+	fake.routingKeysForHostnameReturnsOnCall[i] = struct {
+		result1 routingtable.RoutingKeys
+	}{result1}
+}
+
+func (fake *FakeRoutingTable) RoutingKeysForInstance(arg1 string) routingtable.RoutingKeys {
+	fake.routingKeysForInstanceMutex.Lock()
+	ret, specificReturn := fake.routingKeysForInstanceReturnsOnCall[len(fake.routingKeysForInstanceArgsForCall)]
+	fake.routingKeysForInstanceArgsForCall = append(fake.routingKeysForInstanceArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	fake.recordInvocation("RoutingKeysForInstance", []interface{}{arg1})
+	fake.routingKeysForInstanceMutex.Unlock()
+	if fake.RoutingKeysForInstanceStub != nil {
+		return fake.RoutingKeysForInstanceStub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.routingKeysForInstanceReturns
+	return fakeReturns.result1
+}
+
+func (fake *FakeRoutingTable) RoutingKeysForInstanceCallCount() int {
+	fake.routingKeysForInstanceMutex.RLock()
+	defer fake.routingKeysForInstanceMutex.RUnlock()
+	return len(fake.routingKeysForInstanceArgsForCall)
+}
+
+func (fake *FakeRoutingTable) RoutingKeysForInstanceCalls(stub func(string) routingtable.RoutingKeys) {
+	fake.routingKeysForInstanceMutex.Lock()
+	defer fake.routingKeysForInstanceMutex.Unlock()
+	fake.RoutingKeysForInstanceStub = stub
+}
+
+func (fake *FakeRoutingTable) RoutingKeysForInstanceArgsForCall(i int) string {
+	fake.routingKeysForInstanceMutex.RLock()
+	defer fake.routingKeysForInstanceMutex.RUnlock()
+	argsForCall := fake.routingKeysForInstanceArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeRoutingTable) RoutingKeysForInstanceReturns(result1 routingtable.RoutingKeys) {
+	fake.routingKeysForInstanceMutex.Lock()
+	defer fake.routingKeysForInstanceMutex.Unlock()
+	fake.RoutingKeysForInstanceStub = nil
+	fake.routingKeysForInstanceReturns = struct {
+		result1 routingtable.RoutingKeys
+	}{result1}
+}
+
+func (fake *FakeRoutingTable) RoutingKeysForInstanceReturnsOnCall(i int, result1 routingtable.RoutingKeys) {
+	fake.routingKeysForInstanceMutex.Lock()
+	defer fake.routingKeysForInstanceMutex.Unlock()
+	fake.RoutingKeysForInstanceStub = nil
+	if fake.routingKeysForInstanceReturnsOnCall == nil {
+		fake.routingKeysForInstanceReturnsOnCall = make(map[int]struct {
+			result1 routingtable.RoutingKeys
+		})
+	}
+	fake.routingKeysForInstanceReturnsOnCall[i] = struct {
+		result1 routingtable.RoutingKeys
+	}{result1}
+}
+
+func (fake *FakeRoutingTable) RoutingKeysForRouterGroupPort(arg1 string, arg2 uint32) routingtable.RoutingKeys {
+	fake.routingKeysForRouterGroupPortMutex.Lock()
+	ret, specificReturn := fake.routingKeysForRouterGroupPortReturnsOnCall[len(fake.routingKeysForRouterGroupPortArgsForCall)]
+	fake.routingKeysForRouterGroupPortArgsForCall = append(fake.routingKeysForRouterGroupPortArgsForCall, struct {
+		arg1 string
+		arg2 uint32
+	}{arg1, arg2})
+	fake.recordInvocation("RoutingKeysForRouterGroupPort", []interface{}{arg1, arg2})
+	fake.routingKeysForRouterGroupPortMutex.Unlock()
+	if fake.RoutingKeysForRouterGroupPortStub != nil {
+		return fake.RoutingKeysForRouterGroupPortStub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.routingKeysForRouterGroupPortReturns
+	return fakeReturns.result1
+}
+
+func (fake *FakeRoutingTable) RoutingKeysForRouterGroupPortCallCount() int {
+	fake.routingKeysForRouterGroupPortMutex.RLock()
+	defer fake.routingKeysForRouterGroupPortMutex.RUnlock()
+	return len(fake.routingKeysForRouterGroupPortArgsForCall)
+}
+
+func (fake *FakeRoutingTable) RoutingKeysForRouterGroupPortCalls(stub func(string, uint32) routingtable.RoutingKeys) {
+	fake.routingKeysForRouterGroupPortMutex.Lock()
+	defer fake.routingKeysForRouterGroupPortMutex.Unlock()
+	fake.RoutingKeysForRouterGroupPortStub = stub
+}
+
+func (fake *FakeRoutingTable) RoutingKeysForRouterGroupPortArgsForCall(i int) (string, uint32) {
+	fake.routingKeysForRouterGroupPortMutex.RLock()
+	defer fake.routingKeysForRouterGroupPortMutex.RUnlock()
+	argsForCall := fake.routingKeysForRouterGroupPortArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeRoutingTable) RoutingKeysForRouterGroupPortReturns(result1 routingtable.RoutingKeys) {
+	fake.routingKeysForRouterGroupPortMutex.Lock()
+	defer fake.routingKeysForRouterGroupPortMutex.Unlock()
+	fake.RoutingKeysForRouterGroupPortStub = nil
+	fake.routingKeysForRouterGroupPortReturns = struct {
+		result1 routingtable.RoutingKeys
+	}{result1}
+}
+
+func (fake *FakeRoutingTable) RoutingKeysForRouterGroupPortReturnsOnCall(i int, result1 routingtable.RoutingKeys) {
+	fake.routingKeysForRouterGroupPortMutex.Lock()
+	defer fake.routingKeysForRouterGroupPortMutex.Unlock()
+	fake.RoutingKeysForRouterGroupPortStub = nil
+	if fake.routingKeysForRouterGroupPortReturnsOnCall == nil {
+		fake.routingKeysForRouterGroupPortReturnsOnCall = make(map[int]struct {
+			result1 routingtable.RoutingKeys
+		})
+	}
+	fake.routingKeysForRouterGroupPortReturnsOnCall[i] = struct {
+		result1 routingtable.RoutingKeys
+	}{result1}
+}
+
 func (fake *FakeRoutingTable) SetRoutes(arg1 lager.Logger, arg2 *models.DesiredLRP, arg3 *models.DesiredLRP) (routingtable.TCPRouteMappings, routingtable.MessagesToEmit) {
 	fake.setRoutesMutex.Lock()
 	ret, specificReturn := fake.setRoutesReturnsOnCall[len(fake.setRoutesArgsForCall)]
@@ -692,7 +1206,119 @@ func (fake *FakeRoutingTable) SetRoutesReturnsOnCall(i int, result1 routingtable
 	}{result1, result2}
 }
 
-func (fake *FakeRoutingTable) Swap(arg1 lager.Logger, arg2 routingtable.RoutingTable, arg3 models.DomainSet) (routingtable.TCPRouteMappings, routingtable.MessagesToEmit) {
+func (fake *FakeRoutingTable) Snapshot() routingtable.Snapshot {
+	fake.snapshotMutex.Lock()
+	ret, specificReturn := fake.snapshotReturnsOnCall[len(fake.snapshotArgsForCall)]
+	fake.snapshotArgsForCall = append(fake.snapshotArgsForCall, struct {
+	}{})
+	fake.recordInvocation("Snapshot", []interface{}{})
+	fake.snapshotMutex.Unlock()
+	if fake.SnapshotStub != nil {
+		return fake.SnapshotStub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.snapshotReturns
+	return fakeReturns.result1
+}
+
+func (fake *FakeRoutingTable) SnapshotCallCount() int {
+	fake.snapshotMutex.RLock()
+	defer fake.snapshotMutex.RUnlock()
+	return len(fake.snapshotArgsForCall)
+}
+
+func (fake *FakeRoutingTable) SnapshotCalls(stub func() routingtable.Snapshot) {
+	fake.snapshotMutex.Lock()
+	defer fake.snapshotMutex.Unlock()
+	fake.SnapshotStub = stub
+}
+
+func (fake *FakeRoutingTable) SnapshotReturns(result1 routingtable.Snapshot) {
+	fake.snapshotMutex.Lock()
+	defer fake.snapshotMutex.Unlock()
+	fake.SnapshotStub = nil
+	fake.snapshotReturns = struct {
+		result1 routingtable.Snapshot
+	}{result1}
+}
+
+func (fake *FakeRoutingTable) SnapshotReturnsOnCall(i int, result1 routingtable.Snapshot) {
+	fake.snapshotMutex.Lock()
+	defer fake.snapshotMutex.Unlock()
+	fake.SnapshotStub = nil
+	if fake.snapshotReturnsOnCall == nil {
+		fake.snapshotReturnsOnCall = make(map[int]struct {
+			result1 routingtable.Snapshot
+		})
+	}
+	fake.snapshotReturnsOnCall[i] = struct {
+		result1 routingtable.Snapshot
+	}{result1}
+}
+
+func (fake *FakeRoutingTable) SnapshotForRoutingKeys(arg1 routingtable.RoutingKeys) routingtable.Snapshot {
+	fake.snapshotForRoutingKeysMutex.Lock()
+	ret, specificReturn := fake.snapshotForRoutingKeysReturnsOnCall[len(fake.snapshotForRoutingKeysArgsForCall)]
+	fake.snapshotForRoutingKeysArgsForCall = append(fake.snapshotForRoutingKeysArgsForCall, struct {
+		arg1 routingtable.RoutingKeys
+	}{arg1})
+	fake.recordInvocation("SnapshotForRoutingKeys", []interface{}{arg1})
+	fake.snapshotForRoutingKeysMutex.Unlock()
+	if fake.SnapshotForRoutingKeysStub != nil {
+		return fake.SnapshotForRoutingKeysStub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.snapshotForRoutingKeysReturns
+	return fakeReturns.result1
+}
+
+func (fake *FakeRoutingTable) SnapshotForRoutingKeysCallCount() int {
+	fake.snapshotForRoutingKeysMutex.RLock()
+	defer fake.snapshotForRoutingKeysMutex.RUnlock()
+	return len(fake.snapshotForRoutingKeysArgsForCall)
+}
+
+func (fake *FakeRoutingTable) SnapshotForRoutingKeysCalls(stub func(routingtable.RoutingKeys) routingtable.Snapshot) {
+	fake.snapshotForRoutingKeysMutex.Lock()
+	defer fake.snapshotForRoutingKeysMutex.Unlock()
+	fake.SnapshotForRoutingKeysStub = stub
+}
+
+func (fake *FakeRoutingTable) SnapshotForRoutingKeysArgsForCall(i int) routingtable.RoutingKeys {
+	fake.snapshotForRoutingKeysMutex.RLock()
+	defer fake.snapshotForRoutingKeysMutex.RUnlock()
+	argsForCall := fake.snapshotForRoutingKeysArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeRoutingTable) SnapshotForRoutingKeysReturns(result1 routingtable.Snapshot) {
+	fake.snapshotForRoutingKeysMutex.Lock()
+	defer fake.snapshotForRoutingKeysMutex.Unlock()
+	fake.SnapshotForRoutingKeysStub = nil
+	fake.snapshotForRoutingKeysReturns = struct {
+		result1 routingtable.Snapshot
+	}{result1}
+}
+
+func (fake *FakeRoutingTable) SnapshotForRoutingKeysReturnsOnCall(i int, result1 routingtable.Snapshot) {
+	fake.snapshotForRoutingKeysMutex.Lock()
+	defer fake.snapshotForRoutingKeysMutex.Unlock()
+	fake.SnapshotForRoutingKeysStub = nil
+	if fake.snapshotForRoutingKeysReturnsOnCall == nil {
+		fake.snapshotForRoutingKeysReturnsOnCall = make(map[int]struct {
+			result1 routingtable.Snapshot
+		})
+	}
+	fake.snapshotForRoutingKeysReturnsOnCall[i] = struct {
+		result1 routingtable.Snapshot
+	}{result1}
+}
+
+func (fake *FakeRoutingTable) Swap(arg1 lager.Logger, arg2 routingtable.RoutingTable, arg3 models.DomainSet) (routingtable.TCPRouteMappings, routingtable.MessagesToEmit, routingtable.SyncReport) {
 	fake.swapMutex.Lock()
 	ret, specificReturn := fake.swapReturnsOnCall[len(fake.swapArgsForCall)]
 	fake.swapArgsForCall = append(fake.swapArgsForCall, struct {
@@ -706,10 +1332,10 @@ func (fake *FakeRoutingTable) Swap(arg1 lager.Logger, arg2 routingtable.RoutingT
 		return fake.SwapStub(arg1, arg2, arg3)
 	}
 	if specificReturn {
-		return ret.result1, ret.result2
+		return ret.result1, ret.result2, ret.result3
 	}
 	fakeReturns := fake.swapReturns
-	return fakeReturns.result1, fakeReturns.result2
+	return fakeReturns.result1, fakeReturns.result2, fakeReturns.result3
 }
 
 func (fake *FakeRoutingTable) SwapCallCount() int {
@@ -718,7 +1344,7 @@ func (fake *FakeRoutingTable) SwapCallCount() int {
 	return len(fake.swapArgsForCall)
 }
 
-func (fake *FakeRoutingTable) SwapCalls(stub func(lager.Logger, routingtable.RoutingTable, models.DomainSet) (routingtable.TCPRouteMappings, routingtable.MessagesToEmit)) {
+func (fake *FakeRoutingTable) SwapCalls(stub func(lager.Logger, routingtable.RoutingTable, models.DomainSet) (routingtable.TCPRouteMappings, routingtable.MessagesToEmit, routingtable.SyncReport)) {
 	fake.swapMutex.Lock()
 	defer fake.swapMutex.Unlock()
 	fake.SwapStub = stub
@@ -731,17 +1357,18 @@ func (fake *FakeRoutingTable) SwapArgsForCall(i int) (lager.Logger, routingtable
 	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
 }
 
-func (fake *FakeRoutingTable) SwapReturns(result1 routingtable.TCPRouteMappings, result2 routingtable.MessagesToEmit) {
+func (fake *FakeRoutingTable) SwapReturns(result1 routingtable.TCPRouteMappings, result2 routingtable.MessagesToEmit, result3 routingtable.SyncReport) {
 	fake.swapMutex.Lock()
 	defer fake.swapMutex.Unlock()
 	fake.SwapStub = nil
 	fake.swapReturns = struct {
 		result1 routingtable.TCPRouteMappings
 		result2 routingtable.MessagesToEmit
-	}{result1, result2}
+		result3 routingtable.SyncReport
+	}{result1, result2, result3}
 }
 
-func (fake *FakeRoutingTable) SwapReturnsOnCall(i int, result1 routingtable.TCPRouteMappings, result2 routingtable.MessagesToEmit) {
+func (fake *FakeRoutingTable) SwapReturnsOnCall(i int, result1 routingtable.TCPRouteMappings, result2 routingtable.MessagesToEmit, result3 routingtable.SyncReport) {
 	fake.swapMutex.Lock()
 	defer fake.swapMutex.Unlock()
 	fake.SwapStub = nil
@@ -749,12 +1376,14 @@ func (fake *FakeRoutingTable) SwapReturnsOnCall(i int, result1 routingtable.TCPR
 		fake.swapReturnsOnCall = make(map[int]struct {
 			result1 routingtable.TCPRouteMappings
 			result2 routingtable.MessagesToEmit
+			result3 routingtable.SyncReport
 		})
 	}
 	fake.swapReturnsOnCall[i] = struct {
 		result1 routingtable.TCPRouteMappings
 		result2 routingtable.MessagesToEmit
-	}{result1, result2}
+		result3 routingtable.SyncReport
+	}{result1, result2, result3}
 }
 
 func (fake *FakeRoutingTable) TCPAssociationsCount() int {
@@ -866,6 +1495,8 @@ func (fake *FakeRoutingTable) Invocations() map[string][][]interface{} {
 	defer fake.invocationsMutex.RUnlock()
 	fake.addEndpointMutex.RLock()
 	defer fake.addEndpointMutex.RUnlock()
+	fake.addressCollisionsMutex.RLock()
+	defer fake.addressCollisionsMutex.RUnlock()
 	fake.getExternalRoutingEventsMutex.RLock()
 	defer fake.getExternalRoutingEventsMutex.RUnlock()
 	fake.getInternalRoutingEventsMutex.RLock()
@@ -876,12 +1507,30 @@ func (fake *FakeRoutingTable) Invocations() map[string][][]interface{} {
 	defer fake.hasExternalRoutesMutex.RUnlock()
 	fake.internalAssociationsCountMutex.RLock()
 	defer fake.internalAssociationsCountMutex.RUnlock()
+	fake.previewSwapMutex.RLock()
+	defer fake.previewSwapMutex.RUnlock()
+	fake.registerRouteProviderMutex.RLock()
+	defer fake.registerRouteProviderMutex.RUnlock()
 	fake.removeEndpointMutex.RLock()
 	defer fake.removeEndpointMutex.RUnlock()
 	fake.removeRoutesMutex.RLock()
 	defer fake.removeRoutesMutex.RUnlock()
+	fake.restoreMutex.RLock()
+	defer fake.restoreMutex.RUnlock()
+	fake.routeProvidersMutex.RLock()
+	defer fake.routeProvidersMutex.RUnlock()
+	fake.routingKeysForHostnameMutex.RLock()
+	defer fake.routingKeysForHostnameMutex.RUnlock()
+	fake.routingKeysForInstanceMutex.RLock()
+	defer fake.routingKeysForInstanceMutex.RUnlock()
+	fake.routingKeysForRouterGroupPortMutex.RLock()
+	defer fake.routingKeysForRouterGroupPortMutex.RUnlock()
 	fake.setRoutesMutex.RLock()
 	defer fake.setRoutesMutex.RUnlock()
+	fake.snapshotMutex.RLock()
+	defer fake.snapshotMutex.RUnlock()
+	fake.snapshotForRoutingKeysMutex.RLock()
+	defer fake.snapshotForRoutingKeysMutex.RUnlock()
 	fake.swapMutex.RLock()
 	defer fake.swapMutex.RUnlock()
 	fake.tCPAssociationsCountMutex.RLock()
@@ -0,0 +1,71 @@
+package routingtable_test
+
+import (
+	"code.cloudfoundry.org/bbs/models"
+	mfakes "code.cloudfoundry.org/diego-logging-client/testhelpers"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Hostname aggregation", func() {
+	var (
+		table      routingtable.RoutingTable
+		logger     *lagertest.TestLogger
+		desiredLRP *models.DesiredLRP
+		actualLRP  *models.ActualLRP
+	)
+
+	tag := models.ModificationTag{Epoch: "abc", Index: 1}
+	newerTag := models.ModificationTag{Epoch: "abc", Index: 2}
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test-route-emitter")
+		table = routingtable.NewRoutingTable(false, &mfakes.FakeIngressClient{}, routingtable.WithHostnameAggregation())
+
+		desiredLRP = createDesiredLRP("process-guid-1", 1, 8080, "log-guid", "", tag, models.DesiredLRPRunInfo{}, "foo.example.com", "bar.example.com")
+		endpoint := routingtable.Endpoint{
+			InstanceGUID:    "ig-1",
+			Host:            "1.1.1.1",
+			ContainerIP:     "1.2.3.4",
+			Port:            11,
+			ContainerPort:   8080,
+			ModificationTag: &tag,
+		}
+		actualLRP = createActualLRP(routingtable.RoutingKey{ProcessGUID: "process-guid-1", ContainerPort: 8080}, endpoint, "domain")
+		table.SetRoutes(logger, nil, desiredLRP)
+	})
+
+	It("registers every hostname of an endpoint in a single message", func() {
+		_, messages := table.AddEndpoint(logger, actualLRP)
+		Expect(messages.RegistrationMessages).To(HaveLen(1))
+		Expect(messages.RegistrationMessages[0].URIs).To(ConsistOf("foo.example.com", "bar.example.com"))
+		Expect(messages.RouteRegistrationCount()).To(BeEquivalentTo(2))
+	})
+
+	It("aggregates the messages of a broadcast", func() {
+		table.AddEndpoint(logger, actualLRP)
+		_, messages := table.GetExternalRoutingEvents()
+		Expect(messages.RegistrationMessages).To(HaveLen(1))
+		Expect(messages.RegistrationMessages[0].URIs).To(ConsistOf("foo.example.com", "bar.example.com"))
+	})
+
+	It("unregisters every hostname of a removed endpoint in a single message", func() {
+		table.AddEndpoint(logger, actualLRP)
+		_, messages := table.RemoveEndpoint(logger, actualLRP)
+		Expect(messages.UnregistrationMessages).To(HaveLen(1))
+		Expect(messages.UnregistrationMessages[0].URIs).To(ConsistOf("foo.example.com", "bar.example.com"))
+	})
+
+	It("only includes the changed hostnames when routes change", func() {
+		table.AddEndpoint(logger, actualLRP)
+		after := createDesiredLRP("process-guid-1", 1, 8080, "log-guid", "", newerTag, models.DesiredLRPRunInfo{}, "foo.example.com", "baz.example.com", "qux.example.com")
+		_, messages := table.SetRoutes(logger, desiredLRP, after)
+		Expect(messages.RegistrationMessages).To(HaveLen(1))
+		Expect(messages.RegistrationMessages[0].URIs).To(ConsistOf("baz.example.com", "qux.example.com"))
+		Expect(messages.UnregistrationMessages).To(HaveLen(1))
+		Expect(messages.UnregistrationMessages[0].URIs).To(ConsistOf("bar.example.com"))
+	})
+})
@@ -0,0 +1,38 @@
+package routingtable
+
+import (
+	"encoding/json"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/routing-info/cfroutes"
+)
+
+// RouteOptions are the per-route load-balancing options of an HTTP route, as
+// understood by the gorouter.
+type RouteOptions struct {
+	LoadBalancingAlgorithm string `json:"loadbalancing,omitempty"`
+	HashHeaderName         string `json:"hash_header,omitempty"`
+	HashBalance            string `json:"hash_balance,omitempty"`
+}
+
+// httpRouteInfo is a route of the cf-router routing info, including the
+// fields that cfroutes.CFRoute does not decode.
+type httpRouteInfo struct {
+	cfroutes.CFRoute
+	Options *RouteOptions `json:"options,omitempty"`
+}
+
+func httpRouteInfosFrom(routes *models.Routes) ([]httpRouteInfo, error) {
+	if routes == nil {
+		return nil, nil
+	}
+
+	data, ok := (*routes)[cfroutes.CF_ROUTER]
+	if !ok || data == nil {
+		return nil, nil
+	}
+
+	infos := []httpRouteInfo{}
+	err := json.Unmarshal(*data, &infos)
+	return infos, err
+}
@@ -0,0 +1,83 @@
+package routingtable_test
+
+import (
+	"encoding/json"
+
+	"code.cloudfoundry.org/bbs/models"
+	mfakes "code.cloudfoundry.org/diego-logging-client/testhelpers"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/routing-info/cfroutes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("HTTP routes with options", func() {
+	var (
+		logger       *lagertest.TestLogger
+		routingTable routingtable.RoutingTable
+		actualLRP    *models.ActualLRP
+	)
+
+	tag := models.ModificationTag{Epoch: "abc", Index: 0}
+
+	desiredLRP := func(cfRouterInfo string, tag models.ModificationTag) *models.DesiredLRP {
+		raw := json.RawMessage(cfRouterInfo)
+		return &models.DesiredLRP{
+			ProcessGuid:     "process-guid",
+			LogGuid:         "log-guid",
+			Domain:          "domain",
+			Instances:       1,
+			ModificationTag: &tag,
+			Routes:          &models.Routes{cfroutes.CF_ROUTER: &raw},
+		}
+	}
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+		routingTable = routingtable.NewRoutingTable(false, &mfakes.FakeIngressClient{})
+		actualLRP = &models.ActualLRP{
+			ActualLRPKey:         models.NewActualLRPKey("process-guid", 0, "domain"),
+			ActualLRPInstanceKey: models.NewActualLRPInstanceKey("instance-guid", "cell-id"),
+			ActualLRPNetInfo:     models.NewActualLRPNetInfo("1.1.1.1", "2.2.2.2", models.ActualLRPNetInfo_PreferredAddressHost, models.NewPortMapping(61001, 8080)),
+			State:                models.ActualLRPStateRunning,
+			ModificationTag:      tag,
+		}
+
+		routingTable.SetRoutes(logger, nil, desiredLRP(`[{"hostnames": ["a.example.com"], "port": 8080, "options": {"loadbalancing": "hash", "hash_header": "X-Tenant"}}]`, tag))
+	})
+
+	It("registers the route with its options", func() {
+		_, messages := routingTable.AddEndpoint(logger, actualLRP)
+		Expect(messages.RegistrationMessages).To(HaveLen(1))
+		Expect(messages.RegistrationMessages[0].Options).To(Equal(&routingtable.RouteOptions{
+			LoadBalancingAlgorithm: "hash",
+			HashHeaderName:         "X-Tenant",
+		}))
+	})
+
+	Context("when only the options of a route change", func() {
+		It("re-registers the route", func() {
+			routingTable.AddEndpoint(logger, actualLRP)
+
+			before := desiredLRP(`[{"hostnames": ["a.example.com"], "port": 8080, "options": {"loadbalancing": "hash", "hash_header": "X-Tenant"}}]`, tag)
+			after := desiredLRP(`[{"hostnames": ["a.example.com"], "port": 8080, "options": {"loadbalancing": "least-connection"}}]`, models.ModificationTag{Epoch: "abc", Index: 1})
+			_, messages := routingTable.SetRoutes(logger, before, after)
+			Expect(messages.RegistrationMessages).To(HaveLen(1))
+			Expect(messages.RegistrationMessages[0].Options).To(Equal(&routingtable.RouteOptions{LoadBalancingAlgorithm: "least-connection"}))
+			Expect(messages.UnregistrationMessages).To(HaveLen(1))
+			Expect(messages.UnregistrationMessages[0].Options.LoadBalancingAlgorithm).To(Equal("hash"))
+		})
+	})
+
+	Context("when a route has no options", func() {
+		It("registers the route without options", func() {
+			routingTable = routingtable.NewRoutingTable(false, &mfakes.FakeIngressClient{})
+			routingTable.SetRoutes(logger, nil, desiredLRP(`[{"hostnames": ["a.example.com"], "port": 8080}]`, tag))
+			_, messages := routingTable.AddEndpoint(logger, actualLRP)
+			Expect(messages.RegistrationMessages).To(HaveLen(1))
+			Expect(messages.RegistrationMessages[0].Options).To(BeNil())
+		})
+	})
+})
@@ -0,0 +1,96 @@
+package routingtable
+
+type RouterGroupPort struct {
+	RouterGroupGUID string
+	Port            uint32
+}
+
+type routingKeySet map[RoutingKey]struct{}
+
+// tableIndexes are secondary indexes over the entries of a tableShard. They
+// must be updated every time an entry changes, which is why all writes to
+// tableShard.entries go through setEntry.
+type tableIndexes struct {
+	hostnames        map[string]routingKeySet
+	instances        map[string]routingKeySet
+	routerGroupPorts map[RouterGroupPort]routingKeySet
+}
+
+func newTableIndexes() tableIndexes {
+	return tableIndexes{
+		hostnames:        make(map[string]routingKeySet),
+		instances:        make(map[string]routingKeySet),
+		routerGroupPorts: make(map[RouterGroupPort]routingKeySet),
+	}
+}
+
+func (i tableIndexes) update(key RoutingKey, before, after RoutableEndpoints) {
+	i.remove(key, before)
+	i.add(key, after)
+}
+
+func (i tableIndexes) add(key RoutingKey, entry RoutableEndpoints) {
+	for _, route := range entry.Routes {
+		switch route := route.(type) {
+		case Route:
+			addToIndex(i.hostnames, route.Hostname, key)
+		case InternalRoute:
+			addToIndex(i.hostnames, route.Hostname, key)
+		case ExternalEndpointInfo:
+			addToIndex(i.routerGroupPorts, route.routerGroupPort(), key)
+		}
+	}
+
+	for _, endpoint := range entry.Endpoints {
+		addToIndex(i.instances, endpoint.InstanceGUID, key)
+	}
+}
+
+func (i tableIndexes) remove(key RoutingKey, entry RoutableEndpoints) {
+	for _, route := range entry.Routes {
+		switch route := route.(type) {
+		case Route:
+			removeFromIndex(i.hostnames, route.Hostname, key)
+		case InternalRoute:
+			removeFromIndex(i.hostnames, route.Hostname, key)
+		case ExternalEndpointInfo:
+			removeFromIndex(i.routerGroupPorts, route.routerGroupPort(), key)
+		}
+	}
+
+	for _, endpoint := range entry.Endpoints {
+		removeFromIndex(i.instances, endpoint.InstanceGUID, key)
+	}
+}
+
+func addToIndex[K comparable](index map[K]routingKeySet, indexKey K, key RoutingKey) {
+	keys, ok := index[indexKey]
+	if !ok {
+		keys = make(routingKeySet)
+		index[indexKey] = keys
+	}
+	keys[key] = struct{}{}
+}
+
+func removeFromIndex[K comparable](index map[K]routingKeySet, indexKey K, key RoutingKey) {
+	keys, ok := index[indexKey]
+	if !ok {
+		return
+	}
+	delete(keys, key)
+	if len(keys) == 0 {
+		delete(index, indexKey)
+	}
+}
+
+func (keys routingKeySet) routingKeys() RoutingKeys {
+	result := make(RoutingKeys, 0, len(keys))
+	for key := range keys {
+		result = append(result, key)
+	}
+	return result
+}
+
+func (info ExternalEndpointInfo) routerGroupPort() RouterGroupPort {
+	return RouterGroupPort{RouterGroupGUID: info.RouterGroupGUID, Port: info.Port}
+}
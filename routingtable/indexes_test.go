@@ -0,0 +1,128 @@
+package routingtable_test
+
+import (
+	"code.cloudfoundry.org/bbs/models"
+	mfakes "code.cloudfoundry.org/diego-logging-client/testhelpers"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Indexes", func() {
+	var (
+		table            routingtable.RoutingTable
+		logger           *lagertest.TestLogger
+		fakeMetronClient *mfakes.FakeIngressClient
+		desiredLRP       *models.DesiredLRP
+		actualLRP        *models.ActualLRP
+		httpKey          routingtable.RoutingKey
+		internalKey      routingtable.RoutingKey
+	)
+
+	tag := models.ModificationTag{Epoch: "abc", Index: 1}
+	newerTag := models.ModificationTag{Epoch: "abc", Index: 2}
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test-route-emitter")
+		fakeMetronClient = &mfakes.FakeIngressClient{}
+		table = routingtable.NewRoutingTable(false, fakeMetronClient)
+
+		httpKey = routingtable.RoutingKey{ProcessGUID: "process-guid-1", ContainerPort: 8080}
+		internalKey = routingtable.RoutingKey{ProcessGUID: "process-guid-1"}
+
+		routes := createRoutingInfo(8080, []string{"foo.example.com"}, []string{"foo.apps.internal"}, "", []uint32{61000}, "router-group-guid")
+		desiredLRP = createDesiredLRPWithRoutes("process-guid-1", 1, routes, "log-guid", tag, models.DesiredLRPRunInfo{})
+		table.SetRoutes(logger, nil, desiredLRP)
+
+		endpoint := routingtable.Endpoint{
+			InstanceGUID:    "ig-1",
+			Host:            "1.1.1.1",
+			ContainerIP:     "1.2.3.4",
+			Port:            11,
+			ContainerPort:   8080,
+			ModificationTag: &tag,
+		}
+		actualLRP = createActualLRP(httpKey, endpoint, "domain")
+		table.AddEndpoint(logger, actualLRP)
+	})
+
+	It("indexes routing keys by hostname", func() {
+		Expect(table.RoutingKeysForHostname("foo.example.com")).To(ConsistOf(httpKey))
+		Expect(table.RoutingKeysForHostname("foo.apps.internal")).To(ConsistOf(internalKey))
+		Expect(table.RoutingKeysForHostname("unknown.example.com")).To(BeEmpty())
+	})
+
+	It("indexes routing keys by instance guid", func() {
+		Expect(table.RoutingKeysForInstance("ig-1")).To(ConsistOf(httpKey, internalKey))
+	})
+
+	It("indexes routing keys by router group and external port", func() {
+		Expect(table.RoutingKeysForRouterGroupPort("router-group-guid", 61000)).To(ConsistOf(httpKey))
+		Expect(table.RoutingKeysForRouterGroupPort("router-group-guid", 61001)).To(BeEmpty())
+	})
+
+	It("returns snapshots of the indexed entries", func() {
+		snapshot := table.SnapshotForRoutingKeys(table.RoutingKeysForHostname("foo.example.com"))
+		Expect(snapshot.HTTP).To(HaveLen(1))
+		Expect(snapshot.HTTP[0].ProcessGUID).To(Equal("process-guid-1"))
+		Expect(snapshot.TCP).To(HaveLen(1))
+		Expect(snapshot.Internal).To(HaveLen(1))
+		Expect(snapshot.Internal[0].InternalRoutes).To(BeEmpty())
+	})
+
+	Context("when the endpoint is removed", func() {
+		BeforeEach(func() {
+			table.RemoveEndpoint(logger, actualLRP)
+		})
+
+		It("removes the instance from the index", func() {
+			Expect(table.RoutingKeysForInstance("ig-1")).To(BeEmpty())
+			Expect(table.RoutingKeysForHostname("foo.example.com")).To(ConsistOf(httpKey))
+		})
+	})
+
+	Context("when the routes change", func() {
+		BeforeEach(func() {
+			routes := createRoutingInfo(8080, []string{"bar.example.com"}, nil, "", nil, "")
+			after := createDesiredLRPWithRoutes("process-guid-1", 1, routes, "log-guid", newerTag, models.DesiredLRPRunInfo{})
+			table.SetRoutes(logger, desiredLRP, after)
+		})
+
+		It("updates the hostname and router group indexes", func() {
+			Expect(table.RoutingKeysForHostname("foo.example.com")).To(BeEmpty())
+			Expect(table.RoutingKeysForHostname("foo.apps.internal")).To(BeEmpty())
+			Expect(table.RoutingKeysForHostname("bar.example.com")).To(ConsistOf(httpKey))
+			Expect(table.RoutingKeysForRouterGroupPort("router-group-guid", 61000)).To(BeEmpty())
+		})
+	})
+
+	Context("when the table is swapped", func() {
+		BeforeEach(func() {
+			routes := createRoutingInfo(8080, []string{"baz.example.com"}, nil, "", nil, "")
+			other := createDesiredLRPWithRoutes("process-guid-2", 1, routes, "log-guid", tag, models.DesiredLRPRunInfo{})
+
+			tempTable := routingtable.NewRoutingTable(false, fakeMetronClient)
+			tempTable.SetRoutes(logger, nil, other)
+			table.Swap(logger, tempTable, models.NewDomainSet([]string{"domain"}))
+		})
+
+		It("reflects the contents of the new table", func() {
+			Expect(table.RoutingKeysForHostname("foo.example.com")).To(BeEmpty())
+			Expect(table.RoutingKeysForInstance("ig-1")).To(BeEmpty())
+			Expect(table.RoutingKeysForHostname("baz.example.com")).To(ConsistOf(
+				routingtable.RoutingKey{ProcessGUID: "process-guid-2", ContainerPort: 8080},
+			))
+		})
+	})
+
+	Context("when the table is restored from a snapshot", func() {
+		It("rebuilds the indexes", func() {
+			restored := routingtable.NewRoutingTable(false, fakeMetronClient)
+			restored.Restore(logger, table.Snapshot())
+			Expect(restored.RoutingKeysForHostname("foo.example.com")).To(ConsistOf(httpKey))
+			Expect(restored.RoutingKeysForInstance("ig-1")).To(ConsistOf(httpKey, internalKey))
+		})
+	})
+})
@@ -0,0 +1,123 @@
+package routingtable
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/routing-info/internalroutes"
+)
+
+// internalRouteInfo is a route of the internal-router routing info, including
+// the fields that internalroutes.InternalRoute does not decode. Routes without
+// a port resolve to the container ip only.
+type internalRouteInfo struct {
+	internalroutes.InternalRoute
+	Port uint32 `json:"port,omitempty"`
+}
+
+func internalRouteInfosFrom(routes *models.Routes) ([]internalRouteInfo, error) {
+	if routes == nil {
+		return nil, nil
+	}
+
+	data, ok := (*routes)[internalroutes.INTERNAL_ROUTER]
+	if !ok || data == nil {
+		return nil, nil
+	}
+
+	infos := []internalRouteInfo{}
+	err := json.Unmarshal(*data, &infos)
+	return infos, err
+}
+
+// Placeholders of instance hostname templates.
+const (
+	InstanceHostnameHostname     = "{hostname}"
+	InstanceHostnameIndex        = "{index}"
+	InstanceHostnameInstanceGUID = "{instance_guid}"
+	InstanceHostnameAZ           = "{az}"
+)
+
+// DefaultInstanceHostnameTemplate is the per-instance alias registered for
+// internal routes when no templates are configured.
+const DefaultInstanceHostnameTemplate = InstanceHostnameIndex + "." + InstanceHostnameHostname
+
+var instanceHostnamePlaceholders = regexp.MustCompile(`\{[^{}]*\}`)
+
+// ValidateInstanceHostnameTemplate returns an error if the template uses an
+// unknown placeholder or does not include the route hostname, which would let
+// the aliases of different routes collide.
+func ValidateInstanceHostnameTemplate(template string) error {
+	for _, placeholder := range instanceHostnamePlaceholders.FindAllString(template, -1) {
+		switch placeholder {
+		case InstanceHostnameHostname, InstanceHostnameIndex, InstanceHostnameInstanceGUID, InstanceHostnameAZ:
+		default:
+			return fmt.Errorf("instance hostname template %q has unknown placeholder %s", template, placeholder)
+		}
+	}
+	if !strings.Contains(template, InstanceHostnameHostname) {
+		return fmt.Errorf("instance hostname template %q does not include %s", template, InstanceHostnameHostname)
+	}
+	return nil
+}
+
+// instanceHostnames renders the per-instance aliases of the route for the
+// endpoint. Templates using a placeholder the endpoint has no value for, such
+// as an availability zone, are skipped.
+func instanceHostnames(endpoint Endpoint, route InternalRoute) []string {
+	if len(route.InstanceHostnameTemplates) == 0 {
+		var index string
+		if endpoint.InstanceGUID != "" {
+			index = fmt.Sprintf("%d", endpoint.Index)
+		}
+		return []string{fmt.Sprintf("%s.%s", index, route.Hostname)}
+	}
+
+	values := map[string]string{
+		InstanceHostnameHostname:     route.Hostname,
+		InstanceHostnameInstanceGUID: endpoint.InstanceGUID,
+		InstanceHostnameAZ:           endpoint.AvailabilityZone,
+	}
+	if endpoint.InstanceGUID != "" {
+		values[InstanceHostnameIndex] = fmt.Sprintf("%d", endpoint.Index)
+	}
+
+	hostnames := []string{}
+	seen := map[string]struct{}{route.Hostname: {}}
+	for _, template := range route.InstanceHostnameTemplates {
+		complete := true
+		hostname := instanceHostnamePlaceholders.ReplaceAllStringFunc(template, func(placeholder string) string {
+			value := values[placeholder]
+			if value == "" {
+				complete = false
+			}
+			return value
+		})
+		if _, ok := seen[hostname]; !complete || ok {
+			continue
+		}
+		seen[hostname] = struct{}{}
+		hostnames = append(hostnames, hostname)
+	}
+	return hostnames
+}
+
+// templatedInternalRoutesFrom returns internalRoutesFrom with every route
+// rendering its per-instance aliases from the templates.
+func templatedInternalRoutesFrom(templates []string) func(*models.DesiredLRP) map[RoutingKey][]RouteMapping {
+	return func(lrp *models.DesiredLRP) map[RoutingKey][]RouteMapping {
+		routeEntries := internalRoutesFrom(lrp)
+		for _, routes := range routeEntries {
+			for i, route := range routes {
+				if internalRoute, ok := route.(InternalRoute); ok {
+					internalRoute.InstanceHostnameTemplates = templates
+					routes[i] = internalRoute
+				}
+			}
+		}
+		return routeEntries
+	}
+}
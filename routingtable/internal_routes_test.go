@@ -0,0 +1,208 @@
+package routingtable_test
+
+import (
+	"encoding/json"
+
+	"code.cloudfoundry.org/bbs/models"
+	mfakes "code.cloudfoundry.org/diego-logging-client/testhelpers"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	. "code.cloudfoundry.org/route-emitter/routingtable/matchers"
+	"code.cloudfoundry.org/routing-info/internalroutes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Internal routes with ports", func() {
+	var (
+		logger         *lagertest.TestLogger
+		internalRoutes string
+		messages       routingtable.MessagesToEmit
+	)
+
+	tag := models.ModificationTag{Epoch: "abc", Index: 0}
+
+	registration := func(hostname string, port, tlsPort uint32) routingtable.RegistryMessage {
+		return routingtable.RegistryMessage{
+			Host:                 "2.2.2.2",
+			Port:                 port,
+			TlsPort:              tlsPort,
+			URIs:                 []string{hostname, "0." + hostname},
+			App:                  "log-guid",
+			Tags:                 map[string]string{"component": "route-emitter"},
+			PrivateInstanceIndex: "0",
+		}
+	}
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+		internalRoutes = `[{"hostname": "a.apps.internal", "port": 8080}, {"hostname": "b.apps.internal"}]`
+	})
+
+	JustBeforeEach(func() {
+		raw := json.RawMessage(internalRoutes)
+		desiredLRP := &models.DesiredLRP{
+			ProcessGuid:     "process-guid",
+			LogGuid:         "log-guid",
+			Domain:          "domain",
+			Instances:       1,
+			ModificationTag: &tag,
+			Routes:          &models.Routes{internalroutes.INTERNAL_ROUTER: &raw},
+		}
+		actualLRP := &models.ActualLRP{
+			ActualLRPKey:         models.NewActualLRPKey("process-guid", 0, "domain"),
+			ActualLRPInstanceKey: models.NewActualLRPInstanceKey("instance-guid", "cell-id"),
+			ActualLRPNetInfo: models.NewActualLRPNetInfo("1.1.1.1", "2.2.2.2", models.ActualLRPNetInfo_PreferredAddressHost,
+				models.NewPortMappingWithTLSProxy(61001, 8080, 61443, 8443),
+				models.NewPortMapping(61002, 9090),
+			),
+			State:           models.ActualLRPStateRunning,
+			ModificationTag: tag,
+		}
+
+		routingTable := routingtable.NewRoutingTable(false, &mfakes.FakeIngressClient{})
+		routingTable.SetRoutes(logger, nil, desiredLRP)
+		_, messages = routingTable.AddEndpoint(logger, actualLRP)
+	})
+
+	It("registers routes with a port against that container port and its TLS proxy port", func() {
+		Expect(messages).To(MatchMessagesToEmit(routingtable.MessagesToEmit{
+			InternalRegistrationMessages: []routingtable.RegistryMessage{
+				registration("a.apps.internal", 8080, 8443),
+				registration("b.apps.internal", 0, 0),
+			},
+		}))
+	})
+
+	Context("when the port of a route is not exposed by the instance", func() {
+		BeforeEach(func() {
+			internalRoutes = `[{"hostname": "a.apps.internal", "port": 7070}]`
+		})
+
+		It("does not register the route", func() {
+			Expect(messages.InternalRegistrationMessages).To(BeEmpty())
+		})
+	})
+})
+
+var _ = Describe("Instance hostname templates", func() {
+	var (
+		logger     *lagertest.TestLogger
+		templates  []string
+		desiredLRP *models.DesiredLRP
+		actualLRP  *models.ActualLRP
+	)
+
+	tag := models.ModificationTag{Epoch: "abc", Index: 0}
+
+	newTable := func(templates []string) routingtable.RoutingTable {
+		var options []routingtable.Option
+		if templates != nil {
+			options = append(options, routingtable.WithInstanceHostnameTemplates(templates))
+		}
+		table := routingtable.NewRoutingTable(false, &mfakes.FakeIngressClient{}, options...)
+		table.SetRoutes(logger, nil, desiredLRP)
+		table.AddEndpoint(logger, actualLRP)
+		return table
+	}
+
+	registration := func(uris ...string) routingtable.RegistryMessage {
+		return routingtable.RegistryMessage{
+			Host:                 "2.2.2.2",
+			URIs:                 uris,
+			App:                  "log-guid",
+			Tags:                 map[string]string{"component": "route-emitter"},
+			PrivateInstanceIndex: "1",
+			AvailabilityZone:     "z1",
+		}
+	}
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+		templates = []string{"{instance_guid}.{hostname}", "{az}-{index}.{hostname}"}
+
+		raw := json.RawMessage(`[{"hostname": "a.apps.internal"}]`)
+		desiredLRP = &models.DesiredLRP{
+			ProcessGuid:     "process-guid",
+			LogGuid:         "log-guid",
+			Domain:          "domain",
+			Instances:       2,
+			ModificationTag: &tag,
+			Routes:          &models.Routes{internalroutes.INTERNAL_ROUTER: &raw},
+		}
+		actualLRP = &models.ActualLRP{
+			ActualLRPKey:         models.NewActualLRPKey("process-guid", 1, "domain"),
+			ActualLRPInstanceKey: models.NewActualLRPInstanceKey("instance-guid", "cell-id"),
+			ActualLRPNetInfo:     models.NewActualLRPNetInfo("1.1.1.1", "2.2.2.2", models.ActualLRPNetInfo_PreferredAddressHost),
+			AvailabilityZone:     "z1",
+			State:                models.ActualLRPStateRunning,
+			ModificationTag:      tag,
+		}
+	})
+
+	It("registers an alias per template", func() {
+		_, messages := newTable(templates).GetInternalRoutingEvents()
+		Expect(messages).To(MatchMessagesToEmit(routingtable.MessagesToEmit{
+			InternalRegistrationMessages: []routingtable.RegistryMessage{
+				registration("a.apps.internal", "instance-guid.a.apps.internal", "z1-1.a.apps.internal"),
+			},
+		}))
+	})
+
+	It("registers the index alias by default", func() {
+		_, messages := newTable(nil).GetInternalRoutingEvents()
+		Expect(messages).To(MatchMessagesToEmit(routingtable.MessagesToEmit{
+			InternalRegistrationMessages: []routingtable.RegistryMessage{
+				registration("a.apps.internal", "1.a.apps.internal"),
+			},
+		}))
+	})
+
+	Context("when the instance has no value for a placeholder", func() {
+		BeforeEach(func() {
+			actualLRP.AvailabilityZone = ""
+		})
+
+		It("skips the aliases of that template", func() {
+			_, messages := newTable(templates).GetInternalRoutingEvents()
+			expected := registration("a.apps.internal", "instance-guid.a.apps.internal")
+			expected.AvailabilityZone = ""
+			Expect(messages).To(MatchMessagesToEmit(routingtable.MessagesToEmit{
+				InternalRegistrationMessages: []routingtable.RegistryMessage{expected},
+			}))
+		})
+	})
+
+	Context("when the templates change between restarts", func() {
+		It("unregisters the aliases of the previous templates", func() {
+			table := routingtable.NewRoutingTable(false, &mfakes.FakeIngressClient{}, routingtable.WithInstanceHostnameTemplates(templates))
+			table.Restore(logger, newTable(nil).Snapshot())
+
+			_, messages, _ := table.Swap(logger, newTable(templates), models.NewDomainSet([]string{"domain"}))
+			Expect(messages).To(MatchMessagesToEmit(routingtable.MessagesToEmit{
+				InternalRegistrationMessages: []routingtable.RegistryMessage{
+					registration("a.apps.internal", "instance-guid.a.apps.internal", "z1-1.a.apps.internal"),
+				},
+				InternalUnregistrationMessages: []routingtable.RegistryMessage{
+					registration("a.apps.internal", "1.a.apps.internal"),
+				},
+			}))
+		})
+	})
+
+	Describe("ValidateInstanceHostnameTemplate", func() {
+		It("accepts templates including the hostname", func() {
+			Expect(routingtable.ValidateInstanceHostnameTemplate(routingtable.DefaultInstanceHostnameTemplate)).To(Succeed())
+			Expect(routingtable.ValidateInstanceHostnameTemplate("{az}.{instance_guid}.{hostname}")).To(Succeed())
+		})
+
+		It("rejects unknown placeholders", func() {
+			Expect(routingtable.ValidateInstanceHostnameTemplate("{cell}.{hostname}")).To(MatchError(ContainSubstring("{cell}")))
+		})
+
+		It("rejects templates without the hostname", func() {
+			Expect(routingtable.ValidateInstanceHostnameTemplate("{instance_guid}")).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,109 @@
+package routingtable
+
+import (
+	"fmt"
+	"strconv"
+
+	"code.cloudfoundry.org/bbs/models"
+)
+
+// MetricTagSource is a value the emitter computes for a metric tag of HTTP
+// route registrations, in addition to the tags of the desired LRP.
+type MetricTagSource string
+
+const (
+	MetricTagSourceIndex            MetricTagSource = "index"
+	MetricTagSourceInstanceGUID     MetricTagSource = "instance_guid"
+	MetricTagSourceAvailabilityZone MetricTagSource = "availability_zone"
+	MetricTagSourceCellID           MetricTagSource = "cell_id"
+	MetricTagSourceProcessGUID      MetricTagSource = "process_guid"
+	MetricTagSourceDomain           MetricTagSource = "domain"
+	MetricTagSourceContainerPort    MetricTagSource = "container_port"
+	MetricTagSourceIsolationSegment MetricTagSource = "isolation_segment"
+)
+
+func (source MetricTagSource) Validate() error {
+	switch source {
+	case MetricTagSourceIndex, MetricTagSourceInstanceGUID, MetricTagSourceAvailabilityZone,
+		MetricTagSourceCellID, MetricTagSourceProcessGUID, MetricTagSourceDomain,
+		MetricTagSourceContainerPort, MetricTagSourceIsolationSegment:
+		return nil
+	}
+	return fmt.Errorf("unknown metric tag source %q", source)
+}
+
+// perInstance reports whether the value of the source depends on the
+// endpoint rather than on the desired LRP.
+func (source MetricTagSource) perInstance() bool {
+	switch source {
+	case MetricTagSourceIndex, MetricTagSourceInstanceGUID, MetricTagSourceAvailabilityZone, MetricTagSourceCellID:
+		return true
+	}
+	return false
+}
+
+func (source MetricTagSource) endpointValue(endpoint Endpoint) string {
+	switch source {
+	case MetricTagSourceIndex:
+		return strconv.FormatInt(int64(endpoint.Index), 10)
+	case MetricTagSourceInstanceGUID:
+		return endpoint.InstanceGUID
+	case MetricTagSourceAvailabilityZone:
+		return endpoint.AvailabilityZone
+	case MetricTagSourceCellID:
+		return endpoint.CellID
+	}
+	return ""
+}
+
+// metricTagRoutesFrom returns httpRoutesFrom with the tags of the sources
+// added to every route. Tags set by the desired LRP take precedence. Values
+// of the desired LRP are resolved into static tags right away, values of the
+// endpoint are resolved for each registry message.
+func metricTagRoutesFrom(sources map[string]MetricTagSource) func(*models.DesiredLRP) map[RoutingKey][]RouteMapping {
+	return func(lrp *models.DesiredLRP) map[RoutingKey][]RouteMapping {
+		routeEntries := httpRoutesFrom(lrp)
+		for key, routes := range routeEntries {
+			for i, mapping := range routes {
+				route, ok := mapping.(Route)
+				if !ok {
+					continue
+				}
+
+				metricTags := make(map[string]*models.MetricTagValue, len(route.MetricTags)+len(sources))
+				for name, value := range route.MetricTags {
+					metricTags[name] = value
+				}
+				for name, source := range sources {
+					if _, ok := metricTags[name]; ok {
+						continue
+					}
+					if source.perInstance() {
+						if route.MetricTagSources == nil {
+							route.MetricTagSources = map[string]MetricTagSource{}
+						}
+						route.MetricTagSources[name] = source
+						continue
+					}
+
+					var value string
+					switch source {
+					case MetricTagSourceProcessGUID:
+						value = lrp.ProcessGuid
+					case MetricTagSourceDomain:
+						value = lrp.Domain
+					case MetricTagSourceContainerPort:
+						value = strconv.FormatUint(uint64(key.ContainerPort), 10)
+					case MetricTagSourceIsolationSegment:
+						value = route.IsolationSegment
+					}
+					metricTags[name] = &models.MetricTagValue{Static: value}
+				}
+
+				route.MetricTags = metricTags
+				routes[i] = route
+			}
+		}
+		return routeEntries
+	}
+}
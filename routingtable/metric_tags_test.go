@@ -0,0 +1,91 @@
+package routingtable_test
+
+import (
+	"code.cloudfoundry.org/bbs/models"
+	mfakes "code.cloudfoundry.org/diego-logging-client/testhelpers"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Metric tag sources", func() {
+	var (
+		logger     *lagertest.TestLogger
+		table      routingtable.RoutingTable
+		desiredLRP *models.DesiredLRP
+		actualLRP  *models.ActualLRP
+	)
+
+	tag := models.ModificationTag{Epoch: "abc", Index: 1}
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test-route-emitter")
+		table = routingtable.NewRoutingTable(false, &mfakes.FakeIngressClient{}, routingtable.WithMetricTagSources(map[string]routingtable.MetricTagSource{
+			"az":      routingtable.MetricTagSourceAvailabilityZone,
+			"cell":    routingtable.MetricTagSourceCellID,
+			"process": routingtable.MetricTagSourceProcessGUID,
+			"domain":  routingtable.MetricTagSourceDomain,
+			"port":    routingtable.MetricTagSourceContainerPort,
+			"segment": routingtable.MetricTagSourceIsolationSegment,
+			"foo":     routingtable.MetricTagSourceInstanceGUID,
+		}))
+
+		desiredLRP = createDesiredLRP("process-guid", 1, 8080, "log-guid", "", tag, models.DesiredLRPRunInfo{}, "foo.example.com")
+		desiredLRP.MetricTags = map[string]*models.MetricTagValue{"foo": {Static: "bar"}}
+
+		endpoint := routingtable.Endpoint{
+			InstanceGUID:     "ig-1",
+			Index:            0,
+			Host:             "1.1.1.1",
+			Port:             61000,
+			ContainerPort:    8080,
+			AvailabilityZone: "z1",
+			ModificationTag:  &tag,
+		}
+		actualLRP = createActualLRP(routingtable.RoutingKey{ProcessGUID: "process-guid", ContainerPort: 8080}, endpoint, "domain")
+		actualLRP.CellId = "cell-1"
+
+		table.SetRoutes(logger, nil, desiredLRP)
+	})
+
+	It("tags the registrations with the values of the sources", func() {
+		_, messages := table.AddEndpoint(logger, actualLRP)
+		Expect(messages.RegistrationMessages).To(HaveLen(1))
+		Expect(messages.RegistrationMessages[0].Tags).To(Equal(map[string]string{
+			"component": "route-emitter",
+			"az":        "z1",
+			"cell":      "cell-1",
+			"process":   "process-guid",
+			"domain":    desiredLRP.Domain,
+			"port":      "8080",
+			"segment":   "",
+			"foo":       "bar",
+		}))
+	})
+
+	It("does not change the metric tags of the desired LRP", func() {
+		Expect(desiredLRP.MetricTags).To(HaveLen(1))
+	})
+
+	Context("when an instance moves to another cell", func() {
+		It("re-registers it with the new tags", func() {
+			table.AddEndpoint(logger, actualLRP)
+
+			moved := *actualLRP
+			moved.CellId = "cell-2"
+			moved.ModificationTag = models.ModificationTag{Epoch: "abc", Index: 2}
+			_, messages := table.AddEndpoint(logger, &moved)
+			Expect(messages.RegistrationMessages).To(HaveLen(1))
+			Expect(messages.RegistrationMessages[0].Tags).To(HaveKeyWithValue("cell", "cell-2"))
+		})
+	})
+
+	Describe("Validate", func() {
+		It("rejects unknown sources", func() {
+			Expect(routingtable.MetricTagSourceCellID.Validate()).To(Succeed())
+			Expect(routingtable.MetricTagSource("cell").Validate()).To(HaveOccurred())
+		})
+	})
+})
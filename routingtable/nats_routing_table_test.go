@@ -298,7 +298,7 @@ var _ = Describe("RoutingTable", func() {
 				tempTable.SetRoutes(logger, nil, desiredLRP)
 				tempTable.AddEndpoint(logger, lrp)
 
-				_, messagesToEmit = table.Swap(logger, tempTable, noFreshDomains)
+				_, messagesToEmit, _ = table.Swap(logger, tempTable, noFreshDomains)
 			})
 
 			It("emits only additive changes", func() {
@@ -329,7 +329,7 @@ var _ = Describe("RoutingTable", func() {
 					tempTable.SetRoutes(logger, nil, desiredLRP)
 					tempTable.AddEndpoint(logger, lrp)
 
-					_, messagesToEmit = table.Swap(logger, tempTable, noFreshDomains)
+					_, messagesToEmit, _ = table.Swap(logger, tempTable, noFreshDomains)
 				})
 
 				It("emits nothing", func() {
@@ -345,7 +345,7 @@ var _ = Describe("RoutingTable", func() {
 					lrp := createActualLRP(key, endpoint1, domain)
 					tempTable.SetRoutes(logger, nil, desiredLRP)
 					tempTable.AddEndpoint(logger, lrp)
-					_, messagesToEmit = table.Swap(logger, tempTable, domains)
+					_, messagesToEmit, _ = table.Swap(logger, tempTable, domains)
 				})
 
 				It("emits unregisters the old route", func() {
@@ -372,7 +372,7 @@ var _ = Describe("RoutingTable", func() {
 					tempTable.AddEndpoint(logger, lrp1)
 					tempTable.AddEndpoint(logger, lrp2)
 
-					_, messagesToEmit = table.Swap(logger, tempTable, domains)
+					_, messagesToEmit, _ = table.Swap(logger, tempTable, domains)
 				})
 
 				It("emits registrations for each pairing", func() {
@@ -416,7 +416,7 @@ var _ = Describe("RoutingTable", func() {
 					desiredLRP = createDesiredLRPWithRoutes(key.ProcessGUID, 3, routes, logGuid, *currentTag, runInfo)
 					tempTable.SetRoutes(logger, nil, desiredLRP)
 
-					_, messagesToEmit = table.Swap(logger, tempTable, domains)
+					_, messagesToEmit, _ = table.Swap(logger, tempTable, domains)
 				})
 
 				It("should not emit a registration", func() {
@@ -430,7 +430,7 @@ var _ = Describe("RoutingTable", func() {
 						tempTable.SetRoutes(logger, nil, desiredLRP)
 						tempTable.AddEndpoint(logger, lrp)
 
-						_, messagesToEmit = table.Swap(logger, tempTable, domains)
+						_, messagesToEmit, _ = table.Swap(logger, tempTable, domains)
 					})
 
 					It("emits registrations for each pairing", func() {
@@ -458,7 +458,7 @@ var _ = Describe("RoutingTable", func() {
 				Context("when the routing key subsequently disappears", func() {
 					BeforeEach(func() {
 						tempTable := routingtable.NewRoutingTable(false, fakeMetronClient)
-						_, messagesToEmit = table.Swap(logger, tempTable, domains)
+						_, messagesToEmit, _ = table.Swap(logger, tempTable, domains)
 					})
 
 					It("emits nothing", func() {
@@ -473,7 +473,7 @@ var _ = Describe("RoutingTable", func() {
 					lrp := createActualLRP(key, endpoint1, domain)
 					tempTable.AddEndpoint(logger, lrp)
 
-					_, messagesToEmit = table.Swap(logger, tempTable, domains)
+					_, messagesToEmit, _ = table.Swap(logger, tempTable, domains)
 				})
 
 				It("should not emit a registration", func() {
@@ -489,7 +489,7 @@ var _ = Describe("RoutingTable", func() {
 						tempTable.SetRoutes(logger, nil, desiredLRP)
 						tempTable.AddEndpoint(logger, lrp)
 
-						_, messagesToEmit = table.Swap(logger, tempTable, domains)
+						_, messagesToEmit, _ = table.Swap(logger, tempTable, domains)
 					})
 
 					It("emits registrations for each pairing", func() {
@@ -516,7 +516,7 @@ var _ = Describe("RoutingTable", func() {
 				Context("when the endpoint subsequently disappears", func() {
 					BeforeEach(func() {
 						tempTable := routingtable.NewRoutingTable(false, fakeMetronClient)
-						_, messagesToEmit = table.Swap(logger, tempTable, domains)
+						_, messagesToEmit, _ = table.Swap(logger, tempTable, domains)
 					})
 
 					It("emits nothing", func() {
@@ -569,7 +569,7 @@ var _ = Describe("RoutingTable", func() {
 					tempTable.SetRoutes(logger, nil, desiredLRP)
 					lrp := createActualLRP(key, endpoint1, domain)
 					tempTable.AddEndpoint(logger, lrp)
-					_, messagesToEmit = table.Swap(logger, tempTable, domains)
+					_, messagesToEmit, _ = table.Swap(logger, tempTable, domains)
 				})
 
 				It("emits all registrations and no unregistration", func() {
@@ -631,7 +631,7 @@ var _ = Describe("RoutingTable", func() {
 					tempTable.SetRoutes(logger, nil, desiredLRP)
 					lrp1 := createActualLRP(key, endpoint1, domain)
 					tempTable.AddEndpoint(logger, lrp1)
-					_, messagesToEmit = table.Swap(logger, tempTable, domains)
+					_, messagesToEmit, _ = table.Swap(logger, tempTable, domains)
 				})
 
 				It("emits all registrations and no unregistration", func() {
@@ -661,7 +661,7 @@ var _ = Describe("RoutingTable", func() {
 				lrp2 := createActualLRP(key, endpoint2, domain)
 				tempTable.AddEndpoint(logger, lrp2)
 
-				_, messagesToEmit = table.Swap(logger, tempTable, domains)
+				_, messagesToEmit, _ = table.Swap(logger, tempTable, domains)
 			})
 
 			It("should not emit an unregistration ", func() {
@@ -722,7 +722,7 @@ var _ = Describe("RoutingTable", func() {
 					lrp2 := createActualLRP(key, endpoint2, domain)
 					tempTable.AddEndpoint(logger, lrp2)
 
-					_, messagesToEmit = table.Swap(logger, tempTable, domains)
+					_, messagesToEmit, _ = table.Swap(logger, tempTable, domains)
 				})
 
 				It("emits nothing", func() {
@@ -741,7 +741,7 @@ var _ = Describe("RoutingTable", func() {
 					lrp2 := createActualLRP(key, endpoint2, domain)
 					tempTable.AddEndpoint(logger, lrp2)
 
-					_, messagesToEmit = table.Swap(logger, tempTable, domains)
+					_, messagesToEmit, _ = table.Swap(logger, tempTable, domains)
 				})
 
 				It("emits only the new route", func() {
@@ -786,7 +786,7 @@ var _ = Describe("RoutingTable", func() {
 					lrp2 := createActualLRP(key, endpoint2, domain)
 					tempTable.AddEndpoint(logger, lrp2)
 
-					_, messagesToEmit = table.Swap(logger, tempTable, domains)
+					_, messagesToEmit, _ = table.Swap(logger, tempTable, domains)
 				})
 
 				It("emits registrations and unregistration", func() {
@@ -821,7 +821,7 @@ var _ = Describe("RoutingTable", func() {
 					lrp3 := createActualLRP(key, endpoint3, domain)
 					tempTable.AddEndpoint(logger, lrp3)
 
-					_, messagesToEmit = table.Swap(logger, tempTable, domains)
+					_, messagesToEmit, _ = table.Swap(logger, tempTable, domains)
 				})
 
 				It("emits only the new registrations and no unregistration", func() {
@@ -860,7 +860,7 @@ var _ = Describe("RoutingTable", func() {
 					evacuating := createActualLRP(key, evacuating1, domain)
 					tempTable.AddEndpoint(logger, evacuating)
 
-					_, messagesToEmit = table.Swap(logger, tempTable, domains)
+					_, messagesToEmit, _ = table.Swap(logger, tempTable, domains)
 				})
 
 				It("emits no unregistration", func() {
@@ -878,7 +878,7 @@ var _ = Describe("RoutingTable", func() {
 						evacuating := createActualLRP(key, evacuating1, domain)
 						tempTable.AddEndpoint(logger, evacuating)
 
-						_, messagesToEmit = table.Swap(logger, tempTable, domains)
+						_, messagesToEmit, _ = table.Swap(logger, tempTable, domains)
 					})
 
 					It("emits no unregistration", func() {
@@ -900,7 +900,7 @@ var _ = Describe("RoutingTable", func() {
 					lrp3 := createActualLRP(key, endpoint3, domain)
 					tempTable.AddEndpoint(logger, lrp3)
 
-					_, messagesToEmit = table.Swap(logger, tempTable, domains)
+					_, messagesToEmit, _ = table.Swap(logger, tempTable, domains)
 				})
 
 				It("emits the relevant registrations and no unregisration", func() {
@@ -967,7 +967,7 @@ var _ = Describe("RoutingTable", func() {
 					lrp2 := createActualLRP(key, endpoint2, domain)
 					tempTable.AddEndpoint(logger, lrp2)
 
-					_, messagesToEmit = table.Swap(logger, tempTable, domains)
+					_, messagesToEmit, _ = table.Swap(logger, tempTable, domains)
 				})
 
 				It("emits the relevant unregistrations", func() {
@@ -1010,7 +1010,7 @@ var _ = Describe("RoutingTable", func() {
 					lrp1 := createActualLRP(key, endpoint1, domain)
 					tempTable.AddEndpoint(logger, lrp1)
 
-					_, messagesToEmit = table.Swap(logger, tempTable, domains)
+					_, messagesToEmit, _ = table.Swap(logger, tempTable, domains)
 				})
 
 				It("emits the relevant unregistrations", func() {
@@ -1044,7 +1044,7 @@ var _ = Describe("RoutingTable", func() {
 					lrp1 := createActualLRP(key, endpoint1, domain)
 					tempTable.AddEndpoint(logger, lrp1)
 
-					_, messagesToEmit = table.Swap(logger, tempTable, domains)
+					_, messagesToEmit, _ = table.Swap(logger, tempTable, domains)
 				})
 
 				It("emits no registrations and the relevant unregisrations", func() {
@@ -1088,7 +1088,7 @@ var _ = Describe("RoutingTable", func() {
 					lrp1 := createActualLRP(key, endpoint1, domain)
 					tempTable.AddEndpoint(logger, lrp1)
 
-					_, messagesToEmit = table.Swap(logger, tempTable, domains)
+					_, messagesToEmit, _ = table.Swap(logger, tempTable, domains)
 				})
 
 				It("emits the relevant registrations and the relevant unregisrations", func() {
@@ -1140,7 +1140,7 @@ var _ = Describe("RoutingTable", func() {
 					lrp3 := createActualLRP(key, endpoint3, domain)
 					tempTable.AddEndpoint(logger, lrp3)
 
-					_, messagesToEmit = table.Swap(logger, tempTable, domains)
+					_, messagesToEmit, _ = table.Swap(logger, tempTable, domains)
 				})
 
 				It("emits the relevant registrations and the relevant unregisrations", func() {
@@ -1186,7 +1186,7 @@ var _ = Describe("RoutingTable", func() {
 				})
 
 				JustBeforeEach(func() {
-					_, messagesToEmit = table.Swap(logger, tempTable, domainSet)
+					_, messagesToEmit, _ = table.Swap(logger, tempTable, domainSet)
 				})
 
 				Context("when the domain is fresh", func() {
@@ -1273,7 +1273,7 @@ var _ = Describe("RoutingTable", func() {
 						tempTable.AddEndpoint(logger, lrp2)
 						// doing another swap to make sure the old table is still good
 						table.Swap(logger, tempTable, domainSet)
-						_, messagesToEmit = table.Swap(logger, tempTable, domainSet)
+						_, messagesToEmit, _ = table.Swap(logger, tempTable, domainSet)
 					})
 
 					It("logs the collision", func() {
@@ -1305,13 +1305,13 @@ var _ = Describe("RoutingTable", func() {
 						tempTable.AddEndpoint(logger, lrp1)
 						lrp2 := createActualLRP(key, endpoint2, domain)
 						tempTable.AddEndpoint(logger, lrp2)
-						_, messagesToEmit = table.Swap(logger, tempTable, domains)
+						_, messagesToEmit, _ = table.Swap(logger, tempTable, domains)
 						Expect(messagesToEmit.InternalUnregistrationMessages).To(HaveLen(2))
 
 						tempTable = routingtable.NewRoutingTable(false, fakeMetronClient)
 						lrp1 = createActualLRP(key, endpoint1, domain)
 						tempTable.AddEndpoint(logger, lrp1)
-						_, messagesToEmit = table.Swap(logger, tempTable, domains)
+						_, messagesToEmit, _ = table.Swap(logger, tempTable, domains)
 					})
 
 					It("emits nothing", func() {
@@ -1330,7 +1330,7 @@ var _ = Describe("RoutingTable", func() {
 						tempTable = routingtable.NewRoutingTable(false, fakeMetronClient)
 						desiredLRP = createDesiredLRP(key.ProcessGUID, int32(3), key.ContainerPort, logGuid, "", *currentTag, models.DesiredLRPRunInfo{}, hostname1)
 						tempTable.SetRoutes(logger, nil, desiredLRP)
-						_, messagesToEmit = table.Swap(logger, tempTable, domains)
+						_, messagesToEmit, _ = table.Swap(logger, tempTable, domains)
 					})
 
 					It("emits nothing", func() {
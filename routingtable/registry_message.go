@@ -1,6 +1,7 @@
 package routingtable
 
 import (
+	"encoding/json"
 	"fmt"
 	"strconv"
 
@@ -22,6 +23,7 @@ type RegistryMessage struct {
 	EndpointUpdatedAtNs  int64             `json:"endpoint_updated_at_ns,omitempty" hash:"ignore"`
 	Tags                 map[string]string `json:"tags,omitempty" hash:"ignore"`
 	AvailabilityZone     string            `json:"availability_zone,omitempty" hash:"ignore"`
+	Options              *RouteOptions     `json:"options,omitempty"`
 }
 
 func RegistryMessageFor(endpoint Endpoint, route Route, emitEndpointUpdatedAt bool) RegistryMessage {
@@ -42,7 +44,7 @@ func RegistryMessageFor(endpoint Endpoint, route Route, emitEndpointUpdatedAt bo
 		Protocol:            route.Protocol,
 		App:                 route.LogGUID,
 		IsolationSegment:    route.IsolationSegment,
-		Tags:                populateMetricTags(route.MetricTags, endpoint),
+		Tags:                populateMetricTags(route, endpoint),
 		AvailabilityZone:    endpoint.AvailabilityZone,
 		EndpointUpdatedAtNs: since,
 
@@ -50,6 +52,7 @@ func RegistryMessageFor(endpoint Endpoint, route Route, emitEndpointUpdatedAt bo
 		PrivateInstanceIndex: index,
 		ServerCertDomainSAN:  endpoint.InstanceGUID,
 		RouteServiceUrl:      route.RouteServiceUrl,
+		Options:              route.Options,
 	}
 }
 
@@ -71,7 +74,7 @@ func InternalAddressRegistryMessageFor(endpoint Endpoint, route Route, emitEndpo
 		Protocol:         route.Protocol,
 		App:              route.LogGUID,
 		IsolationSegment: route.IsolationSegment,
-		Tags:             populateMetricTags(route.MetricTags, endpoint),
+		Tags:             populateMetricTags(route, endpoint),
 		AvailabilityZone: endpoint.AvailabilityZone,
 
 		ServerCertDomainSAN:  endpoint.InstanceGUID,
@@ -79,6 +82,7 @@ func InternalAddressRegistryMessageFor(endpoint Endpoint, route Route, emitEndpo
 		PrivateInstanceIndex: index,
 		EndpointUpdatedAtNs:  since,
 		RouteServiceUrl:      route.RouteServiceUrl,
+		Options:              route.Options,
 	}
 }
 
@@ -93,8 +97,10 @@ func InternalEndpointRegistryMessageFor(endpoint Endpoint, route InternalRoute,
 		since = 0
 	}
 	return RegistryMessage{
-		URIs:                []string{route.Hostname, fmt.Sprintf("%s.%s", index, route.Hostname)},
+		URIs:                append([]string{route.Hostname}, instanceHostnames(endpoint, route)...),
 		Host:                endpoint.ContainerIP,
+		Port:                endpoint.ContainerPort,
+		TlsPort:             endpoint.ContainerTlsProxyPort,
 		App:                 route.LogGUID,
 		Tags:                map[string]string{"component": "route-emitter"},
 		AvailabilityZone:    endpoint.AvailabilityZone,
@@ -109,9 +115,9 @@ type ExternalServiceGreetingMessage struct {
 	PruneThresholdInSeconds int `json:"pruneThresholdInSeconds"`
 }
 
-func populateMetricTags(input map[string]*models.MetricTagValue, endpoint Endpoint) map[string]string {
+func populateMetricTags(route Route, endpoint Endpoint) map[string]string {
 	tags := map[string]string{}
-	if input != nil {
+	if input := route.MetricTags; input != nil {
 		for k, v := range input {
 			var value string
 			if v.Dynamic > 0 {
@@ -127,6 +133,71 @@ func populateMetricTags(input map[string]*models.MetricTagValue, endpoint Endpoi
 			tags[k] = value
 		}
 	}
+	for k, source := range route.MetricTagSources {
+		tags[k] = source.endpointValue(endpoint)
+	}
 	tags["component"] = "route-emitter"
 	return tags
 }
+
+// AggregateRegistryMessages merges messages that differ only in their URIs
+// into a single message carrying all of them, preserving the order in which
+// messages and URIs first appear.
+func AggregateRegistryMessages(messages []RegistryMessage) []RegistryMessage {
+	if len(messages) < 2 {
+		return messages
+	}
+
+	aggregated := []RegistryMessage{}
+	positions := map[string]int{}
+	for _, message := range messages {
+		key := message.aggregationKey()
+		position, ok := positions[key]
+		if !ok {
+			message.URIs = append([]string(nil), message.URIs...)
+			positions[key] = len(aggregated)
+			aggregated = append(aggregated, message)
+			continue
+		}
+
+		existing := &aggregated[position]
+		for _, uri := range message.URIs {
+			if !containsString(existing.URIs, uri) {
+				existing.URIs = append(existing.URIs, uri)
+			}
+		}
+	}
+
+	return aggregated
+}
+
+// SplitRegistryMessage returns one copy of the message per URI.
+func SplitRegistryMessage(message RegistryMessage) []RegistryMessage {
+	if len(message.URIs) < 2 {
+		return []RegistryMessage{message}
+	}
+
+	messages := make([]RegistryMessage, 0, len(message.URIs))
+	for _, uri := range message.URIs {
+		single := message
+		single.URIs = []string{uri}
+		messages = append(messages, single)
+	}
+	return messages
+}
+
+func (message RegistryMessage) aggregationKey() string {
+	message.URIs = nil
+	// json encodes map keys in sorted order, so equal tags give equal keys
+	key, _ := json.Marshal(message)
+	return string(key)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
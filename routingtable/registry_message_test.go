@@ -92,6 +92,37 @@ var _ = Describe("RegistryMessage", func() {
 			})
 		})
 
+		Context("when route options are set", func() {
+			BeforeEach(func() {
+				expectedMessage.Options = &routingtable.RouteOptions{
+					LoadBalancingAlgorithm: "hash",
+					HashHeaderName:         "X-Tenant",
+					HashBalance:            "1.25",
+				}
+
+				expectedJSON = `{
+				"host": "1.1.1.1",
+				"port": 61001,
+				"uris": ["host-1.example.com"],
+				"app" : "app-guid",
+				"private_instance_id": "instance-guid",
+				"private_instance_index": "0",
+				"server_cert_domain_san": "instance-guid",
+				"route_service_url": "https://hello.com",
+				"endpoint_updated_at_ns": 1000,
+				"tags": {"component":"route-emitter", "doo": "0", "foo": "bar", "goo": "instance-guid"},
+				"availability_zone": "some-zone",
+				"options": {"loadbalancing": "hash", "hash_header": "X-Tenant", "hash_balance": "1.25"}
+			}`
+			})
+
+			It("marshals the options", func() {
+				payload, err := json.Marshal(expectedMessage)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(payload).To(MatchJSON(expectedJSON))
+			})
+		})
+
 		Context("when protocol is set", func() {
 			BeforeEach(func() {
 				expectedMessage.Protocol = "http2"
@@ -182,6 +213,35 @@ var _ = Describe("RegistryMessage", func() {
 				Expect(message).To(Equal(expectedMessage))
 			})
 		})
+
+		Context("when the route has options", func() {
+			BeforeEach(func() {
+				route.Options = &routingtable.RouteOptions{LoadBalancingAlgorithm: "least-connection"}
+				expectedMessage.Options = route.Options
+			})
+
+			It("carries the options into the message", func() {
+				message := routingtable.RegistryMessageFor(endpoint, route, true)
+				Expect(message).To(Equal(expectedMessage))
+			})
+		})
+
+		Context("when the route has metric tag sources", func() {
+			BeforeEach(func() {
+				endpoint.CellID = "cell-1"
+				route.MetricTagSources = map[string]routingtable.MetricTagSource{
+					"az":   routingtable.MetricTagSourceAvailabilityZone,
+					"cell": routingtable.MetricTagSourceCellID,
+				}
+				expectedMessage.Tags["az"] = "some-zone"
+				expectedMessage.Tags["cell"] = "cell-1"
+			})
+
+			It("resolves the tags from the endpoint", func() {
+				message := routingtable.RegistryMessageFor(endpoint, route, true)
+				Expect(message).To(Equal(expectedMessage))
+			})
+		})
 	})
 
 	Describe("InternalAddressRegistryMessageFor", func() {
@@ -261,6 +321,7 @@ var _ = Describe("RegistryMessage", func() {
 		BeforeEach(func() {
 			expectedMessage = routingtable.RegistryMessage{
 				Host:                 "1.2.3.4",
+				Port:                 11,
 				URIs:                 []string{"host-1.example.com", "0.host-1.example.com"},
 				App:                  "app-guid",
 				Tags:                 map[string]string{"component": "route-emitter"},
@@ -298,4 +359,38 @@ var _ = Describe("RegistryMessage", func() {
 			Expect(message).To(Equal(expectedMessage))
 		})
 	})
+
+	Describe("AggregateRegistryMessages", func() {
+		It("merges the URIs of messages that are otherwise identical", func() {
+			other := expectedMessage
+			other.URIs = []string{"host-2.example.com"}
+			differentPort := expectedMessage
+			differentPort.Port = 61002
+
+			aggregated := routingtable.AggregateRegistryMessages([]routingtable.RegistryMessage{expectedMessage, other, differentPort, other})
+			Expect(aggregated).To(HaveLen(2))
+			Expect(aggregated[0].URIs).To(Equal([]string{"host-1.example.com", "host-2.example.com"}))
+			Expect(aggregated[1]).To(Equal(differentPort))
+			Expect(expectedMessage.URIs).To(Equal([]string{"host-1.example.com"}))
+		})
+
+		It("does not merge messages with different tags", func() {
+			other := expectedMessage
+			other.URIs = []string{"host-2.example.com"}
+			other.Tags = map[string]string{"component": "route-emitter"}
+
+			Expect(routingtable.AggregateRegistryMessages([]routingtable.RegistryMessage{expectedMessage, other})).To(HaveLen(2))
+		})
+	})
+
+	Describe("SplitRegistryMessage", func() {
+		It("returns one message per URI", func() {
+			expectedMessage.URIs = []string{"host-1.example.com", "host-2.example.com"}
+			split := routingtable.SplitRegistryMessage(expectedMessage)
+			Expect(split).To(HaveLen(2))
+			Expect(split[0].URIs).To(Equal([]string{"host-1.example.com"}))
+			Expect(split[1].URIs).To(Equal([]string{"host-2.example.com"}))
+			Expect(split[1].Host).To(Equal(expectedMessage.Host))
+		})
+	})
 })
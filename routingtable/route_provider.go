@@ -0,0 +1,75 @@
+package routingtable
+
+import (
+	"code.cloudfoundry.org/bbs/models"
+)
+
+const (
+	HTTPRouteProvider     = "http"
+	TCPRouteProvider      = "tcp"
+	InternalRouteProvider = "internal"
+)
+
+// RouteOutput selects which of GetExternalRoutingEvents and
+// GetInternalRoutingEvents report the routes of a provider.
+type RouteOutput int
+
+const (
+	ExternalRouteOutput RouteOutput = iota
+	InternalRouteOutput
+)
+
+// RouteProvider describes one kind of route held by the routing table. Every
+// provider gets its own sub-table, and the routing table fans all modifications
+// out to each registered provider in registration order.
+//
+// Only the built-in providers are persisted in snapshots, indexed and listed by
+// the introspection API.
+//
+// The sub-table of a provider that detects address collisions applies the
+// collision policy to its endpoints. Only providers that also report them log,
+// count and list the collisions, so that providers generating the same
+// endpoints do not report every collision more than once.
+type RouteProvider struct {
+	Name                    string
+	RoutesGenerator         func(*models.DesiredLRP) map[RoutingKey][]RouteMapping
+	EndpointGenerator       func(*models.ActualLRP) []Endpoint
+	Output                  RouteOutput
+	DetectAddressCollisions bool
+	ReportAddressCollisions bool
+}
+
+// DefaultRouteProviders returns the http, tcp and internal route providers
+// that every routing table starts with.
+func DefaultRouteProviders() []RouteProvider {
+	return []RouteProvider{
+		{
+			Name:                    HTTPRouteProvider,
+			RoutesGenerator:         httpRoutesFrom,
+			EndpointGenerator:       NewEndpointsFromActual,
+			Output:                  ExternalRouteOutput,
+			DetectAddressCollisions: true,
+			ReportAddressCollisions: true,
+		},
+		{
+			// TCP routes have the same endpoints as HTTP routes, whose
+			// provider reports the collisions
+			Name:                    TCPRouteProvider,
+			RoutesGenerator:         tcpRoutesFrom,
+			EndpointGenerator:       NewEndpointsFromActual,
+			Output:                  ExternalRouteOutput,
+			DetectAddressCollisions: true,
+		},
+		{
+			Name:              InternalRouteProvider,
+			RoutesGenerator:   internalRoutesFrom,
+			EndpointGenerator: internalEndpointsFromActualLRP,
+			Output:            InternalRouteOutput,
+		},
+	}
+}
+
+type providerTable struct {
+	provider RouteProvider
+	table    *internalRoutingTable
+}
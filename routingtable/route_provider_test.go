@@ -0,0 +1,120 @@
+package routingtable_test
+
+import (
+	"code.cloudfoundry.org/bbs/models"
+	mfakes "code.cloudfoundry.org/diego-logging-client/testhelpers"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RouteProviders", func() {
+	var (
+		table            routingtable.RoutingTable
+		logger           *lagertest.TestLogger
+		fakeMetronClient *mfakes.FakeIngressClient
+		customProvider   routingtable.RouteProvider
+		desiredLRP       *models.DesiredLRP
+		actualLRP        *models.ActualLRP
+		key              routingtable.RoutingKey
+	)
+
+	tag := models.ModificationTag{Epoch: "abc", Index: 1}
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test-route-emitter")
+		fakeMetronClient = &mfakes.FakeIngressClient{}
+		table = routingtable.NewRoutingTable(false, fakeMetronClient)
+
+		key = routingtable.RoutingKey{ProcessGUID: "process-guid-1", ContainerPort: 8080}
+		customProvider = routingtable.RouteProvider{
+			Name: "custom",
+			RoutesGenerator: func(lrp *models.DesiredLRP) map[routingtable.RoutingKey][]routingtable.RouteMapping {
+				if lrp == nil {
+					return nil
+				}
+				return map[routingtable.RoutingKey][]routingtable.RouteMapping{
+					{ProcessGUID: lrp.ProcessGuid, ContainerPort: 8080}: {
+						routingtable.Route{Hostname: lrp.ProcessGuid + ".custom.example.com", LogGUID: lrp.LogGuid},
+					},
+				}
+			},
+			EndpointGenerator: routingtable.NewEndpointsFromActual,
+			Output:            routingtable.ExternalRouteOutput,
+		}
+
+		desiredLRP = createDesiredLRPWithRoutes("process-guid-1", 1, createRoutingInfo(8080, nil, nil, "", nil, ""), "log-guid", tag, models.DesiredLRPRunInfo{})
+		endpoint := routingtable.Endpoint{
+			InstanceGUID:    "ig-1",
+			Host:            "1.1.1.1",
+			ContainerIP:     "1.2.3.4",
+			Port:            11,
+			ContainerPort:   8080,
+			ModificationTag: &tag,
+		}
+		actualLRP = createActualLRP(key, endpoint, "domain")
+	})
+
+	It("starts with the built-in providers", func() {
+		names := []string{}
+		for _, provider := range table.RouteProviders() {
+			names = append(names, provider.Name)
+		}
+		Expect(names).To(Equal([]string{
+			routingtable.HTTPRouteProvider,
+			routingtable.TCPRouteProvider,
+			routingtable.InternalRouteProvider,
+		}))
+	})
+
+	Context("when a custom provider is registered", func() {
+		BeforeEach(func() {
+			table.RegisterRouteProvider(customProvider)
+		})
+
+		It("emits registrations for the routes of the provider", func() {
+			table.SetRoutes(logger, nil, desiredLRP)
+			_, messages := table.AddEndpoint(logger, actualLRP)
+			Expect(messages.RegistrationMessages).To(HaveLen(1))
+			Expect(messages.RegistrationMessages[0].URIs).To(ConsistOf("process-guid-1.custom.example.com"))
+			Expect(table.HTTPAssociationsCount()).To(Equal(0))
+		})
+
+		It("reports the routes of external providers as external routing events", func() {
+			table.SetRoutes(logger, nil, desiredLRP)
+			table.AddEndpoint(logger, actualLRP)
+
+			_, messages := table.GetExternalRoutingEvents()
+			Expect(messages.RegistrationMessages).To(HaveLen(1))
+			_, messages = table.GetInternalRoutingEvents()
+			Expect(messages.InternalRegistrationMessages).To(BeEmpty())
+			Expect(table.HasExternalRoutes(actualLRP)).To(BeTrue())
+		})
+
+		It("unregisters its routes when swapped with a table without the provider", func() {
+			table.SetRoutes(logger, nil, desiredLRP)
+			table.AddEndpoint(logger, actualLRP)
+
+			tempTable := routingtable.NewRoutingTable(false, fakeMetronClient)
+			tempTable.SetRoutes(logger, nil, desiredLRP)
+			tempTable.AddEndpoint(logger, actualLRP)
+
+			_, messages, _ := table.Swap(logger, tempTable, models.NewDomainSet([]string{"domain"}))
+			Expect(messages.UnregistrationMessages).To(HaveLen(1))
+			Expect(messages.UnregistrationMessages[0].URIs).To(ConsistOf("process-guid-1.custom.example.com"))
+		})
+
+		Context("when a provider with the same name is registered again", func() {
+			It("replaces the provider", func() {
+				customProvider.Output = routingtable.InternalRouteOutput
+				table.RegisterRouteProvider(customProvider)
+
+				providers := table.RouteProviders()
+				Expect(providers).To(HaveLen(4))
+				Expect(providers[3].Output).To(Equal(routingtable.InternalRouteOutput))
+			})
+		})
+	})
+})
@@ -2,14 +2,13 @@ package routingtable
 
 import (
 	"sync"
+	"time"
 
 	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/clock"
 	loggingclient "code.cloudfoundry.org/diego-logging-client"
 	"code.cloudfoundry.org/lager/v3"
 	tcpmodels "code.cloudfoundry.org/routing-api/models"
-	"code.cloudfoundry.org/routing-info/cfroutes"
-	"code.cloudfoundry.org/routing-info/internalroutes"
-	"code.cloudfoundry.org/routing-info/tcp_routes"
 )
 
 type TCPRouteMappings struct {
@@ -33,7 +32,9 @@ type RoutingTable interface {
 	RemoveRoutes(logger lager.Logger, desiredLRP *models.DesiredLRP) (TCPRouteMappings, MessagesToEmit)
 	AddEndpoint(logger lager.Logger, actualLRP *models.ActualLRP) (TCPRouteMappings, MessagesToEmit)
 	RemoveEndpoint(logger lager.Logger, actualLRP *models.ActualLRP) (TCPRouteMappings, MessagesToEmit)
-	Swap(logger lager.Logger, t RoutingTable, domains models.DomainSet) (TCPRouteMappings, MessagesToEmit)
+	Swap(logger lager.Logger, t RoutingTable, domains models.DomainSet) (TCPRouteMappings, MessagesToEmit, SyncReport)
+	// PreviewSwap returns what Swap would emit without changing the table.
+	PreviewSwap(t RoutingTable, domains models.DomainSet) (TCPRouteMappings, MessagesToEmit)
 	GetInternalRoutingEvents() (TCPRouteMappings, MessagesToEmit)
 	GetExternalRoutingEvents() (TCPRouteMappings, MessagesToEmit)
 
@@ -44,27 +45,119 @@ type RoutingTable interface {
 	InternalAssociationsCount() int // return number of associations desired-lrp-internal-routes * 2 * actual-lrps
 	TCPAssociationsCount() int      // return number of associations desired-lrp-tcp-routes * actual-lrps
 	TableSize() int
+	AddressCollisions() []AddressCollision
+
+	// queries
+
+	RoutingKeysForHostname(hostname string) RoutingKeys
+	RoutingKeysForInstance(instanceGUID string) RoutingKeys
+	RoutingKeysForRouterGroupPort(routerGroupGUID string, port uint32) RoutingKeys
+	SnapshotForRoutingKeys(keys RoutingKeys) Snapshot
+
+	// persistence
+
+	Snapshot() Snapshot
+	Restore(logger lager.Logger, snapshot Snapshot)
+
+	// providers
+
+	RegisterRouteProvider(provider RouteProvider)
+	RouteProviders() []RouteProvider
 }
 
 type internalRoutingTable struct {
 	endpointGenerator        func(*models.ActualLRP) []Endpoint
-	routesGenerator          func(*models.DesiredLRP) map[RoutingKey][]routeMapping
-	entries                  map[RoutingKey]RoutableEndpoints
-	addressEntries           map[Address]EndpointKey
+	routesGenerator          func(*models.DesiredLRP) map[RoutingKey][]RouteMapping
+	shards                   []*tableShard
+	collisions               *addressCollisions
 	addressGenerator         func(endpoint Endpoint) Address
 	directInstanceRoute      bool
 	metronClient             loggingclient.IngressClient
 	suppressAddressCollision bool
-	sync.Locker
+	reportAddressCollision   bool
+	aggregateHostnames       bool
 }
 
 type routingTable struct {
-	tcpRoutesRoutingTable      *internalRoutingTable
-	httpRoutesRoutingTable     *internalRoutingTable
-	internalRoutesRoutingTable *internalRoutingTable
+	directInstanceRoute bool
+	metronClient        loggingclient.IngressClient
+	addressGenerator    func(endpoint Endpoint) Address
+	aggregateHostnames  bool
+	shardCount          int
+	collisionPolicy     AddressCollisionPolicy
+	tcpRoutesToTLSProxy bool
+	hostnameTemplates   []string
+	metricTagSources    map[string]MetricTagSource
+	clock               clock.Clock
+
+	providersLock sync.RWMutex
+	providers     []providerTable
+
+	syncedAtLock sync.Mutex
+	syncedAt     time.Time
+}
+
+// Option configures optional behaviour of a routing table.
+type Option func(*routingTable)
+
+// WithHostnameAggregation makes the table emit a single registry message
+// carrying every hostname of an endpoint whose routes otherwise produce
+// identical messages, instead of one message per hostname.
+func WithHostnameAggregation() Option {
+	return func(t *routingTable) {
+		t.aggregateHostnames = true
+	}
+}
+
+// WithShardCount sets the number of shards each sub-table is partitioned into.
+// Updates to LRPs in different shards can be applied concurrently.
+func WithShardCount(count int) Option {
+	return func(t *routingTable) {
+		t.shardCount = count
+	}
+}
+
+// WithTCPRoutesToTLSProxy makes every TCP route target the TLS proxy port of
+// the instances that have one, rather than only the routes that ask for it.
+func WithTCPRoutesToTLSProxy() Option {
+	return func(t *routingTable) {
+		t.tcpRoutesToTLSProxy = true
+	}
+}
+
+// WithInstanceHostnameTemplates sets the templates the per-instance aliases
+// of internal routes are rendered from, replacing the default
+// DefaultInstanceHostnameTemplate.
+func WithInstanceHostnameTemplates(templates []string) Option {
+	return func(t *routingTable) {
+		t.hostnameTemplates = templates
+	}
+}
+
+// WithMetricTagSources adds a metric tag to the registrations of every HTTP
+// route for each of the named sources.
+func WithMetricTagSources(sources map[string]MetricTagSource) Option {
+	return func(t *routingTable) {
+		t.metricTagSources = sources
+	}
 }
 
-func NewRoutingTable(directInstanceRoute bool, metronClient loggingclient.IngressClient) RoutingTable {
+// WithAddressCollisionPolicy sets how endpoints of different instances that
+// share an address are registered. The default only reports collisions.
+func WithAddressCollisionPolicy(policy AddressCollisionPolicy) Option {
+	return func(t *routingTable) {
+		t.collisionPolicy = policy
+	}
+}
+
+// WithClock sets the clock the table records the time of its last sync with.
+func WithClock(clock clock.Clock) Option {
+	return func(t *routingTable) {
+		t.clock = clock
+	}
+}
+
+func NewRoutingTable(directInstanceRoute bool, metronClient loggingclient.IngressClient, options ...Option) RoutingTable {
 	addressGenerator := func(endpoint Endpoint) Address {
 		if endpoint.IsDirectInstanceRoute(directInstanceRoute) {
 			return Address{Host: endpoint.ContainerIP, Port: endpoint.ContainerPort}
@@ -72,69 +165,147 @@ func NewRoutingTable(directInstanceRoute bool, metronClient loggingclient.Ingres
 		return Address{Host: endpoint.Host, Port: endpoint.Port}
 	}
 
-	httpRoutingTable := &internalRoutingTable{
-		endpointGenerator:   NewEndpointsFromActual,
-		routesGenerator:     httpRoutesFrom,
-		entries:             make(map[RoutingKey]RoutableEndpoints),
-		addressEntries:      make(map[Address]EndpointKey),
+	table := &routingTable{
 		directInstanceRoute: directInstanceRoute,
-		addressGenerator:    addressGenerator,
 		metronClient:        metronClient,
-		Locker:              &sync.Mutex{},
-	}
-	tcpRoutingTable := &internalRoutingTable{
-		endpointGenerator:        NewEndpointsFromActual,
-		routesGenerator:          tcpRoutesFrom,
-		entries:                  make(map[RoutingKey]RoutableEndpoints),
-		addressEntries:           make(map[Address]EndpointKey),
-		directInstanceRoute:      directInstanceRoute,
-		addressGenerator:         addressGenerator,
-		metronClient:             metronClient,
-		suppressAddressCollision: true,
-		Locker:                   &sync.Mutex{},
-	}
-	internalRoutingTable := &internalRoutingTable{
-		endpointGenerator:        internalEndpointsFromActualLRP,
-		routesGenerator:          internalRoutesFrom,
-		entries:                  make(map[RoutingKey]RoutableEndpoints),
-		addressEntries:           make(map[Address]EndpointKey),
-		directInstanceRoute:      directInstanceRoute,
-		addressGenerator:         addressGenerator,
-		metronClient:             metronClient,
-		suppressAddressCollision: true,
-		Locker:                   &sync.Mutex{},
-	}
-
-	return &routingTable{
-		tcpRoutesRoutingTable:      tcpRoutingTable,
-		httpRoutesRoutingTable:     httpRoutingTable,
-		internalRoutesRoutingTable: internalRoutingTable,
+		addressGenerator:    addressGenerator,
+		shardCount:          DefaultShardCount,
+		clock:               clock.NewClock(),
 	}
+	for _, option := range options {
+		option(table)
+	}
+	for _, provider := range DefaultRouteProviders() {
+		if provider.Name == TCPRouteProvider && table.tcpRoutesToTLSProxy {
+			provider.RoutesGenerator = tlsProxyTCPRoutesFrom
+		}
+		if provider.Name == HTTPRouteProvider && len(table.metricTagSources) > 0 {
+			provider.RoutesGenerator = metricTagRoutesFrom(table.metricTagSources)
+		}
+		if provider.Name == InternalRouteProvider && len(table.hostnameTemplates) > 0 {
+			provider.RoutesGenerator = templatedInternalRoutesFrom(table.hostnameTemplates)
+		}
+		table.RegisterRouteProvider(provider)
+	}
+
+	return table
 }
 
+func (t *routingTable) newInternalRoutingTable(provider RouteProvider) *internalRoutingTable {
+	if provider.RoutesGenerator == nil {
+		provider.RoutesGenerator = func(*models.DesiredLRP) map[RoutingKey][]RouteMapping { return nil }
+	}
+	if provider.EndpointGenerator == nil {
+		provider.EndpointGenerator = func(*models.ActualLRP) []Endpoint { return nil }
+	}
+
+	return &internalRoutingTable{
+		endpointGenerator:        provider.EndpointGenerator,
+		routesGenerator:          provider.RoutesGenerator,
+		shards:                   newTableShards(t.shardCount),
+		collisions:               newAddressCollisions(t.collisionPolicy),
+		directInstanceRoute:      t.directInstanceRoute,
+		addressGenerator:         t.addressGenerator,
+		metronClient:             t.metronClient,
+		suppressAddressCollision: !provider.DetectAddressCollisions,
+		reportAddressCollision:   provider.ReportAddressCollisions,
+		aggregateHostnames:       t.aggregateHostnames,
+	}
+}
+
+// RegisterRouteProvider adds a provider with an empty sub-table. A provider
+// with the same name as an existing one replaces it and discards its entries.
+func (t *routingTable) RegisterRouteProvider(provider RouteProvider) {
+	t.providersLock.Lock()
+	defer t.providersLock.Unlock()
+
+	registered := providerTable{provider: provider, table: t.newInternalRoutingTable(provider)}
+	for i := range t.providers {
+		if t.providers[i].provider.Name == provider.Name {
+			t.providers[i] = registered
+			return
+		}
+	}
+	t.providers = append(t.providers, registered)
+}
+
+func (t *routingTable) RouteProviders() []RouteProvider {
+	t.providersLock.RLock()
+	defer t.providersLock.RUnlock()
+
+	providers := make([]RouteProvider, 0, len(t.providers))
+	for _, p := range t.providers {
+		providers = append(providers, p.provider)
+	}
+	return providers
+}
+
+func (t *routingTable) providerTables() []providerTable {
+	t.providersLock.RLock()
+	defer t.providersLock.RUnlock()
+
+	return append([]providerTable(nil), t.providers...)
+}
+
+// providerTable returns the sub-table of the named provider, or an empty
+// table if no such provider is registered.
+func (t *routingTable) providerTable(name string) *internalRoutingTable {
+	for _, p := range t.providerTables() {
+		if p.provider.Name == name {
+			return p.table
+		}
+	}
+	return t.newInternalRoutingTable(RouteProvider{Name: name})
+}
+
+func (t *routingTable) fanOut(update func(*internalRoutingTable) (TCPRouteMappings, MessagesToEmit, bool)) (TCPRouteMappings, MessagesToEmit, bool) {
+	var mappings TCPRouteMappings
+	var messages MessagesToEmit
+	changed := false
+	for _, p := range t.providerTables() {
+		providerMappings, providerMessages, providerChanged := update(p.table)
+		mappings = mappings.Merge(providerMappings)
+		messages = messages.Merge(providerMessages)
+		changed = changed || providerChanged
+	}
+	return mappings, messages, changed
+}
+
+// internalEndpointsFromActualLRP returns an endpoint without a port for
+// internal routes without a port, and one endpoint per container port for
+// internal routes with one.
 func internalEndpointsFromActualLRP(actualLRP *models.ActualLRP) []Endpoint {
-	return []Endpoint{
+	endpoints := []Endpoint{
 		{
-			InstanceGUID:    actualLRP.InstanceGuid,
-			Index:           actualLRP.Index,
-			Host:            actualLRP.Address,
-			ContainerIP:     actualLRP.InstanceAddress,
-			Presence:        actualLRP.Presence,
-			Since:           actualLRP.Since,
-			ModificationTag: &actualLRP.ModificationTag,
+			InstanceGUID:     actualLRP.InstanceGuid,
+			Index:            actualLRP.Index,
+			Host:             actualLRP.Address,
+			ContainerIP:      actualLRP.InstanceAddress,
+			Presence:         actualLRP.Presence,
+			Since:            actualLRP.Since,
+			AvailabilityZone: actualLRP.AvailabilityZone,
+			ModificationTag:  &actualLRP.ModificationTag,
 		},
 	}
+
+	for _, portMapping := range actualLRP.Ports {
+		if portMapping == nil || portMapping.ContainerPort == 0 {
+			continue
+		}
+		endpoint := endpoints[0]
+		endpoint.ContainerPort = portMapping.ContainerPort
+		endpoint.ContainerTlsProxyPort = portMapping.ContainerTlsProxyPort
+		endpoints = append(endpoints, endpoint)
+	}
+
+	return endpoints
 }
 
 func (table *routingTable) AddEndpoint(logger lager.Logger, actualLRP *models.ActualLRP) (TCPRouteMappings, MessagesToEmit) {
-	httpMappings, httpMessages, httpChanged := table.httpRoutesRoutingTable.AddEndpoint(logger, actualLRP)
-	tcpMappings, tcpMessages, tcpChanged := table.tcpRoutesRoutingTable.AddEndpoint(logger, actualLRP)
-	internalMappings, internalMessages, internalChanged := table.internalRoutesRoutingTable.AddEndpoint(logger, actualLRP)
-
-	mappings := httpMappings.Merge(tcpMappings).Merge(internalMappings)
-	messages := httpMessages.Merge(tcpMessages).Merge(internalMessages)
+	mappings, messages, changed := table.fanOut(func(t *internalRoutingTable) (TCPRouteMappings, MessagesToEmit, bool) {
+		return t.AddEndpoint(logger, actualLRP)
+	})
 
-	changed := httpChanged || tcpChanged || internalChanged
 	if changed {
 		logger.Info("added", ActualLRPData(actualLRP))
 	}
@@ -143,14 +314,10 @@ func (table *routingTable) AddEndpoint(logger lager.Logger, actualLRP *models.Ac
 }
 
 func (table *routingTable) RemoveEndpoint(logger lager.Logger, actualLRP *models.ActualLRP) (TCPRouteMappings, MessagesToEmit) {
-	httpMappings, httpMessages, httpChanged := table.httpRoutesRoutingTable.RemoveEndpoint(logger, actualLRP)
-	tcpMappings, tcpMessages, tcpChanged := table.tcpRoutesRoutingTable.RemoveEndpoint(logger, actualLRP)
-	internalMappings, internalMessages, internalChanged := table.internalRoutesRoutingTable.RemoveEndpoint(logger, actualLRP)
-
-	mappings := httpMappings.Merge(tcpMappings).Merge(internalMappings)
-	messages := httpMessages.Merge(tcpMessages).Merge(internalMessages)
+	mappings, messages, changed := table.fanOut(func(t *internalRoutingTable) (TCPRouteMappings, MessagesToEmit, bool) {
+		return t.RemoveEndpoint(logger, actualLRP)
+	})
 
-	changed := httpChanged || tcpChanged || internalChanged
 	if changed {
 		logger.Info("removed", ActualLRPData(actualLRP))
 	}
@@ -158,47 +325,76 @@ func (table *routingTable) RemoveEndpoint(logger lager.Logger, actualLRP *models
 	return mappings, messages
 }
 
-func (t *routingTable) Swap(logger lager.Logger, other RoutingTable, domains models.DomainSet) (TCPRouteMappings, MessagesToEmit) {
+// Swap pairs the sub-tables of both routing tables by provider name. A
+// provider missing from the other table is swapped with an empty table, which
+// unregisters all of its routes.
+func (t *routingTable) Swap(logger lager.Logger, other RoutingTable, domains models.DomainSet) (TCPRouteMappings, MessagesToEmit, SyncReport) {
 	table, ok := other.(*routingTable)
 	if !ok {
 		logger.Error("failed-to-convert-to-routing-table", nil)
-		return TCPRouteMappings{}, MessagesToEmit{}
+		return TCPRouteMappings{}, MessagesToEmit{}, newSyncReport(nil)
 	}
 	logger = logger.Session("swap")
 	logger.Info("starting", lager.Data{"domains": domains})
 	defer logger.Info("finished")
 
-	httpMappings, httpMessages := t.httpRoutesRoutingTable.Swap(table.httpRoutesRoutingTable, domains)
-	tcpMappings, tcpMessages := t.tcpRoutesRoutingTable.Swap(table.tcpRoutesRoutingTable, domains)
-	internalMappings, internalMessages := t.internalRoutesRoutingTable.Swap(table.internalRoutesRoutingTable, domains)
+	var mappings TCPRouteMappings
+	var messages MessagesToEmit
+	tallies := map[string]syncTally{}
+	for _, p := range t.providerTables() {
+		providerMappings, providerMessages, tally := p.table.Swap(logger, table.providerTable(p.provider.Name), domains)
+		mappings = mappings.Merge(providerMappings)
+		messages = messages.Merge(providerMessages)
+		tallies[p.provider.Name] = tally
+	}
+	t.setSyncedAt(t.clock.Now())
+	return mappings, messages, newSyncReport(tallies)
+}
 
-	mappings := httpMappings.Merge(tcpMappings).Merge(internalMappings)
-	messages := httpMessages.Merge(tcpMessages).Merge(internalMessages)
+func (t *routingTable) PreviewSwap(other RoutingTable, domains models.DomainSet) (TCPRouteMappings, MessagesToEmit) {
+	table, ok := other.(*routingTable)
+	if !ok {
+		return TCPRouteMappings{}, MessagesToEmit{}
+	}
+
+	var mappings TCPRouteMappings
+	var messages MessagesToEmit
+	for _, p := range t.providerTables() {
+		providerMappings, providerMessages := p.table.PreviewSwap(table.providerTable(p.provider.Name), domains)
+		mappings = mappings.Merge(providerMappings)
+		messages = messages.Merge(providerMessages)
+	}
 	return mappings, messages
 }
 
 func (t *routingTable) GetExternalRoutingEvents() (TCPRouteMappings, MessagesToEmit) {
-	httpMappings, httpMessages := t.httpRoutesRoutingTable.GetRoutingEvents()
-	tcpMappings, tcpMessages := t.tcpRoutesRoutingTable.GetRoutingEvents()
-
-	mappings := httpMappings.Merge(tcpMappings)
-	messages := httpMessages.Merge(tcpMessages)
-	return mappings, messages
+	return t.routingEvents(ExternalRouteOutput)
 }
 
 func (t *routingTable) GetInternalRoutingEvents() (TCPRouteMappings, MessagesToEmit) {
-	return t.internalRoutesRoutingTable.GetRoutingEvents()
+	return t.routingEvents(InternalRouteOutput)
 }
 
-func (t *routingTable) SetRoutes(logger lager.Logger, before, after *models.DesiredLRP) (TCPRouteMappings, MessagesToEmit) {
-	httpMappings, httpMessages, httpChanged := t.httpRoutesRoutingTable.SetRoutes(before, after)
-	tcpMappings, tcpMessages, tcpChanged := t.tcpRoutesRoutingTable.SetRoutes(before, after)
-	internalMappings, internalMessages, internalChanged := t.internalRoutesRoutingTable.SetRoutes(before, after)
+func (t *routingTable) routingEvents(output RouteOutput) (TCPRouteMappings, MessagesToEmit) {
+	var mappings TCPRouteMappings
+	var messages MessagesToEmit
+	for _, p := range t.providerTables() {
+		if p.provider.Output != output {
+			continue
+		}
+		providerMappings, providerMessages := p.table.GetRoutingEvents()
+		mappings = mappings.Merge(providerMappings)
+		messages = messages.Merge(providerMessages)
+	}
+	return mappings, messages
+}
 
-	mappings := httpMappings.Merge(tcpMappings).Merge(internalMappings)
-	messages := httpMessages.Merge(tcpMessages).Merge(internalMessages)
+func (t *routingTable) SetRoutes(logger lager.Logger, before, after *models.DesiredLRP) (TCPRouteMappings, MessagesToEmit) {
+	mappings, messages, changed := t.fanOut(func(table *internalRoutingTable) (TCPRouteMappings, MessagesToEmit, bool) {
+		return table.SetRoutes(before, after)
+	})
 
-	if httpChanged || tcpChanged || internalChanged {
+	if changed {
 		logger.Info("set-routes", lager.Data{"before": DesiredLRPData(before), "after": DesiredLRPData(after)})
 	}
 
@@ -206,14 +402,11 @@ func (t *routingTable) SetRoutes(logger lager.Logger, before, after *models.Desi
 }
 
 func (t *routingTable) RemoveRoutes(logger lager.Logger, desiredLRP *models.DesiredLRP) (TCPRouteMappings, MessagesToEmit) {
-	httpMappings, httpMessages, httpChanged := t.httpRoutesRoutingTable.RemoveRoutes(desiredLRP)
-	tcpMappings, tcpMessages, tcpChanged := t.tcpRoutesRoutingTable.RemoveRoutes(desiredLRP)
-	internalMappings, internalMessages, internalChanged := t.internalRoutesRoutingTable.RemoveRoutes(desiredLRP)
+	mappings, messages, changed := t.fanOut(func(table *internalRoutingTable) (TCPRouteMappings, MessagesToEmit, bool) {
+		return table.RemoveRoutes(desiredLRP)
+	})
 
-	mappings := httpMappings.Merge(tcpMappings).Merge(internalMappings)
-	messages := httpMessages.Merge(tcpMessages).Merge(internalMessages)
-
-	if httpChanged || tcpChanged || internalChanged {
+	if changed {
 		logger.Info("remove-routes", DesiredLRPData(desiredLRP))
 	}
 
@@ -221,21 +414,32 @@ func (t *routingTable) RemoveRoutes(logger lager.Logger, desiredLRP *models.Desi
 }
 
 func (table *internalRoutingTable) AddEndpoint(logger lager.Logger, actualLRP *models.ActualLRP) (TCPRouteMappings, MessagesToEmit, bool) {
-	table.Lock()
-	defer table.Unlock()
+	mappings, messagesToEmit, changed, addresses, before := table.addEndpoint(logger, actualLRP)
+	collisionMappings, collisionMessages := table.resolveCollisions(logger, addresses, before)
+	return mappings.Merge(collisionMappings), messagesToEmit.Merge(collisionMessages), changed
+}
+
+func (table *internalRoutingTable) addEndpoint(logger lager.Logger, actualLRP *models.ActualLRP) (TCPRouteMappings, MessagesToEmit, bool, map[Address]struct{}, collisionState) {
+	shard := table.shardFor(actualLRP.ProcessGuid)
+	shard.Lock()
+	defer shard.Unlock()
 
 	changeDetected := false
 	endpoints := table.endpointGenerator(actualLRP)
 
 	// collision detection
 
+	var addresses map[Address]struct{}
+	var before collisionState
 	if !table.suppressAddressCollision {
+		table.collisions.Lock()
+		addresses = table.addresses(endpoints)
+		before = table.collisions.state(addresses)
 		for _, endpoint := range endpoints {
 			address := table.addressGenerator(endpoint)
 			// if the address exists and the instance guid doesn't match then we have a collision
-			if existingEndpointKey, ok := table.addressEntries[address]; ok && existingEndpointKey.InstanceGUID != endpoint.InstanceGUID {
+			if existingInstanceGuid, ok := table.collisions.collidingInstance(address, endpoint.InstanceGUID); ok && table.reportAddressCollision {
 				table.metronClient.IncrementCounter(addressCollisionsCounter)
-				existingInstanceGuid := existingEndpointKey.InstanceGUID
 				logger.Info("collision-detected-with-endpoint", lager.Data{
 					"instance_guid_a": existingInstanceGuid,
 					"instance_guid_b": endpoint.InstanceGUID,
@@ -243,8 +447,9 @@ func (table *internalRoutingTable) AddEndpoint(logger lager.Logger, actualLRP *m
 				})
 			}
 
-			table.addressEntries[address] = endpoint.key()
+			table.collisions.add(address, actualLRP.ProcessGuid, endpoint)
 		}
+		table.collisions.Unlock()
 	}
 
 	// add endpoints
@@ -257,7 +462,7 @@ func (table *internalRoutingTable) AddEndpoint(logger lager.Logger, actualLRP *m
 			ProcessGUID:   actualLRP.ProcessGuid,
 			ContainerPort: routingEndpoint.ContainerPort,
 		}
-		currentEntry := table.entries[key]
+		currentEntry := shard.entries[key]
 		// Since desiredLRP is same, only need to check one entry
 		if currentEntry.DesiredInstances > 0 && routingEndpoint.Index >= currentEntry.DesiredInstances {
 			logger.Debug("skipping-undesired-instance")
@@ -269,38 +474,49 @@ func (table *internalRoutingTable) AddEndpoint(logger lager.Logger, actualLRP *m
 		}
 		newEntry := currentEntry.copy()
 		newEntry.Endpoints[routingEndpoint.key()] = routingEndpoint
-		table.entries[key] = newEntry
+		shard.setEntry(key, newEntry)
 		mapping, message, changed := table.emitDiffMessages(key, currentEntry, newEntry)
 		mappings = mappings.Merge(mapping)
 		messagesToEmit = messagesToEmit.Merge(message)
 		changeDetected = changeDetected || changed
 	}
 
-	return mappings, messagesToEmit, changeDetected
+	return mappings, messagesToEmit, changeDetected, addresses, before
 }
 
 func (table *internalRoutingTable) RemoveEndpoint(logger lager.Logger, actualLRP *models.ActualLRP) (TCPRouteMappings, MessagesToEmit, bool) {
-	table.Lock()
-	defer table.Unlock()
+	mappings, messagesToEmit, changed, addresses, before := table.removeEndpoint(logger, actualLRP)
+	collisionMappings, collisionMessages := table.resolveCollisions(logger, addresses, before)
+	return mappings.Merge(collisionMappings), messagesToEmit.Merge(collisionMessages), changed
+}
+
+func (table *internalRoutingTable) removeEndpoint(logger lager.Logger, actualLRP *models.ActualLRP) (TCPRouteMappings, MessagesToEmit, bool, map[Address]struct{}, collisionState) {
+	shard := table.shardFor(actualLRP.ProcessGuid)
+	shard.Lock()
+	defer shard.Unlock()
 
 	changeDetected := false
 	endpoints := table.endpointGenerator(actualLRP)
 
 	// remove address
+	var addresses map[Address]struct{}
+	var before collisionState
 	if !table.suppressAddressCollision {
+		table.collisions.Lock()
+		addresses = table.addresses(endpoints)
+		before = table.collisions.state(addresses)
 		for _, endpoint := range endpoints {
 			address := table.addressGenerator(endpoint)
-			currentEntry, ok := table.addressEntries[address]
-			if ok && currentEntry.InstanceGUID != endpoint.InstanceGUID {
+			table.collisions.remove(address, endpoint)
+			if otherInstanceGuid, ok := table.collisions.collidingInstance(address, endpoint.InstanceGUID); ok && table.reportAddressCollision {
 				logger.Info("collision-detected-with-endpoint", lager.Data{
-					"instance_guid_a": currentEntry.InstanceGUID,
+					"instance_guid_a": otherInstanceGuid,
 					"instance_guid_b": endpoint.InstanceGUID,
 					"Address":         address,
 				})
-				continue
 			}
-			delete(table.addressEntries, address)
 		}
+		table.collisions.Unlock()
 	}
 
 	// remove endpoint
@@ -312,7 +528,7 @@ func (table *internalRoutingTable) RemoveEndpoint(logger lager.Logger, actualLRP
 			ContainerPort: routingEndpoint.ContainerPort,
 		}
 
-		currentEntry := table.entries[key]
+		currentEntry := shard.entries[key]
 		endpointKey := routingEndpoint.key()
 		currentEndpoint, ok := currentEntry.Endpoints[endpointKey]
 
@@ -325,8 +541,8 @@ func (table *internalRoutingTable) RemoveEndpoint(logger lager.Logger, actualLRP
 		newEntry := currentEntry.copy()
 		delete(newEntry.Endpoints, endpointKey)
 
-		table.entries[key] = newEntry
-		table.deleteEntryIfEmpty(key)
+		shard.setEntry(key, newEntry)
+		shard.deleteEntryIfEmpty(key)
 
 		mapping, message, changed := table.emitDiffMessages(key, currentEntry, newEntry)
 		messagesToEmit = messagesToEmit.Merge(message)
@@ -334,54 +550,169 @@ func (table *internalRoutingTable) RemoveEndpoint(logger lager.Logger, actualLRP
 		changeDetected = changeDetected || changed
 	}
 
-	return mappings, messagesToEmit, changeDetected
+	return mappings, messagesToEmit, changeDetected, addresses, before
+}
+
+// Swap replaces the entries of the table with those of otherTable one shard at
+// a time, so updates to LRPs in other shards are not blocked while it runs.
+func (t *internalRoutingTable) Swap(logger lager.Logger, otherTable *internalRoutingTable, domains models.DomainSet) (TCPRouteMappings, MessagesToEmit, syncTally) {
+	var messagesToEmit MessagesToEmit
+	var mappings TCPRouteMappings
+	tally := newSyncTally()
+
+	otherEntries := otherTable.entriesByShard(len(t.shards))
+
+	var before collisionState
+	if !t.suppressAddressCollision {
+		t.collisions.Lock()
+		before = t.collisions.state(t.collisions.allAddresses())
+		t.rebuildCollisions(otherEntries)
+		t.collisions.Unlock()
+	}
+
+	for i, shard := range t.shards {
+		mapping, message := shard.swap(t, otherEntries[i], domains, tally)
+		messagesToEmit = messagesToEmit.Merge(message)
+		mappings = mappings.Merge(mapping)
+	}
+
+	if !t.suppressAddressCollision {
+		// entries of non-fresh domains survive the swap, so rebuild from the
+		// merged entries before looking for changed collisions
+		entries := t.entriesByShard(len(t.shards))
+		t.collisions.Lock()
+		t.rebuildCollisions(entries)
+		addresses := t.collisions.allAddresses()
+		t.collisions.Unlock()
+
+		mapping, message := t.resolveCollisions(logger, addresses, before)
+		messagesToEmit = messagesToEmit.Merge(message)
+		mappings = mappings.Merge(mapping)
+	}
+
+	return mappings, messagesToEmit, tally
 }
 
-func (t *internalRoutingTable) Swap(otherTable *internalRoutingTable, domains models.DomainSet) (TCPRouteMappings, MessagesToEmit) {
-	t.Lock()
-	defer t.Unlock()
+// PreviewSwap diffs the entries the way Swap does, but leaves the table and
+// its address collisions alone.
+func (t *internalRoutingTable) PreviewSwap(otherTable *internalRoutingTable, domains models.DomainSet) (TCPRouteMappings, MessagesToEmit) {
+	var messagesToEmit MessagesToEmit
+	var mappings TCPRouteMappings
+
+	otherEntries := otherTable.entriesByShard(len(t.shards))
+	for i, shard := range t.shards {
+		mapping, message := shard.previewSwap(t, otherEntries[i], domains)
+		messagesToEmit = messagesToEmit.Merge(message)
+		mappings = mappings.Merge(mapping)
+	}
+	return mappings, messagesToEmit
+}
+
+// entriesByShard partitions the entries of the table as they would be
+// partitioned in a table with count shards.
+func (t *internalRoutingTable) entriesByShard(count int) []map[RoutingKey]RoutableEndpoints {
+	partitioned := make([]map[RoutingKey]RoutableEndpoints, count)
+	for i := range partitioned {
+		partitioned[i] = make(map[RoutingKey]RoutableEndpoints)
+	}
+
+	for _, shard := range t.shards {
+		shard.Lock()
+		for key, entry := range shard.entries {
+			partitioned[shardIndex(key.ProcessGUID, count)][key] = entry
+		}
+		shard.Unlock()
+	}
 
-	t.addressEntries = otherTable.addressEntries
+	return partitioned
+}
+
+// swap records how it changed every routing key of the shard in tally.
+func (shard *tableShard) swap(table *internalRoutingTable, otherEntries map[RoutingKey]RoutableEndpoints, domains models.DomainSet, tally syncTally) (TCPRouteMappings, MessagesToEmit) {
+	shard.Lock()
+	defer shard.Unlock()
+
+	otherShard := newTableShard()
+	for key, entry := range otherEntries {
+		otherShard.setEntry(key, entry)
+	}
 
 	var messagesToEmit MessagesToEmit
 	var mappings TCPRouteMappings
 
 	mergedRoutingKeys := map[RoutingKey]struct{}{}
-	for key, _ := range otherTable.entries {
+	for key, _ := range otherShard.entries {
 		mergedRoutingKeys[key] = struct{}{}
 	}
-	for key, _ := range t.entries {
+	for key, _ := range shard.entries {
 		mergedRoutingKeys[key] = struct{}{}
 	}
 
 	for key := range mergedRoutingKeys {
-		existingEntry, ok := t.entries[key]
-		newEntry := otherTable.entries[key]
+		existingEntry, ok := shard.entries[key]
+		newEntry := otherShard.entries[key]
 		if !ok {
 			// routing key only exist in the new table
-			mapping, message, _ := t.emitDiffMessages(key, RoutableEndpoints{}, newEntry)
+			mapping, message, _ := table.emitDiffMessages(key, RoutableEndpoints{}, newEntry)
 			messagesToEmit = messagesToEmit.Merge(message)
 			mappings = mappings.Merge(mapping)
+			tally.record(key, RoutableEndpoints{}, newEntry, SyncCounts{Added: 1}, mapping, message)
 			continue
 		}
 
 		// entry exists in both tables or in old table, merge the two entries to ensure non-fresh domain endpoints aren't removed
-		merged := mergeUnfreshRoutes(existingEntry, newEntry, domains)
-		otherTable.entries[key] = merged
-		otherTable.deleteEntryIfEmpty(key)
-		mapping, message, _ := t.emitDiffMessages(key, existingEntry, merged)
+		merged, held := mergeUnfreshRoutes(existingEntry, newEntry, domains)
+		otherShard.setEntry(key, merged)
+		otherShard.deleteEntryIfEmpty(key)
+		mapping, message, changed := table.emitDiffMessages(key, existingEntry, merged)
 		messagesToEmit = messagesToEmit.Merge(message)
 		mappings = mappings.Merge(mapping)
+
+		var counts SyncCounts
+		if _, ok := otherShard.entries[key]; !ok {
+			counts.Removed = 1
+		} else if changed {
+			counts.Changed = 1
+		}
+		if held {
+			counts.Held = 1
+		}
+		tally.record(key, existingEntry, merged, counts, mapping, message)
 	}
 
-	t.entries = otherTable.entries
+	shard.entries = otherShard.entries
+	shard.indexes = otherShard.indexes
 
 	return mappings, messagesToEmit
 }
 
-// merge the routes from both endpoints, ensuring that non-fresh routes aren't removed
-func mergeUnfreshRoutes(before, after RoutableEndpoints, domains models.DomainSet) RoutableEndpoints {
-	merged := after.copy()
+func (shard *tableShard) previewSwap(table *internalRoutingTable, otherEntries map[RoutingKey]RoutableEndpoints, domains models.DomainSet) (TCPRouteMappings, MessagesToEmit) {
+	shard.Lock()
+	defer shard.Unlock()
+
+	var messagesToEmit MessagesToEmit
+	var mappings TCPRouteMappings
+	for key, newEntry := range otherEntries {
+		if _, ok := shard.entries[key]; ok {
+			continue
+		}
+		mapping, message, _ := table.emitDiffMessages(key, RoutableEndpoints{}, newEntry)
+		messagesToEmit = messagesToEmit.Merge(message)
+		mappings = mappings.Merge(mapping)
+	}
+	for key, existingEntry := range shard.entries {
+		merged, _ := mergeUnfreshRoutes(existingEntry, otherEntries[key], domains)
+		mapping, message, _ := table.emitDiffMessages(key, existingEntry, merged)
+		messagesToEmit = messagesToEmit.Merge(message)
+		mappings = mappings.Merge(mapping)
+	}
+	return mappings, messagesToEmit
+}
+
+// merge the routes from both endpoints, ensuring that non-fresh routes aren't
+// removed. held is true if any route of before was kept.
+func mergeUnfreshRoutes(before, after RoutableEndpoints, domains models.DomainSet) (merged RoutableEndpoints, held bool) {
+	merged = after.copy()
 	merged.Domain = before.Domain
 
 	if !domains.Contains(before.Domain) {
@@ -397,45 +728,50 @@ func mergeUnfreshRoutes(before, after RoutableEndpoints, domains models.DomainSe
 			}
 			if !routeExistInNewLRP() {
 				merged.Routes = append(merged.Routes, oldRoute)
+				held = true
 			}
 		}
 	}
 
-	return merged
+	return merged, held
 }
 
 func (t *internalRoutingTable) GetRoutingEvents() (TCPRouteMappings, MessagesToEmit) {
-	t.Lock()
-	defer t.Unlock()
-
 	var messagesToEmit MessagesToEmit
 	var mappings TCPRouteMappings
-	for key, route := range t.entries {
-		mapping, message, _ := t.emitDiffMessages(key, RoutableEndpoints{}, route)
+	for _, shard := range t.shards {
+		shard.Lock()
+		for key, route := range shard.entries {
+			mapping, message, _ := t.emitDiffMessages(key, RoutableEndpoints{}, route)
 
-		mappings = mappings.Merge(mapping)
-		messagesToEmit = messagesToEmit.Merge(message)
+			mappings = mappings.Merge(mapping)
+			messagesToEmit = messagesToEmit.Merge(message)
+		}
+		shard.Unlock()
 	}
 
 	return mappings, messagesToEmit
 }
 
-type routeMapping interface {
+// RouteMapping is a single route of a RoutableEndpoints entry. Hash must
+// identify the route so that diffs between entries can match old and new
+// routes.
+type RouteMapping interface {
 	MessageFor(endpoint Endpoint, directInstanceAddress, emitEndpointUpdatedAt bool) (*RegistryMessage, *tcpmodels.TcpRouteMapping, *RegistryMessage)
 	Hash() interface{}
 }
 
-func httpRoutesFrom(lrp *models.DesiredLRP) map[RoutingKey][]routeMapping {
+func httpRoutesFrom(lrp *models.DesiredLRP) map[RoutingKey][]RouteMapping {
 	if lrp == nil || lrp.Routes == nil {
 		return nil
 	}
 
-	routes, _ := cfroutes.CFRoutesFromRoutingInfo(*lrp.Routes)
-	routeEntries := make(map[RoutingKey][]routeMapping)
+	routes, _ := httpRouteInfosFrom(lrp.Routes)
+	routeEntries := make(map[RoutingKey][]RouteMapping)
 	for _, route := range routes {
 		key := RoutingKey{ProcessGUID: lrp.ProcessGuid, ContainerPort: route.Port}
 
-		routes := []routeMapping{}
+		routes := []RouteMapping{}
 		for _, hostname := range route.Hostnames {
 			route := Route{
 				Hostname:         hostname,
@@ -444,6 +780,7 @@ func httpRoutesFrom(lrp *models.DesiredLRP) map[RoutingKey][]routeMapping {
 				IsolationSegment: route.IsolationSegment,
 				MetricTags:       lrp.MetricTags,
 				Protocol:         route.Protocol,
+				Options:          route.Options,
 			}
 			routes = append(routes, route)
 		}
@@ -452,37 +789,40 @@ func httpRoutesFrom(lrp *models.DesiredLRP) map[RoutingKey][]routeMapping {
 	return routeEntries
 }
 
-func tcpRoutesFrom(lrp *models.DesiredLRP) map[RoutingKey][]routeMapping {
+func tcpRoutesFrom(lrp *models.DesiredLRP) map[RoutingKey][]RouteMapping {
 	if lrp == nil {
 		return nil
 	}
 
-	routes, _ := tcp_routes.TCPRoutesFromRoutingInfo(lrp.Routes)
+	routes, _ := tcpRouteInfosFrom(lrp.Routes)
 
-	routeEntries := make(map[RoutingKey][]routeMapping)
+	routeEntries := make(map[RoutingKey][]RouteMapping)
 	for _, route := range routes {
 		key := RoutingKey{ProcessGUID: lrp.ProcessGuid, ContainerPort: route.ContainerPort}
 
 		routeEntries[key] = append(routeEntries[key], ExternalEndpointInfo{
 			RouterGroupGUID: route.RouterGroupGuid,
 			Port:            route.ExternalPort,
+			SniHostname:     route.SniHostname,
+			TLSProxy:        route.TLSProxy,
 		})
 	}
 	return routeEntries
 }
 
-func internalRoutesFrom(lrp *models.DesiredLRP) map[RoutingKey][]routeMapping {
+func internalRoutesFrom(lrp *models.DesiredLRP) map[RoutingKey][]RouteMapping {
 	if lrp == nil || lrp.Routes == nil {
 		return nil
 	}
 
-	routes, _ := internalroutes.InternalRoutesFromRoutingInfo(*lrp.Routes)
+	routes, _ := internalRouteInfosFrom(lrp.Routes)
 
-	routeEntries := make(map[RoutingKey][]routeMapping)
+	routeEntries := make(map[RoutingKey][]RouteMapping)
 	for _, route := range routes {
-		key := RoutingKey{ProcessGUID: lrp.ProcessGuid}
+		key := RoutingKey{ProcessGUID: lrp.ProcessGuid, ContainerPort: route.Port}
 		routeEntries[key] = append(routeEntries[key], InternalRoute{
 			Hostname: route.Hostname,
+			Port:     route.Port,
 			LogGUID:  lrp.LogGuid,
 		})
 	}
@@ -490,8 +830,15 @@ func internalRoutesFrom(lrp *models.DesiredLRP) map[RoutingKey][]routeMapping {
 }
 
 func (table *internalRoutingTable) SetRoutes(before, after *models.DesiredLRP) (TCPRouteMappings, MessagesToEmit, bool) {
-	table.Lock()
-	defer table.Unlock()
+	processGUID := ""
+	if after != nil {
+		processGUID = after.ProcessGuid
+	} else if before != nil {
+		processGUID = before.ProcessGuid
+	}
+	shard := table.shardFor(processGUID)
+	shard.Lock()
+	defer shard.Unlock()
 
 	// update routes
 	removedRouteEntries := table.routesGenerator(before)
@@ -504,7 +851,7 @@ func (table *internalRoutingTable) SetRoutes(before, after *models.DesiredLRP) (
 	changedDetected := false
 
 	for key, routes := range routeEntries {
-		currentEntry := table.entries[key]
+		currentEntry := shard.entries[key]
 		// if modification tag is old, ignore the new lrp
 		if !currentEntry.ModificationTag.SucceededBy(after.ModificationTag) {
 			continue
@@ -528,7 +875,7 @@ func (table *internalRoutingTable) SetRoutes(before, after *models.DesiredLRP) (
 			newEntry.Endpoints = newEndpoints
 		}
 
-		table.entries[key] = newEntry
+		shard.setEntry(key, newEntry)
 
 		mapping, message, changed := table.emitDiffMessages(key, currentEntry, newEntry)
 		messagesToEmit = messagesToEmit.Merge(message)
@@ -541,7 +888,7 @@ func (table *internalRoutingTable) SetRoutes(before, after *models.DesiredLRP) (
 			continue
 		}
 
-		currentEntry := table.entries[key]
+		currentEntry := shard.entries[key]
 		if after == nil {
 			// this is a delete (after == nil), then before lrp modification tag must be >=
 			if !currentEntry.ModificationTag.Equal(before.ModificationTag) && !currentEntry.ModificationTag.SucceededBy(before.ModificationTag) {
@@ -560,9 +907,9 @@ func (table *internalRoutingTable) SetRoutes(before, after *models.DesiredLRP) (
 			newEntry.DesiredInstances = after.Instances
 		}
 
-		table.entries[key] = newEntry
+		shard.setEntry(key, newEntry)
 
-		table.deleteEntryIfEmpty(key)
+		shard.deleteEntryIfEmpty(key)
 
 		mapping, message, changed := table.emitDiffMessages(key, currentEntry, newEntry)
 		messagesToEmit = messagesToEmit.Merge(message)
@@ -573,11 +920,110 @@ func (table *internalRoutingTable) SetRoutes(before, after *models.DesiredLRP) (
 	return mappings, messagesToEmit, changedDetected
 }
 
-func (table *internalRoutingTable) deleteEntryIfEmpty(key RoutingKey) {
-	entry := table.entries[key]
-	if len(entry.Endpoints) == 0 && len(entry.Routes) == 0 {
-		delete(table.entries, key)
+func (table *internalRoutingTable) shardFor(processGUID string) *tableShard {
+	return table.shards[shardIndex(processGUID, len(table.shards))]
+}
+
+func (table *internalRoutingTable) addresses(endpoints []Endpoint) map[Address]struct{} {
+	addresses := make(map[Address]struct{}, len(endpoints))
+	for _, endpoint := range endpoints {
+		addresses[table.addressGenerator(endpoint)] = struct{}{}
+	}
+	return addresses
+}
+
+// rebuildCollisions must be called with the collisions lock held.
+func (table *internalRoutingTable) rebuildCollisions(entries []map[RoutingKey]RoutableEndpoints) {
+	table.collisions.reset()
+	for _, shardEntries := range entries {
+		for key, entry := range shardEntries {
+			for _, endpoint := range entry.Endpoints {
+				table.collisions.add(table.addressGenerator(endpoint), key.ProcessGUID, endpoint)
+			}
+		}
+	}
+}
+
+func (table *internalRoutingTable) isSuppressed(endpoint Endpoint) bool {
+	if table.suppressAddressCollision {
+		return false
 	}
+
+	table.collisions.Lock()
+	defer table.collisions.Unlock()
+	return table.collisions.suppressed(table.addressGenerator(endpoint), endpoint.InstanceGUID)
+}
+
+// resolveCollisions withdraws the registrations of endpoints that the
+// collision policy suppressed since before was taken, and restores those it
+// released. It must be called without holding any shard lock, as the
+// endpoints may belong to any shard.
+func (table *internalRoutingTable) resolveCollisions(logger lager.Logger, addresses map[Address]struct{}, before collisionState) (TCPRouteMappings, MessagesToEmit) {
+	var messagesToEmit MessagesToEmit
+	var mappings TCPRouteMappings
+	if len(addresses) == 0 {
+		return mappings, messagesToEmit
+	}
+
+	table.collisions.Lock()
+	after := table.collisions.state(addresses)
+	table.collisions.Unlock()
+
+	suppressed, released := before.transitions(after)
+	for ref, entry := range suppressed {
+		logger.Info("suppressing-colliding-endpoint", lager.Data{
+			"instance_guid": ref.key.InstanceGUID,
+			"process_guid":  ref.processGUID,
+			"Address":       ref.address,
+			"policy":        table.collisions.policy,
+		})
+		mapping, message := table.reemitEndpoint(ref, entry, false)
+		messagesToEmit = messagesToEmit.Merge(message)
+		mappings = mappings.Merge(mapping)
+	}
+	for ref, entry := range released {
+		logger.Info("releasing-endpoint-after-collision", lager.Data{
+			"instance_guid": ref.key.InstanceGUID,
+			"process_guid":  ref.processGUID,
+			"Address":       ref.address,
+		})
+		mapping, message := table.reemitEndpoint(ref, entry, true)
+		messagesToEmit = messagesToEmit.Merge(message)
+		mappings = mappings.Merge(mapping)
+	}
+
+	return mappings, messagesToEmit
+}
+
+// reemitEndpoint registers or unregisters all routes of a single endpoint, as
+// long as it is still in the table and its suppression has not changed again.
+func (table *internalRoutingTable) reemitEndpoint(ref endpointRef, entry addressEntry, register bool) (TCPRouteMappings, MessagesToEmit) {
+	shard := table.shardFor(ref.processGUID)
+	shard.Lock()
+	defer shard.Unlock()
+
+	key := RoutingKey{ProcessGUID: ref.processGUID, ContainerPort: entry.endpoint.ContainerPort}
+	routableEndpoints := shard.entries[key]
+	endpoint, ok := routableEndpoints.Endpoints[ref.key]
+	if !ok || table.isSuppressed(endpoint) == register {
+		return TCPRouteMappings{}, MessagesToEmit{}
+	}
+
+	endpoints := map[EndpointKey]Endpoint{ref.key: endpoint}
+	if register {
+		return table.messages(routesDiff{after: routableEndpoints.Routes}, endpointsDiff{added: endpoints})
+	}
+	return table.messages(routesDiff{before: routableEndpoints.Routes}, endpointsDiff{removed: endpoints})
+}
+
+func (t *internalRoutingTable) AddressCollisions() []AddressCollision {
+	if t.suppressAddressCollision || !t.reportAddressCollision {
+		return nil
+	}
+
+	t.collisions.Lock()
+	defer t.collisions.Unlock()
+	return t.collisions.collisions()
 }
 
 func (table *internalRoutingTable) emitDiffMessages(key RoutingKey, oldEntry, newEntry RoutableEndpoints) (TCPRouteMappings, MessagesToEmit, bool) {
@@ -585,7 +1031,7 @@ func (table *internalRoutingTable) emitDiffMessages(key RoutingKey, oldEntry, ne
 	endpointsDiff := diffEndpoints(oldEntry.Endpoints, newEntry.Endpoints)
 
 	changed := false
-	if len(routesDiff.added) > 0 || len(routesDiff.removed) > 0 ||
+	if len(routesDiff.added) > 0 || len(routesDiff.removed) > 0 || len(routesDiff.updated) > 0 ||
 		len(endpointsDiff.added) > 0 || len(endpointsDiff.removed) > 0 {
 		changed = true
 	}
@@ -594,17 +1040,26 @@ func (table *internalRoutingTable) emitDiffMessages(key RoutingKey, oldEntry, ne
 	return mappings, messages, changed
 }
 
+// updated routes have the same hash before and after, but their
+// registrations changed, e.g. because of different metric tags. They are
+// registered again without being unregistered first.
 type routesDiff struct {
-	before, after, removed, added []routeMapping
+	before, after, removed, added, updated []RouteMapping
+}
+
+// updatableRoute is implemented by routes with attributes that are not part
+// of their hash but are part of their registrations.
+type updatableRoute interface {
+	updates(before RouteMapping) bool
 }
 
 type endpointsDiff struct {
 	before, after, removed, added map[EndpointKey]Endpoint
 }
 
-func diffRoutes(before, after []routeMapping) routesDiff {
-	existingRoutes := map[interface{}]routeMapping{}
-	newRoutes := map[interface{}]routeMapping{}
+func diffRoutes(before, after []RouteMapping) routesDiff {
+	existingRoutes := map[interface{}]RouteMapping{}
+	newRoutes := map[interface{}]RouteMapping{}
 	for _, route := range before {
 		existingRoutes[route.Hash()] = route
 	}
@@ -623,9 +1078,14 @@ func diffRoutes(before, after []routeMapping) routesDiff {
 		}
 	}
 
-	for routeHash := range newRoutes {
-		if _, ok := existingRoutes[routeHash]; !ok {
-			diff.added = append(diff.added, newRoutes[routeHash])
+	for routeHash, route := range newRoutes {
+		existingRoute, ok := existingRoutes[routeHash]
+		if !ok {
+			diff.added = append(diff.added, route)
+			continue
+		}
+		if updatable, ok := route.(updatableRoute); ok && updatable.updates(existingRoute) {
+			diff.updated = append(diff.updated, route)
 		}
 	}
 
@@ -679,11 +1139,11 @@ func diffEndpoints(before, after map[EndpointKey]Endpoint) endpointsDiff {
 func (table *internalRoutingTable) messages(routesDiff routesDiff, endpointDiff endpointsDiff) (TCPRouteMappings, MessagesToEmit) {
 	type registrationMetadata struct {
 		emitEndpointUpdatedAt bool
-		route                 routeMapping
+		route                 RouteMapping
 	}
 
 	type unregistrationMetadata struct {
-		route routeMapping
+		route RouteMapping
 	}
 
 	// maps used to remove duplicates
@@ -706,8 +1166,11 @@ func (table *internalRoutingTable) messages(routesDiff routesDiff, endpointDiff
 		}
 	}
 
-	// for added routes add all currently known endpoints
-	for _, route := range routesDiff.added {
+	// for added and updated routes add all currently known endpoints
+	addedRoutes := make([]RouteMapping, 0, len(routesDiff.added)+len(routesDiff.updated))
+	addedRoutes = append(addedRoutes, routesDiff.added...)
+	addedRoutes = append(addedRoutes, routesDiff.updated...)
+	for _, route := range addedRoutes {
 		rh := route.Hash()
 		for _, container := range endpointDiff.after {
 			if registrations[rh] != nil && registrations[rh][container] != nil {
@@ -761,6 +1224,9 @@ func (table *internalRoutingTable) messages(routesDiff routesDiff, endpointDiff
 
 	for _, es := range registrations {
 		for e, metadata := range es {
+			if table.isSuppressed(e) {
+				continue
+			}
 			msg, mapping, internalMsg := metadata.route.MessageFor(e, table.directInstanceRoute, metadata.emitEndpointUpdatedAt)
 			if msg != nil {
 				messages.RegistrationMessages = append(messages.RegistrationMessages, *msg)
@@ -788,6 +1254,11 @@ func (table *internalRoutingTable) messages(routesDiff routesDiff, endpointDiff
 			}
 		}
 	}
+
+	if table.aggregateHostnames {
+		messages.RegistrationMessages = AggregateRegistryMessages(messages.RegistrationMessages)
+		messages.UnregistrationMessages = AggregateRegistryMessages(messages.UnregistrationMessages)
+	}
 	return mappings, messages
 }
 
@@ -796,27 +1267,36 @@ func (table *internalRoutingTable) RemoveRoutes(desiredLRP *models.DesiredLRP) (
 }
 
 func (t *internalRoutingTable) AssociationsCount() int {
-	t.Lock()
-	defer t.Unlock()
-
 	count := 0
-	for _, entry := range t.entries {
-		count += len(entry.Routes) * len(entry.Endpoints)
+	for _, shard := range t.shards {
+		shard.Lock()
+		for _, entry := range shard.entries {
+			count += len(entry.Routes) * len(entry.Endpoints)
+		}
+		shard.Unlock()
 	}
 
 	return count
 }
 
 func (t *internalRoutingTable) TableSize() int {
-	t.Lock()
-	defer t.Unlock()
+	size := 0
+	for _, shard := range t.shards {
+		shard.Lock()
+		size += len(shard.entries)
+		shard.Unlock()
+	}
 
-	return len(t.entries)
+	return size
 }
 
 func (t *internalRoutingTable) HasExternalRoutes(actualLRP *models.ActualLRP) bool {
+	shard := t.shardFor(actualLRP.ProcessGuid)
+	shard.Lock()
+	defer shard.Unlock()
+
 	for _, key := range NewRoutingKeysFromActual(actualLRP) {
-		if len(t.entries[key].Routes) > 0 {
+		if len(shard.entries[key].Routes) > 0 {
 			return true
 		}
 	}
@@ -825,21 +1305,193 @@ func (t *internalRoutingTable) HasExternalRoutes(actualLRP *models.ActualLRP) bo
 }
 
 func (t *routingTable) HTTPAssociationsCount() int {
-	return t.httpRoutesRoutingTable.AssociationsCount()
+	return t.providerTable(HTTPRouteProvider).AssociationsCount()
 }
 
 func (t *routingTable) TCPAssociationsCount() int {
-	return t.tcpRoutesRoutingTable.AssociationsCount()
+	return t.providerTable(TCPRouteProvider).AssociationsCount()
 }
 
 func (t *routingTable) InternalAssociationsCount() int {
-	return 2 * t.internalRoutesRoutingTable.AssociationsCount()
+	return 2 * t.providerTable(InternalRouteProvider).AssociationsCount()
 }
 
 func (t *routingTable) TableSize() int {
-	return t.httpRoutesRoutingTable.TableSize() + t.tcpRoutesRoutingTable.TableSize() + t.internalRoutesRoutingTable.TableSize()
+	size := 0
+	for _, p := range t.providerTables() {
+		size += p.table.TableSize()
+	}
+	return size
 }
 
 func (t *routingTable) HasExternalRoutes(actualLRP *models.ActualLRP) bool {
-	return t.httpRoutesRoutingTable.HasExternalRoutes(actualLRP) || t.tcpRoutesRoutingTable.HasExternalRoutes(actualLRP)
+	for _, p := range t.providerTables() {
+		if p.provider.Output == ExternalRouteOutput && p.table.HasExternalRoutes(actualLRP) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *routingTable) AddressCollisions() []AddressCollision {
+	collisions := []AddressCollision{}
+	for _, p := range t.providerTables() {
+		collisions = append(collisions, p.table.AddressCollisions()...)
+	}
+	return collisions
+}
+
+func (t *routingTable) Snapshot() Snapshot {
+	return Snapshot{
+		Version:              SnapshotVersion,
+		SyncedAt:             t.getSyncedAt(),
+		DirectInstanceRoutes: t.directInstanceRoute,
+		HTTP:                 t.providerTable(HTTPRouteProvider).Snapshot(),
+		TCP:                  t.providerTable(TCPRouteProvider).Snapshot(),
+		Internal:             t.providerTable(InternalRouteProvider).Snapshot(),
+	}
+}
+
+// Restore replaces the contents of the table with the snapshot without
+// emitting any messages. The next broadcast will register everything restored
+// and the next Swap will reconcile it against the BBS. The table keeps the
+// sync time of the snapshot, so that snapshots of it taken before the next
+// Swap are as old as the restored one.
+func (t *routingTable) Restore(logger lager.Logger, snapshot Snapshot) {
+	t.providerTable(HTTPRouteProvider).Restore(snapshot.HTTP)
+	t.providerTable(TCPRouteProvider).Restore(snapshot.TCP)
+	t.providerTable(InternalRouteProvider).Restore(snapshot.Internal)
+	t.setSyncedAt(snapshot.SyncedAt)
+
+	logger.Info("restored-routing-table", lager.Data{
+		"http-entries":     len(snapshot.HTTP),
+		"tcp-entries":      len(snapshot.TCP),
+		"internal-entries": len(snapshot.Internal),
+	})
+}
+
+func (t *routingTable) setSyncedAt(syncedAt time.Time) {
+	t.syncedAtLock.Lock()
+	defer t.syncedAtLock.Unlock()
+	t.syncedAt = syncedAt
+}
+
+func (t *routingTable) getSyncedAt() time.Time {
+	t.syncedAtLock.Lock()
+	defer t.syncedAtLock.Unlock()
+	return t.syncedAt
+}
+
+func (t *routingTable) RoutingKeysForHostname(hostname string) RoutingKeys {
+	return t.queryIndexes(func(indexes tableIndexes) routingKeySet {
+		return indexes.hostnames[hostname]
+	})
+}
+
+func (t *routingTable) RoutingKeysForInstance(instanceGUID string) RoutingKeys {
+	return t.queryIndexes(func(indexes tableIndexes) routingKeySet {
+		return indexes.instances[instanceGUID]
+	})
+}
+
+func (t *routingTable) RoutingKeysForRouterGroupPort(routerGroupGUID string, port uint32) RoutingKeys {
+	return t.queryIndexes(func(indexes tableIndexes) routingKeySet {
+		return indexes.routerGroupPorts[RouterGroupPort{RouterGroupGUID: routerGroupGUID, Port: port}]
+	})
+}
+
+func (t *routingTable) queryIndexes(query func(tableIndexes) routingKeySet) RoutingKeys {
+	keys := routingKeySet{}
+	for _, name := range []string{HTTPRouteProvider, TCPRouteProvider, InternalRouteProvider} {
+		for key := range t.providerTable(name).queryIndexes(query) {
+			keys[key] = struct{}{}
+		}
+	}
+	return keys.routingKeys()
+}
+
+// SnapshotForRoutingKeys is like Snapshot but only includes the entries for the
+// given routing keys, which makes it cheap to combine with the index queries.
+func (t *routingTable) SnapshotForRoutingKeys(keys RoutingKeys) Snapshot {
+	return Snapshot{
+		Version:              SnapshotVersion,
+		SyncedAt:             t.getSyncedAt(),
+		DirectInstanceRoutes: t.directInstanceRoute,
+		HTTP:                 t.providerTable(HTTPRouteProvider).SnapshotForRoutingKeys(keys),
+		TCP:                  t.providerTable(TCPRouteProvider).SnapshotForRoutingKeys(keys),
+		Internal:             t.providerTable(InternalRouteProvider).SnapshotForRoutingKeys(keys),
+	}
+}
+
+func (t *internalRoutingTable) queryIndexes(query func(tableIndexes) routingKeySet) routingKeySet {
+	keys := routingKeySet{}
+	for _, shard := range t.shards {
+		shard.Lock()
+		for key := range query(shard.indexes) {
+			keys[key] = struct{}{}
+		}
+		shard.Unlock()
+	}
+	return keys
+}
+
+func (t *internalRoutingTable) SnapshotForRoutingKeys(keys RoutingKeys) []SnapshotEntry {
+	entries := []SnapshotEntry{}
+	for _, key := range keys {
+		shard := t.shardFor(key.ProcessGUID)
+		shard.Lock()
+		if entry, ok := shard.entries[key]; ok {
+			entries = append(entries, newSnapshotEntry(key, entry))
+		}
+		shard.Unlock()
+	}
+
+	return entries
+}
+
+func (t *internalRoutingTable) Snapshot() []SnapshotEntry {
+	entries := []SnapshotEntry{}
+	for _, shard := range t.shards {
+		shard.Lock()
+		for key, entry := range shard.entries {
+			entries = append(entries, newSnapshotEntry(key, entry))
+		}
+		shard.Unlock()
+	}
+
+	return entries
+}
+
+func (t *internalRoutingTable) Restore(snapshotEntries []SnapshotEntry) {
+	for _, shard := range t.shards {
+		shard.Lock()
+		defer shard.Unlock()
+	}
+	t.collisions.Lock()
+	defer t.collisions.Unlock()
+
+	t.collisions.reset()
+	for _, shard := range t.shards {
+		shard.entries = make(map[RoutingKey]RoutableEndpoints)
+		shard.indexes = newTableIndexes()
+	}
+
+	for _, snapshotEntry := range snapshotEntries {
+		entry := RoutableEndpoints{
+			Domain:           snapshotEntry.Domain,
+			Routes:           snapshotEntry.routes(),
+			Endpoints:        make(map[EndpointKey]Endpoint),
+			DesiredInstances: snapshotEntry.DesiredInstances,
+			ModificationTag:  snapshotEntry.ModificationTag,
+		}
+
+		for _, endpoint := range snapshotEntry.Endpoints {
+			entry.Endpoints[endpoint.key()] = endpoint
+			if !t.suppressAddressCollision {
+				t.collisions.add(t.addressGenerator(endpoint), snapshotEntry.ProcessGUID, endpoint)
+			}
+		}
+
+		t.shardFor(snapshotEntry.ProcessGUID).setEntry(snapshotEntry.routingKey(), entry)
+	}
 }
@@ -391,6 +391,38 @@ var _ = Describe("RoutingTable", func() {
 					}
 					Expect(messagesToEmit).To(Equal(expected))
 				})
+
+				It("re-registers the endpoints with the new tags without unregistering them", func() {
+					taggedDesiredLRP := *afterDesiredLRP
+					taggedDesiredLRP.MetricTags = map[string]*models.MetricTagValue{"foo": &models.MetricTagValue{Static: "bar"}}
+					taggedDesiredLRP.ModificationTag = &models.ModificationTag{Epoch: "lmn", Index: 0}
+					tcpRouteMappings, messagesToEmit = table.SetRoutes(logger, afterDesiredLRP, &taggedDesiredLRP)
+
+					Expect(tcpRouteMappings).To(BeZero())
+					expected := routingtable.MessagesToEmit{
+						RegistrationMessages: []routingtable.RegistryMessage{
+							routingtable.InternalAddressRegistryMessageFor(
+								endpoint1,
+								routingtable.Route{
+									Hostname:   "bar.example.com",
+									LogGUID:    logGuid,
+									MetricTags: taggedDesiredLRP.MetricTags,
+								},
+								false,
+							),
+						},
+					}
+					Expect(messagesToEmit).To(Equal(expected))
+				})
+
+				Context("when the metric tags do not change", func() {
+					It("emits nothing", func() {
+						sameDesiredLRP := *afterDesiredLRP
+						sameDesiredLRP.ModificationTag = &models.ModificationTag{Epoch: "lmn", Index: 0}
+						tcpRouteMappings, messagesToEmit = table.SetRoutes(logger, afterDesiredLRP, &sameDesiredLRP)
+						Expect(messagesToEmit).To(BeZero())
+					})
+				})
 			})
 		})
 
@@ -549,7 +581,7 @@ var _ = Describe("RoutingTable", func() {
 			tempTable = routingtable.NewRoutingTable(false, fakeMetronClient)
 			actualLRP = createActualLRP(key, endpoint1, domain)
 			tempTable.AddEndpoint(logger, actualLRP)
-			tcpRouteMappings, messagesToEmit = table.Swap(logger, tempTable, freshDomains)
+			tcpRouteMappings, messagesToEmit, _ = table.Swap(logger, tempTable, freshDomains)
 
 			expectedHTTP := routingtable.MessagesToEmit{
 				UnregistrationMessages: []routingtable.RegistryMessage{
@@ -586,7 +618,7 @@ var _ = Describe("RoutingTable", func() {
 					actualLRP := createActualLRP(key, endpoint1, domain)
 					tempTable := routingtable.NewRoutingTable(false, fakeMetronClient)
 					tempTable.AddEndpoint(logger, actualLRP)
-					_, messagesToEmit := table.Swap(logger, tempTable, noFreshDomains)
+					_, messagesToEmit, _ := table.Swap(logger, tempTable, noFreshDomains)
 					Expect(messagesToEmit.InternalUnregistrationMessages).To(BeEmpty())
 					Expect(messagesToEmit.InternalRegistrationMessages).To(BeEmpty())
 
@@ -618,7 +650,7 @@ var _ = Describe("RoutingTable", func() {
 				Context("and the new table has nothing in it", func() {
 					BeforeEach(func() {
 						tempTable := routingtable.NewRoutingTable(false, fakeMetronClient)
-						tcpRouteMappings, messagesToEmit = table.Swap(logger, tempTable, noFreshDomains)
+						tcpRouteMappings, messagesToEmit, _ = table.Swap(logger, tempTable, noFreshDomains)
 					})
 
 					It("unregisters non-existent endpoints", func() {
@@ -646,7 +678,7 @@ var _ = Describe("RoutingTable", func() {
 						actualLRP := createActualLRP(key, endpoint1, domain)
 						tempTable := routingtable.NewRoutingTable(false, fakeMetronClient)
 						tempTable.AddEndpoint(logger, actualLRP)
-						tcpRouteMappings, messagesToEmit = table.Swap(logger, tempTable, noFreshDomains)
+						tcpRouteMappings, messagesToEmit, _ = table.Swap(logger, tempTable, noFreshDomains)
 
 						expectedHTTP := routingtable.MessagesToEmit{
 							RegistrationMessages: []routingtable.RegistryMessage{
@@ -685,7 +717,7 @@ var _ = Describe("RoutingTable", func() {
 			actualLRP = createActualLRP(key, endpoint1, domain)
 			table.AddEndpoint(logger, actualLRP)
 
-			Expect(table.TableSize()).To(Equal(3))
+			Expect(table.TableSize()).To(Equal(4))
 		})
 
 		Context("when routes are deleted", func() {
@@ -695,7 +727,7 @@ var _ = Describe("RoutingTable", func() {
 			})
 
 			It("doesn't remove the entry from the table", func() {
-				Expect(table.TableSize()).To(Equal(3))
+				Expect(table.TableSize()).To(Equal(4))
 			})
 
 			Context("and endpoints are deleted", func() {
@@ -715,7 +747,7 @@ var _ = Describe("RoutingTable", func() {
 			})
 
 			It("doesn't remove the entry from the table", func() {
-				Expect(table.TableSize()).To(Equal(3))
+				Expect(table.TableSize()).To(Equal(4))
 			})
 
 			Context("and endpoints are deleted", func() {
@@ -0,0 +1,56 @@
+package routingtable
+
+import (
+	"hash/fnv"
+	"sync"
+)
+
+// DefaultShardCount is the number of shards each sub-table is partitioned
+// into unless WithShardCount is given.
+const DefaultShardCount = 32
+
+// tableShard holds one partition of the entries of an internalRoutingTable.
+// Entries are partitioned by process guid, so every routing key of an LRP
+// lives in the same shard and updates to one LRP are serialized by its lock.
+type tableShard struct {
+	entries map[RoutingKey]RoutableEndpoints
+	indexes tableIndexes
+	sync.Locker
+}
+
+func newTableShard() *tableShard {
+	return &tableShard{
+		entries: make(map[RoutingKey]RoutableEndpoints),
+		indexes: newTableIndexes(),
+		Locker:  &sync.Mutex{},
+	}
+}
+
+func newTableShards(count int) []*tableShard {
+	if count < 1 {
+		count = 1
+	}
+	shards := make([]*tableShard, count)
+	for i := range shards {
+		shards[i] = newTableShard()
+	}
+	return shards
+}
+
+func shardIndex(processGUID string, count int) int {
+	h := fnv.New32a()
+	h.Write([]byte(processGUID))
+	return int(h.Sum32() % uint32(count))
+}
+
+func (shard *tableShard) setEntry(key RoutingKey, entry RoutableEndpoints) {
+	shard.indexes.update(key, shard.entries[key], entry)
+	shard.entries[key] = entry
+}
+
+func (shard *tableShard) deleteEntryIfEmpty(key RoutingKey) {
+	entry := shard.entries[key]
+	if len(entry.Endpoints) == 0 && len(entry.Routes) == 0 {
+		delete(shard.entries, key)
+	}
+}
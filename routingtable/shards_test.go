@@ -0,0 +1,88 @@
+package routingtable_test
+
+import (
+	"fmt"
+	"sync"
+
+	"code.cloudfoundry.org/bbs/models"
+	mfakes "code.cloudfoundry.org/diego-logging-client/testhelpers"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Shards", func() {
+	var (
+		logger           *lagertest.TestLogger
+		fakeMetronClient *mfakes.FakeIngressClient
+	)
+
+	tag := models.ModificationTag{Epoch: "abc", Index: 1}
+	const lrpCount = 50
+
+	desiredLRPFor := func(i int) *models.DesiredLRP {
+		return createDesiredLRP(fmt.Sprintf("process-guid-%d", i), 1, 8080, "log-guid", "", tag, models.DesiredLRPRunInfo{}, fmt.Sprintf("app-%d.example.com", i))
+	}
+
+	actualLRPFor := func(i int) *models.ActualLRP {
+		endpoint := routingtable.Endpoint{
+			InstanceGUID:    fmt.Sprintf("ig-%d", i),
+			Host:            "1.1.1.1",
+			ContainerIP:     "1.2.3.4",
+			Port:            uint32(61000 + i),
+			ContainerPort:   8080,
+			ModificationTag: &tag,
+		}
+		key := routingtable.RoutingKey{ProcessGUID: fmt.Sprintf("process-guid-%d", i), ContainerPort: 8080}
+		return createActualLRP(key, endpoint, "domain")
+	}
+
+	populate := func(table routingtable.RoutingTable) {
+		wg := sync.WaitGroup{}
+		for i := 0; i < lrpCount; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer GinkgoRecover()
+				defer wg.Done()
+				table.SetRoutes(logger, nil, desiredLRPFor(i))
+				table.AddEndpoint(logger, actualLRPFor(i))
+			}(i)
+		}
+		wg.Wait()
+	}
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test-route-emitter")
+		fakeMetronClient = &mfakes.FakeIngressClient{}
+	})
+
+	It("applies updates for different LRPs concurrently", func() {
+		table := routingtable.NewRoutingTable(false, fakeMetronClient, routingtable.WithShardCount(8))
+		populate(table)
+
+		Expect(table.Snapshot().HTTP).To(HaveLen(lrpCount))
+		Expect(table.HTTPAssociationsCount()).To(Equal(lrpCount))
+		_, messages := table.GetExternalRoutingEvents()
+		Expect(messages.RegistrationMessages).To(HaveLen(lrpCount))
+	})
+
+	Context("when swapping tables with a different number of shards", func() {
+		It("emits no messages for unchanged entries", func() {
+			table := routingtable.NewRoutingTable(false, fakeMetronClient, routingtable.WithShardCount(8))
+			populate(table)
+
+			tempTable := routingtable.NewRoutingTable(false, fakeMetronClient, routingtable.WithShardCount(3))
+			populate(tempTable)
+
+			_, messages, _ := table.Swap(logger, tempTable, models.NewDomainSet([]string{"domain"}))
+			Expect(messages.RegistrationMessages).To(BeEmpty())
+			Expect(messages.UnregistrationMessages).To(BeEmpty())
+			Expect(table.Snapshot().HTTP).To(HaveLen(lrpCount))
+			Expect(table.RoutingKeysForHostname("app-7.example.com")).To(ConsistOf(
+				routingtable.RoutingKey{ProcessGUID: "process-guid-7", ContainerPort: 8080},
+			))
+		})
+	})
+})
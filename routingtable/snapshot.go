@@ -0,0 +1,141 @@
+package routingtable
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"code.cloudfoundry.org/bbs/models"
+)
+
+// SnapshotVersion must be bumped whenever the on-disk format of Snapshot
+// changes in a way that older emitters cannot read, or that would restore the
+// routes of older snapshots with fields missing. Snapshots of other versions
+// are not restored.
+const SnapshotVersion = 1
+
+var ErrSnapshotVersionMismatch = errors.New("routing table snapshot version mismatch")
+
+// Snapshot is the on-disk form of a routing table. SyncedAt is the time of
+// the last sync with the BBS its routes are from, and zero if the table was
+// never synced.
+type Snapshot struct {
+	Version              int             `json:"version"`
+	SyncedAt             time.Time       `json:"synced_at"`
+	DirectInstanceRoutes bool            `json:"direct_instance_routes"`
+	HTTP                 []SnapshotEntry `json:"http"`
+	TCP                  []SnapshotEntry `json:"tcp"`
+	Internal             []SnapshotEntry `json:"internal"`
+}
+
+type SnapshotEntry struct {
+	ProcessGUID      string                  `json:"process_guid"`
+	ContainerPort    uint32                  `json:"container_port"`
+	Domain           string                  `json:"domain"`
+	DesiredInstances int32                   `json:"desired_instances"`
+	ModificationTag  *models.ModificationTag `json:"modification_tag,omitempty"`
+	HTTPRoutes       []Route                 `json:"http_routes,omitempty"`
+	TCPRoutes        []ExternalEndpointInfo  `json:"tcp_routes,omitempty"`
+	InternalRoutes   []InternalRoute         `json:"internal_routes,omitempty"`
+	Endpoints        []Endpoint              `json:"endpoints,omitempty"`
+}
+
+func (entry SnapshotEntry) routingKey() RoutingKey {
+	return RoutingKey{ProcessGUID: entry.ProcessGUID, ContainerPort: entry.ContainerPort}
+}
+
+func (entry SnapshotEntry) routes() []RouteMapping {
+	var routes []RouteMapping
+	for _, route := range entry.HTTPRoutes {
+		routes = append(routes, route)
+	}
+	for _, route := range entry.TCPRoutes {
+		routes = append(routes, route)
+	}
+	for _, route := range entry.InternalRoutes {
+		routes = append(routes, route)
+	}
+	return routes
+}
+
+func newSnapshotEntry(key RoutingKey, entry RoutableEndpoints) SnapshotEntry {
+	snapshotEntry := SnapshotEntry{
+		ProcessGUID:      key.ProcessGUID,
+		ContainerPort:    key.ContainerPort,
+		Domain:           entry.Domain,
+		DesiredInstances: entry.DesiredInstances,
+		ModificationTag:  entry.ModificationTag,
+	}
+
+	for _, route := range entry.Routes {
+		switch route := route.(type) {
+		case Route:
+			snapshotEntry.HTTPRoutes = append(snapshotEntry.HTTPRoutes, route)
+		case ExternalEndpointInfo:
+			snapshotEntry.TCPRoutes = append(snapshotEntry.TCPRoutes, route)
+		case InternalRoute:
+			snapshotEntry.InternalRoutes = append(snapshotEntry.InternalRoutes, route)
+		}
+	}
+
+	for _, endpoint := range entry.Endpoints {
+		snapshotEntry.Endpoints = append(snapshotEntry.Endpoints, endpoint)
+	}
+
+	return snapshotEntry
+}
+
+// WriteSnapshot atomically replaces the file at path with the JSON encoding of
+// the snapshot, so a crash mid-write never leaves a truncated file behind.
+func WriteSnapshot(path string, snapshot Snapshot) error {
+	payload, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	_, err = tmpFile.Write(payload)
+	if err != nil {
+		tmpFile.Close()
+		return err
+	}
+
+	err = tmpFile.Sync()
+	if err != nil {
+		tmpFile.Close()
+		return err
+	}
+
+	err = tmpFile.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmpFile.Name(), path)
+}
+
+func ReadSnapshot(path string) (Snapshot, error) {
+	payload, err := os.ReadFile(path)
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	var snapshot Snapshot
+	err = json.Unmarshal(payload, &snapshot)
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	if snapshot.Version != SnapshotVersion {
+		return Snapshot{}, ErrSnapshotVersionMismatch
+	}
+
+	return snapshot, nil
+}
@@ -0,0 +1,120 @@
+package routingtable_test
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/clock/fakeclock"
+	mfakes "code.cloudfoundry.org/diego-logging-client/testhelpers"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	. "code.cloudfoundry.org/route-emitter/routingtable/matchers"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+)
+
+var _ = Describe("Snapshot", func() {
+	var (
+		table            routingtable.RoutingTable
+		logger           *lagertest.TestLogger
+		fakeMetronClient *mfakes.FakeIngressClient
+		snapshotPath     string
+	)
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test-route-emitter")
+		fakeMetronClient = &mfakes.FakeIngressClient{}
+		table = routingtable.NewRoutingTable(false, fakeMetronClient)
+
+		tmpDir, err := os.MkdirTemp("", "snapshot")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(os.RemoveAll, tmpDir)
+		snapshotPath = filepath.Join(tmpDir, "routing-table.json")
+
+		tag := models.ModificationTag{Epoch: "abc", Index: 1}
+		routes := createRoutingInfo(8080, []string{"foo.example.com"}, []string{"foo.apps.internal"}, "", []uint32{61000}, "router-group-guid")
+		desiredLRP := createDesiredLRPWithRoutes("process-guid-1", 1, routes, "log-guid", tag, models.DesiredLRPRunInfo{})
+		table.SetRoutes(logger, nil, desiredLRP)
+
+		endpoint := routingtable.Endpoint{
+			InstanceGUID:    "ig-1",
+			Host:            "1.1.1.1",
+			ContainerIP:     "1.2.3.4",
+			Port:            11,
+			ContainerPort:   8080,
+			ModificationTag: &tag,
+		}
+		key := routingtable.RoutingKey{ProcessGUID: "process-guid-1", ContainerPort: 8080}
+		table.AddEndpoint(logger, createActualLRP(key, endpoint, "domain"))
+	})
+
+	It("round trips the table through a file", func() {
+		err := routingtable.WriteSnapshot(snapshotPath, table.Snapshot())
+		Expect(err).NotTo(HaveOccurred())
+
+		snapshot, err := routingtable.ReadSnapshot(snapshotPath)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(snapshot.HTTP).To(HaveLen(1))
+		Expect(snapshot.TCP).To(HaveLen(1))
+		Expect(snapshot.Internal).To(HaveLen(2))
+
+		restored := routingtable.NewRoutingTable(false, fakeMetronClient)
+		restored.Restore(logger, snapshot)
+
+		expectedMappings, expectedMessages := table.GetExternalRoutingEvents()
+		mappings, messages := restored.GetExternalRoutingEvents()
+		Expect(messages).To(MatchMessagesToEmit(expectedMessages))
+		Expect(mappings.Registrations).To(ConsistOf(expectedMappings.Registrations))
+
+		_, expectedInternalMessages := table.GetInternalRoutingEvents()
+		_, internalMessages := restored.GetInternalRoutingEvents()
+		Expect(internalMessages).To(MatchMessagesToEmit(expectedInternalMessages))
+
+		Expect(restored.HTTPAssociationsCount()).To(Equal(table.HTTPAssociationsCount()))
+		Expect(restored.TableSize()).To(Equal(table.TableSize()))
+	})
+
+	It("logs the number of restored entries", func() {
+		restored := routingtable.NewRoutingTable(false, fakeMetronClient)
+		restored.Restore(logger, table.Snapshot())
+		Expect(logger).To(gbytes.Say("restored-routing-table"))
+	})
+
+	It("records the time of the last sync and keeps it across a restore", func() {
+		clock := fakeclock.NewFakeClock(time.Now())
+		synced := routingtable.NewRoutingTable(false, fakeMetronClient, routingtable.WithClock(clock))
+		Expect(synced.Snapshot().SyncedAt.IsZero()).To(BeTrue())
+
+		synced.Swap(logger, table, models.NewDomainSet([]string{"domain"}))
+		syncedAt := clock.Now()
+		Expect(synced.Snapshot().SyncedAt).To(BeTemporally("==", syncedAt))
+
+		clock.Increment(time.Hour)
+		restored := routingtable.NewRoutingTable(false, fakeMetronClient, routingtable.WithClock(clock))
+		restored.Restore(logger, synced.Snapshot())
+		Expect(restored.Snapshot().SyncedAt).To(BeTemporally("==", syncedAt))
+	})
+
+	Context("when the snapshot was written by a different version", func() {
+		BeforeEach(func() {
+			err := os.WriteFile(snapshotPath, []byte(`{"version": 999}`), 0644)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("returns an error", func() {
+			_, err := routingtable.ReadSnapshot(snapshotPath)
+			Expect(err).To(Equal(routingtable.ErrSnapshotVersionMismatch))
+		})
+	})
+
+	Context("when the snapshot file does not exist", func() {
+		It("returns a not-exist error", func() {
+			_, err := routingtable.ReadSnapshot(snapshotPath)
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+	})
+})
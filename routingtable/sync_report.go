@@ -0,0 +1,111 @@
+package routingtable
+
+import "sort"
+
+// SyncReportTopProcessGUIDs is the number of process guids listed in
+// SyncReport.TopProcessGUIDs.
+const SyncReportTopProcessGUIDs = 10
+
+// SyncCounts counts the routing keys of one route type and domain by how a
+// Swap changed them. Held routing keys kept routes that are missing from the
+// new table because their domain was not fresh.
+type SyncCounts struct {
+	Added   int `json:"added"`
+	Removed int `json:"removed"`
+	Changed int `json:"changed"`
+	Held    int `json:"held"`
+}
+
+func (c SyncCounts) add(o SyncCounts) SyncCounts {
+	return SyncCounts{
+		Added:   c.Added + o.Added,
+		Removed: c.Removed + o.Removed,
+		Changed: c.Changed + o.Changed,
+		Held:    c.Held + o.Held,
+	}
+}
+
+// ProcessGUIDChanges is the number of route registrations and
+// unregistrations a Swap emitted for a process guid.
+type ProcessGUIDChanges struct {
+	ProcessGUID string `json:"process_guid"`
+	Changes     int    `json:"changes"`
+}
+
+// SyncReport describes how a Swap changed the routing table. RouteTypes is
+// keyed by route provider name and then by domain.
+type SyncReport struct {
+	RouteTypes      map[string]map[string]SyncCounts `json:"route_types"`
+	TopProcessGUIDs []ProcessGUIDChanges             `json:"top_process_guids"`
+}
+
+// Totals sums the counts of every route type and domain.
+func (r SyncReport) Totals() SyncCounts {
+	var totals SyncCounts
+	for _, domains := range r.RouteTypes {
+		for _, counts := range domains {
+			totals = totals.add(counts)
+		}
+	}
+	return totals
+}
+
+// syncTally accumulates the changes of a Swap of one sub-table.
+type syncTally struct {
+	domains map[string]SyncCounts
+	changes map[string]int
+}
+
+func newSyncTally() syncTally {
+	return syncTally{
+		domains: map[string]SyncCounts{},
+		changes: map[string]int{},
+	}
+}
+
+// record adds the change of the entry at key from before to after. Entries
+// without routes before and after are not counted, as no routes changed.
+func (t syncTally) record(key RoutingKey, before, after RoutableEndpoints, counts SyncCounts, mappings TCPRouteMappings, messages MessagesToEmit) {
+	if len(before.Routes) == 0 && len(after.Routes) == 0 {
+		return
+	}
+	t.domains[after.Domain] = t.domains[after.Domain].add(counts)
+
+	changes := len(mappings.Registrations) + len(mappings.Unregistrations) +
+		int(messages.RouteRegistrationCount()+messages.RouteUnregistrationCount()) +
+		int(messages.InternalRouteRegistrationCount()+messages.InternalRouteUnregistrationCount())
+	if changes > 0 {
+		t.changes[key.ProcessGUID] += changes
+	}
+}
+
+func newSyncReport(tallies map[string]syncTally) SyncReport {
+	report := SyncReport{
+		RouteTypes:      map[string]map[string]SyncCounts{},
+		TopProcessGUIDs: []ProcessGUIDChanges{},
+	}
+
+	changes := map[string]int{}
+	for name, tally := range tallies {
+		report.RouteTypes[name] = tally.domains
+		for processGUID, count := range tally.changes {
+			changes[processGUID] += count
+		}
+	}
+
+	for processGUID, count := range changes {
+		report.TopProcessGUIDs = append(report.TopProcessGUIDs, ProcessGUIDChanges{ProcessGUID: processGUID, Changes: count})
+	}
+	sort.Slice(report.TopProcessGUIDs, func(i, j int) bool {
+		a, b := report.TopProcessGUIDs[i], report.TopProcessGUIDs[j]
+		if a.Changes != b.Changes {
+			return a.Changes > b.Changes
+		}
+		return a.ProcessGUID < b.ProcessGUID
+	})
+	if len(report.TopProcessGUIDs) > SyncReportTopProcessGUIDs {
+		report.TopProcessGUIDs = report.TopProcessGUIDs[:SyncReportTopProcessGUIDs]
+	}
+
+	return report
+}
@@ -0,0 +1,100 @@
+package routingtable_test
+
+import (
+	"fmt"
+
+	"code.cloudfoundry.org/bbs/models"
+	mfakes "code.cloudfoundry.org/diego-logging-client/testhelpers"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SyncReport", func() {
+	var (
+		logger           *lagertest.TestLogger
+		fakeMetronClient *mfakes.FakeIngressClient
+		table            routingtable.RoutingTable
+		tempTable        routingtable.RoutingTable
+		report           routingtable.SyncReport
+	)
+
+	tag := models.ModificationTag{Epoch: "abc", Index: 1}
+
+	desiredLRP := func(processGUID, domain string, hostnames ...string) *models.DesiredLRP {
+		lrp := createDesiredLRP(processGUID, 1, 8080, "log-guid", "", tag, models.DesiredLRPRunInfo{}, hostnames...)
+		lrp.Domain = domain
+		return lrp
+	}
+
+	actualLRP := func(processGUID string, i int) *models.ActualLRP {
+		endpoint := routingtable.Endpoint{
+			InstanceGUID:    fmt.Sprintf("ig-%d", i),
+			Host:            "1.1.1.1",
+			Port:            uint32(61000 + i),
+			ContainerPort:   8080,
+			ModificationTag: &tag,
+		}
+		return createActualLRP(routingtable.RoutingKey{ProcessGUID: processGUID, ContainerPort: 8080}, endpoint, "domain")
+	}
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test-route-emitter")
+		fakeMetronClient = &mfakes.FakeIngressClient{}
+
+		table = routingtable.NewRoutingTable(false, fakeMetronClient)
+		table.SetRoutes(logger, nil, desiredLRP("pg-changed", "fresh", "changed.example.com"))
+		table.SetRoutes(logger, nil, desiredLRP("pg-removed", "fresh", "removed.example.com"))
+		table.SetRoutes(logger, nil, desiredLRP("pg-held", "stale", "held.example.com"))
+		table.AddEndpoint(logger, actualLRP("pg-changed", 1))
+		table.AddEndpoint(logger, actualLRP("pg-removed", 2))
+		table.AddEndpoint(logger, actualLRP("pg-held", 3))
+
+		tempTable = routingtable.NewRoutingTable(false, fakeMetronClient)
+		tempTable.SetRoutes(logger, nil, desiredLRP("pg-changed", "fresh", "changed.example.com", "other.example.com"))
+		tempTable.SetRoutes(logger, nil, desiredLRP("pg-added", "fresh", "added.example.com"))
+		tempTable.AddEndpoint(logger, actualLRP("pg-changed", 1))
+		tempTable.AddEndpoint(logger, actualLRP("pg-added", 4))
+		tempTable.AddEndpoint(logger, actualLRP("pg-held", 3))
+	})
+
+	JustBeforeEach(func() {
+		_, _, report = table.Swap(logger, tempTable, models.NewDomainSet([]string{"fresh"}))
+	})
+
+	It("counts the routing keys by route type, domain and change", func() {
+		Expect(report.RouteTypes[routingtable.HTTPRouteProvider]).To(Equal(map[string]routingtable.SyncCounts{
+			"fresh": {Added: 1, Removed: 1, Changed: 1},
+			"stale": {Held: 1},
+		}))
+		Expect(report.RouteTypes[routingtable.TCPRouteProvider]).To(BeEmpty())
+		Expect(report.Totals()).To(Equal(routingtable.SyncCounts{Added: 1, Removed: 1, Changed: 1, Held: 1}))
+	})
+
+	It("lists the process guids with the most route changes", func() {
+		Expect(report.TopProcessGUIDs).To(ConsistOf(
+			routingtable.ProcessGUIDChanges{ProcessGUID: "pg-added", Changes: 1},
+			routingtable.ProcessGUIDChanges{ProcessGUID: "pg-changed", Changes: 1},
+			routingtable.ProcessGUIDChanges{ProcessGUID: "pg-removed", Changes: 1},
+		))
+	})
+
+	Context("when many process guids change", func() {
+		BeforeEach(func() {
+			for i := 0; i < routingtable.SyncReportTopProcessGUIDs+5; i++ {
+				processGUID := fmt.Sprintf("pg-many-%d", i)
+				tempTable.SetRoutes(logger, nil, desiredLRP(processGUID, "fresh", "many.example.com"))
+				tempTable.AddEndpoint(logger, actualLRP(processGUID, 10+i))
+			}
+			tempTable.SetRoutes(logger, nil, desiredLRP("pg-biggest", "fresh", "a.example.com", "b.example.com", "c.example.com"))
+			tempTable.AddEndpoint(logger, actualLRP("pg-biggest", 100))
+		})
+
+		It("only lists the biggest changes", func() {
+			Expect(report.TopProcessGUIDs).To(HaveLen(routingtable.SyncReportTopProcessGUIDs))
+			Expect(report.TopProcessGUIDs[0]).To(Equal(routingtable.ProcessGUIDChanges{ProcessGUID: "pg-biggest", Changes: 3}))
+		})
+	})
+})
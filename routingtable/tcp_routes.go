@@ -0,0 +1,46 @@
+package routingtable
+
+import (
+	"encoding/json"
+
+	"code.cloudfoundry.org/bbs/models"
+	"code.cloudfoundry.org/routing-info/tcp_routes"
+)
+
+// tcpRouteInfo is a route of the tcp-router routing info, including the
+// fields that tcp_routes.TCPRoute does not decode.
+type tcpRouteInfo struct {
+	tcp_routes.TCPRoute
+	SniHostname string `json:"sni_hostname,omitempty"`
+	TLSProxy    bool   `json:"tls_proxy,omitempty"`
+}
+
+func tcpRouteInfosFrom(routes *models.Routes) ([]tcpRouteInfo, error) {
+	if routes == nil {
+		return nil, nil
+	}
+
+	data, ok := (*routes)[tcp_routes.TCP_ROUTER]
+	if !ok || data == nil {
+		return nil, nil
+	}
+
+	infos := []tcpRouteInfo{}
+	err := json.Unmarshal(*data, &infos)
+	return infos, err
+}
+
+// tlsProxyTCPRoutesFrom is tcpRoutesFrom with every route targeting the TLS
+// proxy port.
+func tlsProxyTCPRoutesFrom(lrp *models.DesiredLRP) map[RoutingKey][]RouteMapping {
+	routeEntries := tcpRoutesFrom(lrp)
+	for _, routes := range routeEntries {
+		for i, route := range routes {
+			if info, ok := route.(ExternalEndpointInfo); ok {
+				info.TLSProxy = true
+				routes[i] = info
+			}
+		}
+	}
+	return routeEntries
+}
@@ -0,0 +1,172 @@
+package routingtable_test
+
+import (
+	"encoding/json"
+
+	"code.cloudfoundry.org/bbs/models"
+	mfakes "code.cloudfoundry.org/diego-logging-client/testhelpers"
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	tcpmodels "code.cloudfoundry.org/routing-api/models"
+	"code.cloudfoundry.org/routing-info/tcp_routes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TCP routes with SNI hostnames", func() {
+	var (
+		logger       *lagertest.TestLogger
+		routingTable routingtable.RoutingTable
+		actualLRP    *models.ActualLRP
+	)
+
+	tag := models.ModificationTag{Epoch: "abc", Index: 0}
+
+	desiredLRP := func(tcpRouterInfo string) *models.DesiredLRP {
+		raw := json.RawMessage(tcpRouterInfo)
+		return &models.DesiredLRP{
+			ProcessGuid:     "process-guid",
+			LogGuid:         "log-guid",
+			Domain:          "domain",
+			Instances:       1,
+			ModificationTag: &tag,
+			Routes:          &models.Routes{tcp_routes.TCP_ROUTER: &raw},
+		}
+	}
+
+	sniHostname := func(hostname string) *string {
+		return &hostname
+	}
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+		routingTable = routingtable.NewRoutingTable(false, &mfakes.FakeIngressClient{})
+		actualLRP = &models.ActualLRP{
+			ActualLRPKey:         models.NewActualLRPKey("process-guid", 0, "domain"),
+			ActualLRPInstanceKey: models.NewActualLRPInstanceKey("instance-guid", "cell-id"),
+			ActualLRPNetInfo:     models.NewActualLRPNetInfo("1.1.1.1", "2.2.2.2", models.ActualLRPNetInfo_PreferredAddressHost, models.NewPortMapping(61001, 5222)),
+			State:                models.ActualLRPStateRunning,
+			ModificationTag:      tag,
+		}
+
+		routingTable.SetRoutes(logger, nil, desiredLRP(`[{"router_group_guid": "rg", "external_port": 61000, "container_port": 5222, "sni_hostname": "a.example.com"}]`))
+	})
+
+	It("registers route mappings with the SNI hostname", func() {
+		routingEvents, _ := routingTable.AddEndpoint(logger, actualLRP)
+		Expect(routingEvents.Registrations).To(ConsistOf(
+			tcpmodels.NewSniTcpRouteMapping("rg", 61000, sniHostname("a.example.com"), "1.1.1.1", 61001, 0),
+		))
+	})
+
+	It("includes the SNI hostname in snapshots", func() {
+		routingTable.AddEndpoint(logger, actualLRP)
+		snapshot := routingTable.Snapshot()
+		Expect(snapshot.TCP).To(HaveLen(1))
+		Expect(snapshot.TCP[0].TCPRoutes).To(ConsistOf(routingtable.ExternalEndpointInfo{
+			RouterGroupGUID: "rg",
+			Port:            61000,
+			SniHostname:     "a.example.com",
+		}))
+	})
+
+	Context("when the SNI hostname of a route changes", func() {
+		It("replaces the route mapping", func() {
+			before := desiredLRP(`[{"router_group_guid": "rg", "external_port": 61000, "container_port": 5222, "sni_hostname": "a.example.com"}]`)
+			after := desiredLRP(`[{"router_group_guid": "rg", "external_port": 61000, "container_port": 5222, "sni_hostname": "b.example.com"}]`)
+			after.ModificationTag = &models.ModificationTag{Epoch: "abc", Index: 1}
+			routingTable.AddEndpoint(logger, actualLRP)
+
+			routingEvents, _ := routingTable.SetRoutes(logger, before, after)
+			Expect(routingEvents.Registrations).To(ConsistOf(
+				tcpmodels.NewSniTcpRouteMapping("rg", 61000, sniHostname("b.example.com"), "1.1.1.1", 61001, 0),
+			))
+			Expect(routingEvents.Unregistrations).To(ConsistOf(
+				tcpmodels.NewSniTcpRouteMapping("rg", 61000, sniHostname("a.example.com"), "1.1.1.1", 61001, 0),
+			))
+		})
+	})
+
+	Context("when a route has no SNI hostname", func() {
+		It("registers a plain route mapping", func() {
+			routingTable = routingtable.NewRoutingTable(false, &mfakes.FakeIngressClient{})
+			routingTable.SetRoutes(logger, nil, desiredLRP(`[{"router_group_guid": "rg", "external_port": 61000, "container_port": 5222}]`))
+			routingEvents, _ := routingTable.AddEndpoint(logger, actualLRP)
+			Expect(routingEvents.Registrations).To(ConsistOf(
+				tcpmodels.NewTcpRouteMapping("rg", 61000, "1.1.1.1", 61001, 0),
+			))
+		})
+	})
+})
+
+var _ = Describe("TCP routes to the TLS proxy port", func() {
+	var (
+		logger    *lagertest.TestLogger
+		options   []routingtable.Option
+		tcpRoutes string
+		events    routingtable.TCPRouteMappings
+	)
+
+	tag := models.ModificationTag{Epoch: "abc", Index: 0}
+
+	tlsMapping := func(host string, port uint16) tcpmodels.TcpRouteMapping {
+		mapping := tcpmodels.NewTcpRouteMapping("rg", 61000, host, port, 0)
+		mapping.HostTLSPort = int(port)
+		mapping.InstanceId = "instance-guid"
+		return mapping
+	}
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+		options = nil
+		tcpRoutes = `[{"router_group_guid": "rg", "external_port": 61000, "container_port": 5222}]`
+	})
+
+	JustBeforeEach(func() {
+		raw := json.RawMessage(tcpRoutes)
+		desiredLRP := &models.DesiredLRP{
+			ProcessGuid:     "process-guid",
+			LogGuid:         "log-guid",
+			Domain:          "domain",
+			Instances:       1,
+			ModificationTag: &tag,
+			Routes:          &models.Routes{tcp_routes.TCP_ROUTER: &raw},
+		}
+		actualLRP := &models.ActualLRP{
+			ActualLRPKey:         models.NewActualLRPKey("process-guid", 0, "domain"),
+			ActualLRPInstanceKey: models.NewActualLRPInstanceKey("instance-guid", "cell-id"),
+			ActualLRPNetInfo:     models.NewActualLRPNetInfo("1.1.1.1", "2.2.2.2", models.ActualLRPNetInfo_PreferredAddressHost, models.NewPortMappingWithTLSProxy(61001, 5222, 61443, 61443)),
+			State:                models.ActualLRPStateRunning,
+			ModificationTag:      tag,
+		}
+
+		routingTable := routingtable.NewRoutingTable(false, &mfakes.FakeIngressClient{}, options...)
+		routingTable.SetRoutes(logger, nil, desiredLRP)
+		events, _ = routingTable.AddEndpoint(logger, actualLRP)
+	})
+
+	It("targets the application port by default", func() {
+		Expect(events.Registrations).To(ConsistOf(tcpmodels.NewTcpRouteMapping("rg", 61000, "1.1.1.1", 61001, 0)))
+	})
+
+	Context("when the route asks for the TLS proxy", func() {
+		BeforeEach(func() {
+			tcpRoutes = `[{"router_group_guid": "rg", "external_port": 61000, "container_port": 5222, "tls_proxy": true}]`
+		})
+
+		It("targets the TLS proxy port and verifies the instance", func() {
+			Expect(events.Registrations).To(ConsistOf(tlsMapping("1.1.1.1", 61443)))
+		})
+	})
+
+	Context("when every TCP route targets the TLS proxy", func() {
+		BeforeEach(func() {
+			options = []routingtable.Option{routingtable.WithTCPRoutesToTLSProxy()}
+		})
+
+		It("targets the TLS proxy port and verifies the instance", func() {
+			Expect(events.Registrations).To(ConsistOf(tlsMapping("1.1.1.1", 61443)))
+		})
+	})
+})
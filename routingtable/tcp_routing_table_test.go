@@ -208,7 +208,7 @@ var _ = Describe("TCPRoutingTable", func() {
 
 			It("emits routing events for new routes", func() {
 				Expect(routingTable.TCPAssociationsCount()).Should(Equal(0))
-				routingEvents, _ := routingTable.Swap(logger, tempRoutingTable, models.DomainSet{})
+				routingEvents, _, _ := routingTable.Swap(logger, tempRoutingTable, models.DomainSet{})
 				Expect(routingTable.TCPAssociationsCount()).Should(Equal(2))
 
 				ttl := 0
@@ -238,7 +238,7 @@ var _ = Describe("TCPRoutingTable", func() {
 
 				It("emits routing events for new routes", func() {
 					Expect(routingTable.TCPAssociationsCount()).Should(Equal(0))
-					routingEvents, _ := routingTable.Swap(logger, tempRoutingTable, models.DomainSet{})
+					routingEvents, _, _ := routingTable.Swap(logger, tempRoutingTable, models.DomainSet{})
 					Expect(routingTable.TCPAssociationsCount()).Should(Equal(2))
 
 					ttl := 0
@@ -271,7 +271,7 @@ var _ = Describe("TCPRoutingTable", func() {
 						tempRoutingTable.AddEndpoint(logger, actualLRP)
 						Expect(tempRoutingTable.TCPAssociationsCount()).Should(Equal(1))
 						Expect(routingTable.TCPAssociationsCount()).Should(Equal(0))
-						routingEvents, _ := routingTable.Swap(logger, tempRoutingTable, models.DomainSet{})
+						routingEvents, _, _ := routingTable.Swap(logger, tempRoutingTable, models.DomainSet{})
 						Expect(routingTable.TCPAssociationsCount()).Should(Equal(1))
 
 						ttl := 0
@@ -302,7 +302,7 @@ var _ = Describe("TCPRoutingTable", func() {
 			It("should not swap the tables", func() {
 				routingTable = routingtable.NewRoutingTable(false, fakeMetronClient)
 				fakeTable := &fakeroutingtable.FakeRoutingTable{}
-				routingEvents, _ := routingTable.Swap(logger, fakeTable, models.DomainSet{})
+				routingEvents, _, _ := routingTable.Swap(logger, fakeTable, models.DomainSet{})
 				Expect(routingEvents.Registrations).To(HaveLen(0))
 				Expect(routingEvents.Unregistrations).To(HaveLen(0))
 				Expect(routingTable.TCPAssociationsCount()).Should(Equal(0))
@@ -1470,7 +1470,7 @@ var _ = Describe("TCPRoutingTable", func() {
 				})
 
 				It("overwrites the existing entries and emits registration and unregistration routing events", func() {
-					routingEvents, _ := routingTable.Swap(logger, tempRoutingTable, models.DomainSet{})
+					routingEvents, _, _ := routingTable.Swap(logger, tempRoutingTable, models.DomainSet{})
 					ttl := 0
 					Expect(routingEvents.Unregistrations).To(ConsistOf(tcpmodels.TcpRouteMapping{
 						TcpMappingEntity: tcpmodels.TcpMappingEntity{
@@ -1523,7 +1523,7 @@ var _ = Describe("TCPRoutingTable", func() {
 				})
 
 				It("overwrites the existing entries and emits registration and unregistration routing events", func() {
-					routingEvents, _ := routingTable.Swap(logger, tempRoutingTable, models.DomainSet{})
+					routingEvents, _, _ := routingTable.Swap(logger, tempRoutingTable, models.DomainSet{})
 					ttl := 0
 					Expect(routingEvents.Unregistrations).To(ConsistOf(tcpmodels.TcpRouteMapping{
 						TcpMappingEntity: tcpmodels.TcpMappingEntity{
@@ -1586,7 +1586,7 @@ var _ = Describe("TCPRoutingTable", func() {
 				It("emits registration and unregistration events", func() {
 					domains := models.DomainSet{}
 					domains.Add("domain")
-					routingEvents, _ := routingTable.Swap(logger, tempRoutingTable, domains)
+					routingEvents, _, _ := routingTable.Swap(logger, tempRoutingTable, domains)
 
 					ttl := 0
 					Expect(routingEvents.Registrations).To(ConsistOf(tcpmodels.TcpRouteMapping{
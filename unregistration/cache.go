@@ -34,7 +34,7 @@ func (c *cache) Add(registryMessages []routingtable.RegistryMessage) error {
 	c.mux.Lock()
 	defer c.mux.Unlock()
 	c.logger.Debug("add", lager.Data{"cache": registryMessages})
-	for _, registryMessage := range registryMessages {
+	for _, registryMessage := range splitByURI(registryMessages) {
 		registryMessageHash, err := hashstructure.Hash(registryMessage, nil)
 		if err != nil {
 			return err
@@ -50,7 +50,7 @@ func (c *cache) Remove(registryMessages []routingtable.RegistryMessage) error {
 	c.mux.Lock()
 	defer c.mux.Unlock()
 	c.logger.Debug("remove", lager.Data{"cache": registryMessages})
-	for _, registryMessage := range registryMessages {
+	for _, registryMessage := range splitByURI(registryMessages) {
 		registryMessageHash, err := hashstructure.Hash(registryMessage, nil)
 		if err != nil {
 			return err
@@ -70,3 +70,14 @@ func (c *cache) List() []*Message {
 	}
 	return list
 }
+
+// splitByURI caches aggregated messages per URI, so that registering a
+// hostname removes its pending unregistration regardless of which other
+// hostnames the messages were aggregated with.
+func splitByURI(registryMessages []routingtable.RegistryMessage) []routingtable.RegistryMessage {
+	split := []routingtable.RegistryMessage{}
+	for _, registryMessage := range registryMessages {
+		split = append(split, routingtable.SplitRegistryMessage(registryMessage)...)
+	}
+	return split
+}
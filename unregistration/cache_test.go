@@ -107,6 +107,20 @@ var _ = Describe("Cache", func() {
 			))
 		})
 
+		It("removes single hostnames of aggregated messages", func() {
+			aggregated := registryMessage1
+			aggregated.URIs = []string{"host-1.example.com", "host-3.example.com"}
+			err := cache.Add([]routingtable.RegistryMessage{aggregated})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cache.List()).To(HaveLen(2))
+
+			err = cache.Remove([]routingtable.RegistryMessage{registryMessage1})
+			Expect(err).NotTo(HaveOccurred())
+			cachedMessages := cache.List()
+			Expect(cachedMessages).To(HaveLen(1))
+			Expect(cachedMessages[0].RegistryMessage.URIs).To(Equal([]string{"host-3.example.com"}))
+		})
+
 		It("uses only the relevant ip/port fields in the cache key", func() {
 			registryMessage2 = registryMessage1
 			registryMessage2.App = "some-unique-app-id"
@@ -2,6 +2,7 @@ package watcher
 
 import (
 	"fmt"
+	"hash/fnv"
 	"os"
 	"sync"
 	"sync/atomic"
@@ -17,8 +18,12 @@ import (
 
 const (
 	routeSyncDuration = "RouteEmitterSyncDuration"
+	eventQueueSize    = 64
 )
 
+// RouteHandler must be safe for concurrent calls to HandleEvent and
+// RefreshDesired when the watcher has more than one event worker.
+//
 //go:generate counterfeiter -o fakes/fake_routehandler.go . RouteHandler
 type RouteHandler interface {
 	HandleEvent(logger lager.Logger, event models.Event)
@@ -45,6 +50,8 @@ type Watcher struct {
 	emitInternalCh chan struct{}
 	logger         lager.Logger
 	metronClient   loggingclient.IngressClient
+	eventWorkers   int
+	pendingEvents  sync.WaitGroup
 }
 
 func NewWatcher(
@@ -57,6 +64,7 @@ func NewWatcher(
 	emitInternalCh chan struct{},
 	logger lager.Logger,
 	metronClient loggingclient.IngressClient,
+	eventWorkers int,
 ) *Watcher {
 	return &Watcher{
 		cellID:         cellID,
@@ -68,6 +76,7 @@ func NewWatcher(
 		emitInternalCh: emitInternalCh,
 		logger:         logger.Session("watcher"),
 		metronClient:   metronClient,
+		eventWorkers:   eventWorkers,
 	}
 }
 
@@ -90,6 +99,11 @@ func (watcher *Watcher) Run(signals <-chan os.Signal, ready chan<- struct{}) err
 	var stopEventSource int32
 
 	go watcher.checkForEvents(resubscribeChannel, eventChan, eventSource, watcher.logger)
+
+	done := make(chan struct{})
+	defer close(done)
+	eventQueues := watcher.startEventWorkers(done)
+
 	watcher.logger.Debug("listening-on-channels")
 	close(ready)
 	watcher.logger.Debug("started")
@@ -108,12 +122,13 @@ func (watcher *Watcher) Run(signals <-chan os.Signal, ready chan<- struct{}) err
 				cachedEvents[event.Key()] = event
 				continue
 			}
-			logger := watcher.logger.Session("handling-event")
-			watcher.handleEvent(logger, event)
+			watcher.dispatchEvent(eventQueues, event)
 		case <-watcher.emitExternalCh:
+			watcher.pendingEvents.Wait()
 			logger := watcher.logger.Session("emit-external")
 			watcher.routeHandler.EmitExternal(logger)
 		case <-watcher.emitInternalCh:
+			watcher.pendingEvents.Wait()
 			logger := watcher.logger.Session("emit-internal")
 			watcher.routeHandler.EmitInternal(logger)
 		case syncEvent := <-syncEnd:
@@ -136,6 +151,7 @@ func (watcher *Watcher) Run(signals <-chan os.Signal, ready chan<- struct{}) err
 				syncEvent.desired = append(syncEvent.desired, cachedDesired...)
 			}
 
+			watcher.pendingEvents.Wait()
 			logger.Debug("calling-handler-sync")
 			watcher.routeHandler.Sync(logger,
 				syncEvent.desired,
@@ -184,6 +200,79 @@ func (watcher *Watcher) Run(signals <-chan os.Signal, ready chan<- struct{}) err
 	}
 }
 
+// startEventWorkers starts one goroutine per event worker. Events are queued
+// to a worker by process guid, so the events of a single LRP are still handled
+// in the order they were received.
+func (w *Watcher) startEventWorkers(done <-chan struct{}) []chan models.Event {
+	if w.eventWorkers <= 1 {
+		return nil
+	}
+
+	eventQueues := make([]chan models.Event, w.eventWorkers)
+	for i := range eventQueues {
+		eventQueues[i] = make(chan models.Event, eventQueueSize)
+		go w.processEvents(eventQueues[i], done)
+	}
+	return eventQueues
+}
+
+func (w *Watcher) processEvents(eventQueue <-chan models.Event, done <-chan struct{}) {
+	for {
+		select {
+		case event := <-eventQueue:
+			logger := w.logger.Session("handling-event")
+			w.handleEvent(logger, event)
+			w.pendingEvents.Done()
+		case <-done:
+			return
+		}
+	}
+}
+
+func (w *Watcher) dispatchEvent(eventQueues []chan models.Event, event models.Event) {
+	if len(eventQueues) == 0 {
+		logger := w.logger.Session("handling-event")
+		w.handleEvent(logger, event)
+		return
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(processGUIDFor(event)))
+	w.pendingEvents.Add(1)
+	eventQueues[h.Sum32()%uint32(len(eventQueues))] <- event
+}
+
+func processGUIDFor(event models.Event) string {
+	switch event := event.(type) {
+	case *models.DesiredLRPCreatedEvent:
+		if event.DesiredLrp != nil {
+			return event.DesiredLrp.ProcessGuid
+		}
+	case *models.DesiredLRPChangedEvent:
+		if event.After != nil {
+			return event.After.ProcessGuid
+		}
+		if event.Before != nil {
+			return event.Before.ProcessGuid
+		}
+	case *models.DesiredLRPRemovedEvent:
+		if event.DesiredLrp != nil {
+			return event.DesiredLrp.ProcessGuid
+		}
+	case *models.ActualLRPInstanceCreatedEvent:
+		if event.ActualLrp != nil {
+			return event.ActualLrp.ProcessGuid
+		}
+	case *models.ActualLRPInstanceChangedEvent:
+		return event.ActualLRPKey.ProcessGuid
+	case *models.ActualLRPInstanceRemovedEvent:
+		if event.ActualLrp != nil {
+			return event.ActualLrp.ProcessGuid
+		}
+	}
+	return ""
+}
+
 func (w *Watcher) retrieveDesiredInternal(logger lager.Logger, event models.Event, currentDesireds []*models.DesiredLRP, syncing bool) []*models.DesiredLRP {
 	var err error
 	var actualLRP *models.ActualLRP
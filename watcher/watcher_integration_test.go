@@ -80,6 +80,7 @@ var _ = Describe("Watcher Integration", func() {
 			emitInternalCh,
 			logger,
 			fakeMetronClient,
+			1,
 		)
 	})
 
@@ -82,6 +82,7 @@ var _ = Describe("Watcher", func() {
 		emitExternalCh   chan struct{}
 		emitInternalCh   chan struct{}
 		fakeMetronClient *mfakes.FakeIngressClient
+		eventWorkers     int
 	)
 
 	BeforeEach(func() {
@@ -98,6 +99,7 @@ var _ = Describe("Watcher", func() {
 		emitInternalCh = make(chan struct{})
 		cellID = ""
 		fakeMetronClient = &mfakes.FakeIngressClient{}
+		eventWorkers = 1
 	})
 
 	JustBeforeEach(func() {
@@ -111,6 +113,7 @@ var _ = Describe("Watcher", func() {
 			emitInternalCh,
 			logger,
 			fakeMetronClient,
+			eventWorkers,
 		)
 		process = ifrit.Invoke(testWatcher)
 	})
@@ -325,6 +328,61 @@ var _ = Describe("Watcher", func() {
 		})
 	})
 
+	Context("when there are multiple event workers", func() {
+		var events []models.Event
+
+		BeforeEach(func() {
+			eventWorkers = 4
+			events = nil
+			for i := 0; i < 10; i++ {
+				for _, guid := range []string{"process-guid-1", "process-guid-2"} {
+					desiredLRP := getDesiredLRP(guid, "log-guid", 5222, 61000)
+					desiredLRP.Instances = int32(i)
+					events = append(events, models.NewDesiredLRPCreatedEvent(desiredLRP, "some-trace-id"))
+				}
+			}
+
+			next := 0
+			blockCh := make(chan struct{})
+			eventSource.NextStub = func() (models.Event, error) {
+				if next < len(events) {
+					next++
+					return events[next-1], nil
+				}
+				<-blockCh
+				return nil, nil
+			}
+		})
+
+		It("handles the events of each LRP in order", func() {
+			Eventually(routeHandler.HandleEventCallCount).Should(Equal(len(events)))
+
+			instances := map[string][]int32{}
+			for i := 0; i < routeHandler.HandleEventCallCount(); i++ {
+				_, event := routeHandler.HandleEventArgsForCall(i)
+				desiredLRP := event.(*models.DesiredLRPCreatedEvent).DesiredLrp
+				instances[desiredLRP.ProcessGuid] = append(instances[desiredLRP.ProcessGuid], desiredLRP.Instances)
+			}
+			Expect(instances["process-guid-1"]).To(Equal([]int32{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}))
+			Expect(instances["process-guid-2"]).To(Equal([]int32{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}))
+		})
+
+		It("finishes handling queued events before emitting", func() {
+			handledBeforeEmit := make(chan int, 1)
+			routeHandler.EmitExternalStub = func(lager.Logger) {
+				handledBeforeEmit <- routeHandler.HandleEventCallCount()
+			}
+
+			routeHandler.HandleEventStub = func(lager.Logger, models.Event) {
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			Eventually(eventSource.NextCallCount).Should(BeNumerically(">", len(events)))
+			emitExternalCh <- struct{}{}
+			Eventually(handledBeforeEmit).Should(Receive(Equal(len(events))))
+		})
+	})
+
 	Describe("emit external event", func() {
 		It("emits registrations", func() {
 			emitExternalCh <- struct{}{}
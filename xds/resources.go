@@ -0,0 +1,290 @@
+package xds
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	endpointv3 "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	listenerv3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	routerv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/router/v3"
+	tlsinspectorv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/listener/tls_inspector/v3"
+	hcmv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	tcpproxyv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/tcp_proxy/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const (
+	HTTPListenerName    = "http"
+	HTTPRouteConfigName = "http"
+
+	clusterConnectTimeout = 5 * time.Second
+)
+
+// resources returns the clusters, endpoints, routes and listeners of the
+// state. HTTP routes share a listener on httpListenerPort and are selected by
+// their hostname and path. TCP routes get a listener on their external port,
+// with a filter chain per SNI hostname.
+func (s *routeState) resources(httpListenerPort uint32) (map[resource.Type][]types.Resource, error) {
+	var (
+		clusters  []types.Resource
+		endpoints []types.Resource
+		listeners []types.Resource
+	)
+
+	virtualHosts := map[string]*routev3.VirtualHost{}
+	for _, uri := range sortedKeys(s.http) {
+		name := httpClusterName(uri)
+		clusters = append(clusters, edsCluster(name))
+		endpoints = append(endpoints, loadAssignment(name, s.http[uri]))
+
+		hostname, path := splitURI(uri)
+		virtualHost, ok := virtualHosts[hostname]
+		if !ok {
+			virtualHost = &routev3.VirtualHost{
+				Name:    hostname,
+				Domains: []string{hostname, hostname + ":*"},
+			}
+			virtualHosts[hostname] = virtualHost
+		}
+		virtualHost.Routes = append(virtualHost.Routes, httpRoute(path, name))
+	}
+
+	routeConfig := &routev3.RouteConfiguration{Name: HTTPRouteConfigName}
+	for _, hostname := range sortedKeys(virtualHosts) {
+		virtualHost := virtualHosts[hostname]
+		// the longest path has to be matched first
+		sort.SliceStable(virtualHost.Routes, func(i, j int) bool {
+			return len(routePath(virtualHost.Routes[i])) > len(routePath(virtualHost.Routes[j]))
+		})
+		routeConfig.VirtualHosts = append(routeConfig.VirtualHosts, virtualHost)
+	}
+
+	httpListener, err := newHTTPListener(httpListenerPort)
+	if err != nil {
+		return nil, err
+	}
+	listeners = append(listeners, httpListener)
+
+	// Envoy cannot tell router groups apart, so one cluster serves the
+	// backends of all router groups on a port and SNI hostname
+	tcpRoutes := map[tcpKey]map[backend]struct{}{}
+	for key, backends := range s.tcp {
+		served := tcpKey{port: key.port, sniHostname: key.sniHostname}
+		for b := range backends {
+			addBackend(tcpRoutes, served, b)
+		}
+	}
+
+	tcpListeners := map[uint32]*listenerv3.Listener{}
+	for _, key := range sortedTCPKeys(tcpRoutes) {
+		name := tcpClusterName(key)
+		clusters = append(clusters, edsCluster(name))
+		endpoints = append(endpoints, loadAssignment(name, tcpRoutes[key]))
+
+		listener, ok := tcpListeners[key.port]
+		if !ok {
+			listener, err = newTCPListener(key.port)
+			if err != nil {
+				return nil, err
+			}
+			tcpListeners[key.port] = listener
+			listeners = append(listeners, listener)
+		}
+		filterChain, err := tcpFilterChain(key, name)
+		if err != nil {
+			return nil, err
+		}
+		listener.FilterChains = append(listener.FilterChains, filterChain)
+	}
+
+	return map[resource.Type][]types.Resource{
+		resource.ClusterType:  clusters,
+		resource.EndpointType: endpoints,
+		resource.RouteType:    {routeConfig},
+		resource.ListenerType: listeners,
+	}, nil
+}
+
+func httpClusterName(uri string) string {
+	return "http|" + uri
+}
+
+func tcpClusterName(key tcpKey) string {
+	if key.sniHostname == "" {
+		return fmt.Sprintf("tcp|%d", key.port)
+	}
+	return fmt.Sprintf("tcp|%d|%s", key.port, key.sniHostname)
+}
+
+func adsConfigSource() *corev3.ConfigSource {
+	return &corev3.ConfigSource{
+		ResourceApiVersion:    corev3.ApiVersion_V3,
+		ConfigSourceSpecifier: &corev3.ConfigSource_Ads{Ads: &corev3.AggregatedConfigSource{}},
+	}
+}
+
+func socketAddress(host string, port uint32) *corev3.Address {
+	return &corev3.Address{
+		Address: &corev3.Address_SocketAddress{
+			SocketAddress: &corev3.SocketAddress{
+				Protocol:      corev3.SocketAddress_TCP,
+				Address:       host,
+				PortSpecifier: &corev3.SocketAddress_PortValue{PortValue: port},
+			},
+		},
+	}
+}
+
+func edsCluster(name string) *clusterv3.Cluster {
+	return &clusterv3.Cluster{
+		Name:                 name,
+		ConnectTimeout:       durationpb.New(clusterConnectTimeout),
+		ClusterDiscoveryType: &clusterv3.Cluster_Type{Type: clusterv3.Cluster_EDS},
+		EdsClusterConfig:     &clusterv3.Cluster_EdsClusterConfig{EdsConfig: adsConfigSource()},
+		LbPolicy:             clusterv3.Cluster_ROUND_ROBIN,
+	}
+}
+
+func loadAssignment(name string, backends map[backend]struct{}) *endpointv3.ClusterLoadAssignment {
+	sorted := make([]backend, 0, len(backends))
+	for b := range backends {
+		sorted = append(sorted, b)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].host != sorted[j].host {
+			return sorted[i].host < sorted[j].host
+		}
+		return sorted[i].port < sorted[j].port
+	})
+
+	lbEndpoints := make([]*endpointv3.LbEndpoint, 0, len(sorted))
+	for _, b := range sorted {
+		lbEndpoints = append(lbEndpoints, &endpointv3.LbEndpoint{
+			HostIdentifier: &endpointv3.LbEndpoint_Endpoint{
+				Endpoint: &endpointv3.Endpoint{Address: socketAddress(b.host, b.port)},
+			},
+		})
+	}
+	return &endpointv3.ClusterLoadAssignment{
+		ClusterName: name,
+		Endpoints:   []*endpointv3.LocalityLbEndpoints{{LbEndpoints: lbEndpoints}},
+	}
+}
+
+// httpRoute matches the path and everything below it, like gorouter does.
+func httpRoute(path, cluster string) *routev3.Route {
+	match := &routev3.RouteMatch{PathSpecifier: &routev3.RouteMatch_Prefix{Prefix: "/"}}
+	if path != "" {
+		match.PathSpecifier = &routev3.RouteMatch_PathSeparatedPrefix{PathSeparatedPrefix: path}
+	}
+	return &routev3.Route{
+		Match: match,
+		Action: &routev3.Route_Route{
+			Route: &routev3.RouteAction{
+				ClusterSpecifier: &routev3.RouteAction_Cluster{Cluster: cluster},
+			},
+		},
+	}
+}
+
+func routePath(route *routev3.Route) string {
+	return route.GetMatch().GetPathSeparatedPrefix()
+}
+
+func newHTTPListener(port uint32) (*listenerv3.Listener, error) {
+	router, err := anypb.New(&routerv3.Router{})
+	if err != nil {
+		return nil, err
+	}
+	manager, err := anypb.New(&hcmv3.HttpConnectionManager{
+		CodecType:  hcmv3.HttpConnectionManager_AUTO,
+		StatPrefix: HTTPListenerName,
+		RouteSpecifier: &hcmv3.HttpConnectionManager_Rds{
+			Rds: &hcmv3.Rds{ConfigSource: adsConfigSource(), RouteConfigName: HTTPRouteConfigName},
+		},
+		HttpFilters: []*hcmv3.HttpFilter{{
+			Name:       "envoy.filters.http.router",
+			ConfigType: &hcmv3.HttpFilter_TypedConfig{TypedConfig: router},
+		}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &listenerv3.Listener{
+		Name:    HTTPListenerName,
+		Address: socketAddress("0.0.0.0", port),
+		FilterChains: []*listenerv3.FilterChain{{
+			Filters: []*listenerv3.Filter{{
+				Name:       "envoy.filters.network.http_connection_manager",
+				ConfigType: &listenerv3.Filter_TypedConfig{TypedConfig: manager},
+			}},
+		}},
+	}, nil
+}
+
+func newTCPListener(port uint32) (*listenerv3.Listener, error) {
+	tlsInspector, err := anypb.New(&tlsinspectorv3.TlsInspector{})
+	if err != nil {
+		return nil, err
+	}
+	return &listenerv3.Listener{
+		Name:    fmt.Sprintf("tcp|%d", port),
+		Address: socketAddress("0.0.0.0", port),
+		ListenerFilters: []*listenerv3.ListenerFilter{{
+			Name:       "envoy.filters.listener.tls_inspector",
+			ConfigType: &listenerv3.ListenerFilter_TypedConfig{TypedConfig: tlsInspector},
+		}},
+	}, nil
+}
+
+func tcpFilterChain(key tcpKey, cluster string) (*listenerv3.FilterChain, error) {
+	proxy, err := anypb.New(&tcpproxyv3.TcpProxy{
+		StatPrefix:       cluster,
+		ClusterSpecifier: &tcpproxyv3.TcpProxy_Cluster{Cluster: cluster},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	filterChain := &listenerv3.FilterChain{
+		Filters: []*listenerv3.Filter{{
+			Name:       "envoy.filters.network.tcp_proxy",
+			ConfigType: &listenerv3.Filter_TypedConfig{TypedConfig: proxy},
+		}},
+	}
+	if key.sniHostname != "" {
+		filterChain.FilterChainMatch = &listenerv3.FilterChainMatch{ServerNames: []string{key.sniHostname}}
+	}
+	return filterChain, nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedTCPKeys(m map[tcpKey]map[backend]struct{}) []tcpKey {
+	keys := make([]tcpKey, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].port != keys[j].port {
+			return keys[i].port < keys[j].port
+		}
+		return keys[i].sniHostname < keys[j].sniHostname
+	})
+	return keys
+}
@@ -0,0 +1,172 @@
+package xds
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	discoveryservice "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	cachev3 "github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+	serverv3 "github.com/envoyproxy/go-control-plane/pkg/server/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	"code.cloudfoundry.org/lager/v3"
+	"code.cloudfoundry.org/route-emitter/emitter"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+)
+
+// fleetNodeID is the node every Envoy is served as, so that all of them get
+// the same snapshot whatever their node id.
+const fleetNodeID = "route-emitter"
+
+type fleetHash struct{}
+
+func (fleetHash) ID(*corev3.Node) string {
+	return fleetNodeID
+}
+
+// Server serves the HTTP and TCP routes of the emitter to Envoy over the
+// aggregated discovery service (ADS) of xDS. It is a route sink: every emit is
+// applied to the routes it serves, and a change publishes a new snapshot
+// that Envoys pick up incrementally. As a route snapshot sink, it rebuilds
+// the routes it serves from the routing table after every sync.
+type Server struct {
+	logger           lager.Logger
+	address          string
+	tlsConfig        *tls.Config
+	httpListenerPort uint32
+	cache            cachev3.SnapshotCache
+
+	lock    sync.Mutex
+	state   *routeState
+	version uint64
+}
+
+var _ emitter.RouteSink = new(Server)
+var _ emitter.RouteSnapshotSink = new(Server)
+
+type Option func(*Server)
+
+// WithTLSConfig serves the xDS APIs over TLS.
+func WithTLSConfig(tlsConfig *tls.Config) Option {
+	return func(s *Server) {
+		s.tlsConfig = tlsConfig
+	}
+}
+
+// WithRouterGroup serves only the TCP routes of the router group.
+func WithRouterGroup(routerGroupGUID string) Option {
+	return func(s *Server) {
+		s.state.routerGroupGUID = routerGroupGUID
+	}
+}
+
+// NewServer returns a server listening on address. Envoys are given an HTTP
+// listener on httpListenerPort. Nothing is served before the first emit, so
+// that Envoys do not drop their routes while the emitter starts.
+func NewServer(logger lager.Logger, address string, httpListenerPort uint32, options ...Option) *Server {
+	s := &Server{
+		logger:           logger.Session("xds"),
+		address:          address,
+		httpListenerPort: httpListenerPort,
+		cache:            cachev3.NewSnapshotCache(true, fleetHash{}, nil),
+		state:            newRouteState(""),
+	}
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
+// Emit applies the registrations and unregistrations to the routes served,
+// and publishes a new snapshot if they changed or none was published yet.
+func (s *Server) Emit(messagesToEmit routingtable.MessagesToEmit, routeMappings routingtable.TCPRouteMappings) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	changed := s.state.apply(messagesToEmit, routeMappings)
+	if !changed && s.version > 0 {
+		return nil
+	}
+	return s.publish()
+}
+
+// Replace serves exactly the given registrations, which are all routes of
+// the routing table, instead of the routes built up from emits. It publishes
+// a new snapshot if that changes the routes or none was published yet.
+func (s *Server) Replace(messagesToEmit routingtable.MessagesToEmit, routeMappings routingtable.TCPRouteMappings) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	state := newRouteState(s.state.routerGroupGUID)
+	state.apply(routingtable.MessagesToEmit{RegistrationMessages: messagesToEmit.RegistrationMessages},
+		routingtable.TCPRouteMappings{Registrations: routeMappings.Registrations})
+	if state.equal(s.state) && s.version > 0 {
+		return nil
+	}
+	s.state = state
+	return s.publish()
+}
+
+// Snapshot returns the resources currently served.
+func (s *Server) Snapshot() (cachev3.ResourceSnapshot, error) {
+	return s.cache.GetSnapshot(fleetNodeID)
+}
+
+func (s *Server) publish() error {
+	resources, err := s.state.resources(s.httpListenerPort)
+	if err != nil {
+		return err
+	}
+
+	s.version++
+	snapshot, err := cachev3.NewSnapshot(strconv.FormatUint(s.version, 10), resources)
+	if err != nil {
+		return err
+	}
+	err = snapshot.Consistent()
+	if err != nil {
+		return err
+	}
+
+	s.logger.Debug("publishing-snapshot", lager.Data{"version": s.version})
+	return s.cache.SetSnapshot(context.Background(), fleetNodeID, snapshot)
+}
+
+func (s *Server) Run(signals <-chan os.Signal, ready chan<- struct{}) error {
+	listener, err := net.Listen("tcp", s.address)
+	if err != nil {
+		return err
+	}
+
+	var options []grpc.ServerOption
+	if s.tlsConfig != nil {
+		options = append(options, grpc.Creds(credentials.NewTLS(s.tlsConfig)))
+	}
+	grpcServer := grpc.NewServer(options...)
+
+	xdsServer := serverv3.NewServer(context.Background(), s.cache, nil)
+	discoveryservice.RegisterAggregatedDiscoveryServiceServer(grpcServer, xdsServer)
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- grpcServer.Serve(listener)
+	}()
+
+	s.logger.Info("started", lager.Data{"address": s.address})
+	close(ready)
+
+	select {
+	case <-signals:
+		grpcServer.GracefulStop()
+		s.logger.Info("stopped")
+		return nil
+	case err := <-errCh:
+		return err
+	}
+}
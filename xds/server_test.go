@@ -0,0 +1,191 @@
+package xds_test
+
+import (
+	endpointv3 "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	listenerv3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	"github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+
+	"code.cloudfoundry.org/lager/v3/lagertest"
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	"code.cloudfoundry.org/route-emitter/xds"
+	tcpmodels "code.cloudfoundry.org/routing-api/models"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Server", func() {
+	var server *xds.Server
+
+	registrations := func(messages ...routingtable.RegistryMessage) routingtable.MessagesToEmit {
+		return routingtable.MessagesToEmit{RegistrationMessages: messages}
+	}
+
+	resources := func(typeURL resource.Type) map[string]types.Resource {
+		snapshot, err := server.Snapshot()
+		Expect(err).NotTo(HaveOccurred())
+		return snapshot.GetResources(typeURL)
+	}
+
+	version := func() string {
+		snapshot, err := server.Snapshot()
+		Expect(err).NotTo(HaveOccurred())
+		return snapshot.GetVersion(resource.ClusterType)
+	}
+
+	BeforeEach(func() {
+		server = xds.NewServer(lagertest.NewTestLogger("test"), "127.0.0.1:0", 8080)
+	})
+
+	It("serves nothing before the first emit", func() {
+		_, err := server.Snapshot()
+		Expect(err).To(HaveOccurred())
+	})
+
+	Describe("HTTP routes", func() {
+		BeforeEach(func() {
+			Expect(server.Emit(registrations(
+				routingtable.RegistryMessage{URIs: []string{"Foo.com", "foo.com/bar"}, Host: "1.1.1.1", Port: 61001},
+				routingtable.RegistryMessage{URIs: []string{"foo.com"}, Host: "1.1.1.2", Port: 61002},
+			), routingtable.TCPRouteMappings{})).To(Succeed())
+		})
+
+		It("serves a cluster with the endpoints of every route", func() {
+			Expect(resources(resource.ClusterType)).To(HaveLen(2))
+
+			endpoints := resources(resource.EndpointType)
+			Expect(endpoints).To(HaveKey("http|foo.com"))
+			assignment := endpoints["http|foo.com"].(*endpointv3.ClusterLoadAssignment)
+			Expect(assignment.Endpoints[0].LbEndpoints).To(HaveLen(2))
+			Expect(assignment.Endpoints[0].LbEndpoints[0].GetEndpoint().Address.GetSocketAddress().Address).To(Equal("1.1.1.1"))
+			Expect(assignment.Endpoints[0].LbEndpoints[0].GetEndpoint().Address.GetSocketAddress().GetPortValue()).To(Equal(uint32(61001)))
+		})
+
+		It("serves a virtual host per hostname, matching longer paths first", func() {
+			routes := resources(resource.RouteType)
+			routeConfig := routes[xds.HTTPRouteConfigName].(*routev3.RouteConfiguration)
+			Expect(routeConfig.VirtualHosts).To(HaveLen(1))
+
+			virtualHost := routeConfig.VirtualHosts[0]
+			Expect(virtualHost.Domains).To(ConsistOf("foo.com", "foo.com:*"))
+			Expect(virtualHost.Routes).To(HaveLen(2))
+			Expect(virtualHost.Routes[0].Match.GetPathSeparatedPrefix()).To(Equal("/bar"))
+			Expect(virtualHost.Routes[0].GetRoute().GetCluster()).To(Equal("http|foo.com/bar"))
+			Expect(virtualHost.Routes[1].Match.GetPrefix()).To(Equal("/"))
+		})
+
+		It("serves the HTTP listener", func() {
+			listeners := resources(resource.ListenerType)
+			listener := listeners[xds.HTTPListenerName].(*listenerv3.Listener)
+			Expect(listener.Address.GetSocketAddress().GetPortValue()).To(Equal(uint32(8080)))
+		})
+
+		Context("when a route is unregistered", func() {
+			It("removes the endpoint and publishes a new version", func() {
+				before := version()
+				Expect(server.Emit(routingtable.MessagesToEmit{
+					UnregistrationMessages: []routingtable.RegistryMessage{
+						{URIs: []string{"foo.com/bar"}, Host: "1.1.1.1", Port: 61001},
+					},
+				}, routingtable.TCPRouteMappings{})).To(Succeed())
+
+				Expect(version()).NotTo(Equal(before))
+				Expect(resources(resource.ClusterType)).NotTo(HaveKey("http|foo.com/bar"))
+			})
+		})
+
+		Context("when the routes are replaced with those of the routing table", func() {
+			It("serves only the routes of the table", func() {
+				before := version()
+				Expect(server.Replace(registrations(
+					routingtable.RegistryMessage{URIs: []string{"foo.com"}, Host: "1.1.1.2", Port: 61002},
+				), routingtable.TCPRouteMappings{})).To(Succeed())
+
+				Expect(version()).NotTo(Equal(before))
+				Expect(resources(resource.ClusterType)).To(HaveLen(1))
+				assignment := resources(resource.EndpointType)["http|foo.com"].(*endpointv3.ClusterLoadAssignment)
+				Expect(assignment.Endpoints[0].LbEndpoints).To(HaveLen(1))
+			})
+
+			It("keeps the version if the routes are the same", func() {
+				before := version()
+				Expect(server.Replace(registrations(
+					routingtable.RegistryMessage{URIs: []string{"Foo.com", "foo.com/bar"}, Host: "1.1.1.1", Port: 61001},
+					routingtable.RegistryMessage{URIs: []string{"foo.com"}, Host: "1.1.1.2", Port: 61002},
+				), routingtable.TCPRouteMappings{})).To(Succeed())
+				Expect(version()).To(Equal(before))
+			})
+		})
+
+		Context("when an emit does not change the routes", func() {
+			It("keeps the version", func() {
+				before := version()
+				Expect(server.Emit(registrations(
+					routingtable.RegistryMessage{URIs: []string{"foo.com"}, Host: "1.1.1.2", Port: 61002},
+				), routingtable.TCPRouteMappings{})).To(Succeed())
+				Expect(version()).To(Equal(before))
+			})
+		})
+	})
+
+	Describe("TCP routes", func() {
+		var mappings routingtable.TCPRouteMappings
+
+		BeforeEach(func() {
+			sni := "tls.example.com"
+			mappings = routingtable.TCPRouteMappings{
+				Registrations: []tcpmodels.TcpRouteMapping{
+					tcpmodels.NewTcpRouteMapping("router-group", 5222, "1.1.1.1", 61001, 0),
+					tcpmodels.NewSniTcpRouteMapping("router-group", 5222, &sni, "1.1.1.2", 61002, 0),
+					tcpmodels.NewTcpRouteMapping("other-group", 5333, "1.1.1.3", 61003, 0),
+				},
+			}
+		})
+
+		It("serves a listener per external port with a filter chain per SNI hostname", func() {
+			Expect(server.Emit(routingtable.MessagesToEmit{}, mappings)).To(Succeed())
+
+			listeners := resources(resource.ListenerType)
+			Expect(listeners).To(HaveKey("tcp|5222"))
+			Expect(listeners).To(HaveKey("tcp|5333"))
+
+			listener := listeners["tcp|5222"].(*listenerv3.Listener)
+			Expect(listener.FilterChains).To(HaveLen(2))
+			Expect(listener.FilterChains[0].FilterChainMatch).To(BeNil())
+			Expect(listener.FilterChains[1].FilterChainMatch.ServerNames).To(ConsistOf("tls.example.com"))
+			Expect(resources(resource.ClusterType)).To(HaveKey("tcp|5222|tls.example.com"))
+		})
+
+		Context("when router groups share a port and a backend", func() {
+			It("keeps serving the backend until every router group unregistered it", func() {
+				shared := tcpmodels.NewTcpRouteMapping("other-group", 5222, "1.1.1.1", 61001, 0)
+				mappings.Registrations = append(mappings.Registrations, shared)
+				Expect(server.Emit(routingtable.MessagesToEmit{}, mappings)).To(Succeed())
+
+				Expect(server.Emit(routingtable.MessagesToEmit{}, routingtable.TCPRouteMappings{
+					Unregistrations: []tcpmodels.TcpRouteMapping{shared},
+				})).To(Succeed())
+
+				assignment := resources(resource.EndpointType)["tcp|5222"].(*endpointv3.ClusterLoadAssignment)
+				Expect(assignment.Endpoints[0].LbEndpoints).To(HaveLen(1))
+				Expect(assignment.Endpoints[0].LbEndpoints[0].GetEndpoint().Address.GetSocketAddress().Address).To(Equal("1.1.1.1"))
+			})
+		})
+
+		Context("when the server is limited to a router group", func() {
+			BeforeEach(func() {
+				server = xds.NewServer(lagertest.NewTestLogger("test"), "127.0.0.1:0", 8080, xds.WithRouterGroup("router-group"))
+			})
+
+			It("serves only the routes of the router group", func() {
+				Expect(server.Emit(routingtable.MessagesToEmit{}, mappings)).To(Succeed())
+
+				listeners := resources(resource.ListenerType)
+				Expect(listeners).To(HaveKey("tcp|5222"))
+				Expect(listeners).NotTo(HaveKey("tcp|5333"))
+			})
+		})
+	})
+})
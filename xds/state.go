@@ -0,0 +1,140 @@
+package xds
+
+import (
+	"reflect"
+	"strings"
+
+	"code.cloudfoundry.org/route-emitter/routingtable"
+	tcpmodels "code.cloudfoundry.org/routing-api/models"
+)
+
+type backend struct {
+	host string
+	port uint32
+}
+
+// tcpKey identifies the TCP routes of a router group. Router groups sharing
+// an external port and SNI hostname are served together, but are kept apart
+// so that unregistering a backend in one does not remove it from another.
+type tcpKey struct {
+	routerGroupGUID string
+	port            uint32
+	sniHostname     string
+}
+
+// routeState is the routes the emitter has registered, built up from the
+// registrations and unregistrations of every emit.
+type routeState struct {
+	routerGroupGUID string
+
+	http map[string]map[backend]struct{}
+	tcp  map[tcpKey]map[backend]struct{}
+}
+
+func newRouteState(routerGroupGUID string) *routeState {
+	return &routeState{
+		routerGroupGUID: routerGroupGUID,
+		http:            map[string]map[backend]struct{}{},
+		tcp:             map[tcpKey]map[backend]struct{}{},
+	}
+}
+
+// apply adds the registrations and removes the unregistrations of an emit.
+// It reports whether the routes changed.
+func (s *routeState) apply(messagesToEmit routingtable.MessagesToEmit, routeMappings routingtable.TCPRouteMappings) bool {
+	changed := false
+	for _, message := range messagesToEmit.UnregistrationMessages {
+		for _, uri := range message.URIs {
+			changed = removeBackend(s.http, normalizeURI(uri), httpBackend(message)) || changed
+		}
+	}
+	for _, message := range messagesToEmit.RegistrationMessages {
+		if message.Port == 0 {
+			continue
+		}
+		for _, uri := range message.URIs {
+			changed = addBackend(s.http, normalizeURI(uri), httpBackend(message)) || changed
+		}
+	}
+
+	for _, mapping := range routeMappings.Unregistrations {
+		if s.servesRouterGroup(mapping) {
+			changed = removeBackend(s.tcp, tcpKeyFor(mapping), tcpBackend(mapping)) || changed
+		}
+	}
+	for _, mapping := range routeMappings.Registrations {
+		if s.servesRouterGroup(mapping) {
+			changed = addBackend(s.tcp, tcpKeyFor(mapping), tcpBackend(mapping)) || changed
+		}
+	}
+	return changed
+}
+
+func (s *routeState) equal(other *routeState) bool {
+	return reflect.DeepEqual(s.http, other.http) && reflect.DeepEqual(s.tcp, other.tcp)
+}
+
+func (s *routeState) servesRouterGroup(mapping tcpmodels.TcpRouteMapping) bool {
+	return s.routerGroupGUID == "" || mapping.RouterGroupGuid == s.routerGroupGUID
+}
+
+func addBackend[K comparable](routes map[K]map[backend]struct{}, key K, b backend) bool {
+	backends, ok := routes[key]
+	if !ok {
+		backends = map[backend]struct{}{}
+		routes[key] = backends
+	}
+	if _, ok := backends[b]; ok {
+		return false
+	}
+	backends[b] = struct{}{}
+	return true
+}
+
+func removeBackend[K comparable](routes map[K]map[backend]struct{}, key K, b backend) bool {
+	backends, ok := routes[key]
+	if !ok {
+		return false
+	}
+	if _, ok := backends[b]; !ok {
+		return false
+	}
+	delete(backends, b)
+	if len(backends) == 0 {
+		delete(routes, key)
+	}
+	return true
+}
+
+func httpBackend(message routingtable.RegistryMessage) backend {
+	return backend{host: message.Host, port: message.Port}
+}
+
+func tcpBackend(mapping tcpmodels.TcpRouteMapping) backend {
+	return backend{host: mapping.HostIP, port: uint32(mapping.HostPort)}
+}
+
+func tcpKeyFor(mapping tcpmodels.TcpRouteMapping) tcpKey {
+	key := tcpKey{routerGroupGUID: mapping.RouterGroupGuid, port: uint32(mapping.ExternalPort)}
+	if mapping.SniHostname != nil {
+		key.sniHostname = *mapping.SniHostname
+	}
+	return key
+}
+
+// normalizeURI lower cases the hostname of a route URI and drops a trailing
+// slash from its path, the way gorouter matches routes.
+func normalizeURI(uri string) string {
+	hostname, path := splitURI(uri)
+	return strings.ToLower(hostname) + strings.TrimSuffix(path, "/")
+}
+
+// splitURI splits a route URI into its hostname and path. The path is empty
+// or starts with a slash.
+func splitURI(uri string) (string, string) {
+	i := strings.Index(uri, "/")
+	if i < 0 {
+		return uri, ""
+	}
+	return uri[:i], uri[i:]
+}